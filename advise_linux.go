@@ -0,0 +1,14 @@
+package statichash
+
+import "syscall"
+
+// adviseWillNeed tells the kernel we'll need data soon so it can start reading it in
+func adviseWillNeed(data []byte) error {
+	return syscall.Madvise(data, syscall.MADV_WILLNEED)
+}
+
+// adviseDontNeed tells the kernel it can drop the pages of data. They will be read in again from the file if
+// they are accessed.
+func adviseDontNeed(data []byte) error {
+	return syscall.Madvise(data, syscall.MADV_DONTNEED)
+}
@@ -0,0 +1,14 @@
+//go:build aix || darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+
+package statichash
+
+// adviseWillNeed is a no-op on platforms where the syscall package does not provide madvise. The advice is only
+// a hint.
+func adviseWillNeed(data []byte) error {
+	return nil
+}
+
+// adviseDontNeed is a no-op on platforms where the syscall package does not provide madvise
+func adviseDontNeed(data []byte) error {
+	return nil
+}
@@ -0,0 +1,157 @@
+package statichash
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// allocLayouts are the layouts lookups must not allocate with
+var allocLayouts = []struct {
+	name string
+	opts []Option
+}{
+	{"default", nil},
+	{"seeded", []Option{WithSeed(7)}},
+	{"fingerprints", []Option{WithFingerprints()}},
+	{"insertion-order", []Option{WithInsertionOrder(), WithSortedIndex()}},
+	{"cuckoo", []Option{WithCuckoo()}},
+	{"hopscotch", []Option{WithHopscotch(), WithAutoGrow()}},
+	{"interleaved", []Option{WithInterleavedSlots(), WithFingerprints()}},
+	{"inline-values", []Option{WithInlineValues()}},
+	{"hash-only", []Option{WithHashOnly()}},
+}
+
+// assertNoAllocs fails the test if f allocates
+func assertNoAllocs(t *testing.T, name string, f func()) {
+	t.Helper()
+	assert.Zero(t, testing.AllocsPerRun(100, f), name)
+}
+
+func TestLookupAllocs(t *testing.T) {
+	for _, layout := range allocLayouts {
+		t.Run(layout.name, func(t *testing.T) {
+			tb := buildTable(t, 1000, layout.opts...)
+			tb.Finalize()
+			r := readBack(t, tb)
+
+			for _, tt := range []*table{&tb.table, &r.table} {
+				assertNoAllocs(t, "GetPtr", func() { tt.GetPtr("key500") })
+				assertNoAllocs(t, "GetPtr miss", func() { tt.GetPtr("missing") })
+				assertNoAllocs(t, "GetValue", func() { tt.GetValue("key1") })
+				h := tt.Hash("key2")
+				assertNoAllocs(t, "GetPtrWithHash", func() { tt.GetPtrWithHash("key2", h) })
+				assertNoAllocs(t, "ForEach", func() {
+					tt.ForEach(func(key string, val unsafe.Pointer) bool { return len(key) > 0 })
+				})
+				assertNoAllocs(t, "Iterate", func() {
+					for it := tt.Iterate(); it.Next(); {
+						_, _ = it.Key(), it.Value()
+					}
+				})
+			}
+
+			typed, err := NewTyped[int](r)
+			assert.NoError(t, err)
+			assertNoAllocs(t, "Typed.Get", func() { typed.Get("key3") })
+		})
+	}
+}
+
+func TestLookupAllocsWindowed(t *testing.T) {
+	tb := buildTable(t, 1000)
+	tb.Finalize()
+	r, err := NewFrom(writeTempTable(t, tb), WithWindowedMapping(4096, 64))
+	assert.NoError(t, err)
+	defer r.Close()
+
+	// Values are copied out of windows, but comparing keys in place doesn't allocate
+	r.GetPtr("missing")
+	assertNoAllocs(t, "GetPtr miss", func() { r.GetPtr("missing") })
+	assert.Equal(t, 1.0, testing.AllocsPerRun(100, func() { r.GetPtr("key500") }))
+}
+
+func TestLookupAllocsStrings(t *testing.T) {
+	st := NewStringTable(100, 2000)
+	for i := range 100 {
+		assert.NoError(t, st.Set(fmt.Sprintf("key%d", i), fmt.Sprintf("value %d", i)))
+	}
+	st.Finalize()
+	r, err := stringTableFrom(readBack(t, st.w))
+	assert.NoError(t, err)
+
+	assertNoAllocs(t, "StringTable.Get", func() { r.Get("key42") })
+	assertNoAllocs(t, "GetBytes", func() { r.r.GetBytes("key43") })
+	assertNoAllocs(t, "Range", func() {
+		r.Range(func(key, value string) bool { return len(value) > 0 })
+	})
+}
+
+func TestLookupAllocsComposite(t *testing.T) {
+	a := readFromMap(t, map[string]int{"a": 1, "b": 2})
+	b := readFromMap(t, map[string]int{"b": 20, "c": 3})
+
+	u, err := NewUnion(a, b)
+	assert.NoError(t, err)
+	assertNoAllocs(t, "Union.GetPtr", func() { u.GetPtr("c") })
+
+	o, err := NewOverlay(a, b)
+	assert.NoError(t, err)
+	assertNoAllocs(t, "Overlay.GetPtr", func() { o.GetPtr("c") })
+
+	h := NewHybrid(a)
+	h.Set("d", bytesPointer(make([]byte, 8)))
+	assertNoAllocs(t, "Hybrid.GetPtr", func() { h.GetPtr("d") })
+	assertNoAllocs(t, "Hybrid.GetPtr base", func() { h.GetPtr("a") })
+
+	pw := NewPartitioned(100, 8, 1000, 4)
+	for i := range 100 {
+		assert.NoError(t, pw.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&i)))
+	}
+	name := filepath.Join(t.TempDir(), "partitioned")
+	pw.Finalize()
+	assert.NoError(t, pw.WriteFile(name))
+	p, err := OpenPartitioned(name)
+	assert.NoError(t, err)
+	defer p.Close()
+	// The first lookup maps the partition
+	p.GetPtr("key7")
+	assertNoAllocs(t, "Partitioned.GetPtr", func() { p.GetPtr("key7") })
+}
+
+func BenchmarkGetPtr(b *testing.B) {
+	const n = 100_000
+	for _, layout := range allocLayouts {
+		b.Run(layout.name, func(b *testing.B) {
+			tb := New(n, int64(unsafe.Sizeof(int(0))), n*10, layout.opts...)
+			keys := make([]string, n)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key%d", i)
+				if err := tb.Set(keys[i], unsafe.Pointer(&i)); err != nil {
+					b.Fatal(err)
+				}
+			}
+			tb.Finalize()
+
+			b.Run("hit", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, ok := tb.GetPtr(keys[i%n]); !ok {
+						b.Fatal("key not found")
+					}
+				}
+			})
+			b.Run("miss", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, ok := tb.GetPtr("missing"); ok {
+						b.Fatal("missing key found")
+					}
+				}
+			})
+		})
+	}
+}
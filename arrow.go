@@ -0,0 +1,539 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+/*
+Arrow IPC data is a sequence of messages, each a flatbuffer of metadata followed by a body. We only need a few
+fields from the Schema and RecordBatch messages, so rather than take a dependency on the Arrow libraries we read
+the flatbuffers directly. The field numbers below are from Schema.fbs and Message.fbs in the Arrow format
+specification.
+*/
+
+const (
+	arrowHeaderSchema      = 1
+	arrowHeaderDictionary  = 2
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeInt             = 2
+	arrowTypeFloatingPoint   = 3
+	arrowTypeBinary          = 4
+	arrowTypeUtf8            = 5
+	arrowTypeBool            = 6
+	arrowTypeDecimal         = 7
+	arrowTypeDate            = 8
+	arrowTypeTime            = 9
+	arrowTypeTimestamp       = 10
+	arrowTypeFixedSizeBinary = 15
+	arrowTypeDuration        = 18
+	arrowTypeLargeBinary     = 19
+	arrowTypeLargeUtf8       = 20
+)
+
+// arrowFileMagic starts an Arrow IPC file. The messages that follow are in the stream format.
+var arrowFileMagic = []byte("ARROW1\x00\x00")
+
+// errArrowBounds is used to unwind out of the flatbuffer reader when the data is too short
+var errArrowBounds = errors.New("arrow data is truncated")
+
+// FromArrow builds a finalized table from an Arrow IPC stream or file read from r. keyColumn names the column
+// holding the keys, which must be a string or binary column with no nulls. The other columns are packed into each
+// value in the order they appear. They must be fixed width: integers, floats, booleans, fixed-size binary,
+// decimals, dates, times, timestamps or durations. Nulls are stored as zeros. The returned Schema describes where
+// each column is within a value.
+//
+// Pass WithColumns() with no widths to build a columnar table with a column for each Arrow column. Compressed and
+// dictionary-encoded data are not supported.
+func FromArrow(r io.Reader, keyColumn string, opts ...Option) (t *Write, schema Schema, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, schema, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			if p != errArrowBounds {
+				panic(p)
+			}
+			t, err = nil, fmt.Errorf("%w: %v", ErrCorrupt, errArrowBounds)
+		}
+	}()
+
+	fields, batches, err := readArrow(data)
+	if err != nil {
+		return nil, schema, err
+	}
+
+	key := slices.IndexFunc(fields, func(f arrowField) bool { return f.name == keyColumn })
+	if key < 0 {
+		return nil, schema, fmt.Errorf("no key column %q in arrow schema", keyColumn)
+	}
+	if !fields[key].variable {
+		return nil, schema, fmt.Errorf("key column %q is not a string or binary column", keyColumn)
+	}
+
+	var widths []int
+	var valueSize int
+	for i, f := range fields {
+		if i == key {
+			continue
+		}
+		if f.variable {
+			return nil, schema, fmt.Errorf("column %q is not fixed width", f.name)
+		}
+		// The column's values are in the data, so it can't be wider than all of it. This also stops a corrupt
+		// width making us allocate a huge value.
+		if f.width > len(data) {
+			return nil, schema, fmt.Errorf("%w: column %q is %d bytes wide, but there are only %d bytes of arrow data", ErrCorrupt, f.name, f.width, len(data))
+		}
+		f.field.Offset = valueSize
+		schema.Fields = append(schema.Fields, f.field)
+		widths = append(widths, f.width)
+		valueSize += f.width
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.flags&flagColumnar != 0 && len(o.columns) == 0 {
+		if len(widths) == 0 || len(widths) > maxColumns {
+			return nil, schema, fmt.Errorf("a columnar table needs between 1 and %d value columns, not %d", maxColumns, len(widths))
+		}
+		opts = append(opts, WithColumns(widths...))
+	}
+
+	var rows int
+	var keyLength int64
+	for _, b := range batches {
+		rows += int(b.length)
+		keys := b.columns[key]
+		if keys.nullCount > 0 {
+			return nil, schema, fmt.Errorf("key column %q has nulls", keyColumn)
+		}
+		for j := int64(0); j < b.length; j++ {
+			keyLength += int64(len(keys.bytes(j)))
+		}
+	}
+
+	t = New(rows, int64(valueSize), keyLength, opts...)
+	value := make([]byte, valueSize)
+	for _, b := range batches {
+		for j := int64(0); j < b.length; j++ {
+			offset := 0
+			for i, f := range fields {
+				if i == key {
+					continue
+				}
+				b.columns[i].value(j, value[offset:offset+f.width], f.swap)
+				offset += f.width
+			}
+			if err := t.Set(string(b.columns[key].bytes(j)), bytesPointer(value)); err != nil {
+				return nil, schema, err
+			}
+		}
+	}
+	t.Finalize()
+	return t, schema, nil
+}
+
+// arrowField is a column from an Arrow schema
+type arrowField struct {
+	name string
+	// variable is set for string and binary columns, and large is set if they have 64-bit offsets
+	variable bool
+	large    bool
+	// bits is set for boolean columns, which are stored one bit per row
+	bits bool
+	// swap is set if the values are little-endian numbers that need converting to the native byte order
+	swap  bool
+	width int
+	field Field
+}
+
+// arrowBatch is a record batch read from an Arrow stream
+type arrowBatch struct {
+	length  int64
+	columns []arrowColumn
+}
+
+// arrowColumn is one column of a record batch
+type arrowColumn struct {
+	field     *arrowField
+	nullCount int64
+	validity  []byte
+	offsets   []byte
+	data      []byte
+}
+
+// readArrow reads the schema and record batches from Arrow IPC data
+func readArrow(data []byte) (fields []arrowField, batches []arrowBatch, err error) {
+	data = bytes.TrimPrefix(data, arrowFileMagic)
+
+	var haveSchema bool
+	for len(data) >= 4 {
+		length := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		if length == 0xFFFFFFFF {
+			// A continuation marker precedes the length in current versions of the format
+			if len(data) < 4 {
+				break
+			}
+			length = binary.LittleEndian.Uint32(data)
+			data = data[4:]
+		}
+		if length == 0 {
+			// End of stream
+			break
+		}
+		if int64(length) > int64(len(data)) {
+			panic(errArrowBounds)
+		}
+
+		msg := fbRoot(data[:length])
+		data = data[length:]
+		bodyLength := msg.int64(3, 0)
+		if bodyLength < 0 || bodyLength > int64(len(data)) {
+			panic(errArrowBounds)
+		}
+		body := data[:bodyLength]
+		data = data[bodyLength:]
+
+		switch msg.uint8(1, 0) {
+		case arrowHeaderSchema:
+			if haveSchema {
+				return nil, nil, errors.New("arrow stream has more than one schema")
+			}
+			fields, err = readArrowSchema(msg.table(2))
+			if err != nil {
+				return nil, nil, err
+			}
+			haveSchema = true
+		case arrowHeaderRecordBatch:
+			if !haveSchema {
+				return nil, nil, errors.New("arrow record batch before schema")
+			}
+			b, err := readArrowBatch(msg.table(2), body, fields)
+			if err != nil {
+				return nil, nil, err
+			}
+			batches = append(batches, b)
+		case arrowHeaderDictionary:
+			return nil, nil, errors.New("dictionary-encoded arrow data is not supported")
+		}
+	}
+	if !haveSchema {
+		return nil, nil, errors.New("no schema in arrow data")
+	}
+	return fields, batches, nil
+}
+
+// readArrowSchema reads the columns from a Schema message
+func readArrowSchema(s fbTable) ([]arrowField, error) {
+	if s.int16(0, 0) != 0 {
+		return nil, errors.New("big-endian arrow data is not supported")
+	}
+	start, n := s.vector(1)
+	fields := make([]arrowField, n)
+	for i := range fields {
+		ft := s.vectorTable(start, i)
+		f := &fields[i]
+		f.name = ft.string(0)
+		f.field.Name = f.name
+		if ft.has(4) {
+			return nil, fmt.Errorf("column %q is dictionary-encoded, which is not supported", f.name)
+		}
+
+		typ := ft.table(3)
+		switch ft.uint8(2, 0) {
+		case arrowTypeUtf8, arrowTypeBinary:
+			f.variable = true
+		case arrowTypeLargeUtf8, arrowTypeLargeBinary:
+			f.variable, f.large = true, true
+		case arrowTypeInt:
+			bits, signed := typ.int32(0, 0), typ.uint8(1, 0) != 0
+			types := map[int32][2]FieldType{8: {FieldUint8, FieldInt8}, 16: {FieldUint16, FieldInt16}, 32: {FieldUint32, FieldInt32}, 64: {FieldUint64, FieldInt64}}
+			t, ok := types[bits]
+			if !ok {
+				return nil, fmt.Errorf("column %q has unsupported integer width %d", f.name, bits)
+			}
+			if signed {
+				f.field.Type = t[1]
+			} else {
+				f.field.Type = t[0]
+			}
+		case arrowTypeFloatingPoint:
+			switch typ.int16(0, 0) {
+			case 0:
+				// Go has no half-precision floats, so we keep the bits
+				f.field.Type, f.field.Size = FieldBytes, 2
+			case 1:
+				f.field.Type = FieldFloat32
+			default:
+				f.field.Type = FieldFloat64
+			}
+		case arrowTypeBool:
+			f.field.Type, f.bits = FieldBool, true
+		case arrowTypeFixedSizeBinary:
+			f.field.Type, f.field.Size = FieldBytes, int(typ.int32(0, 0))
+		case arrowTypeDecimal:
+			f.field.Type, f.field.Size = FieldBytes, int(typ.int32(2, 128)/8)
+		case arrowTypeDate:
+			// Dates are days in 32 bits, or milliseconds in 64
+			f.field.Type = FieldInt64
+			if typ.int16(0, 1) == 0 {
+				f.field.Type = FieldInt32
+			}
+		case arrowTypeTime:
+			f.field.Type = FieldInt32
+			if typ.int32(1, 32) == 64 {
+				f.field.Type = FieldInt64
+			}
+		case arrowTypeTimestamp, arrowTypeDuration:
+			f.field.Type = FieldInt64
+		default:
+			return nil, fmt.Errorf("column %q has unsupported arrow type %d", f.name, ft.uint8(2, 0))
+		}
+		if !f.variable {
+			f.width = f.field.size()
+			f.swap = f.field.Type != FieldBytes && f.width > 1
+			if f.width <= 0 {
+				return nil, fmt.Errorf("column %q has invalid width %d", f.name, f.width)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// readArrowBatch reads the columns of a RecordBatch message with the given body
+func readArrowBatch(rb fbTable, body []byte, fields []arrowField) (b arrowBatch, err error) {
+	if rb.has(3) {
+		return b, errors.New("compressed arrow data is not supported")
+	}
+	b.length = rb.int64(0, 0)
+	// Every row takes at least a bit in the body, and this keeps the sizes below from overflowing
+	if b.length < 0 || b.length/8 > int64(len(body)) {
+		return b, fmt.Errorf("%w: arrow record batch has %d rows in %d bytes", ErrCorrupt, b.length, len(body))
+	}
+	nodes, numNodes := rb.vector(1)
+	buffers, numBuffers := rb.vector(2)
+	if numNodes != len(fields) {
+		return b, fmt.Errorf("%w: arrow record batch has %d columns but the schema has %d", ErrCorrupt, numNodes, len(fields))
+	}
+
+	buffer := func(i int) []byte {
+		if i >= numBuffers {
+			panic(errArrowBounds)
+		}
+		offset, length := rb.structInt64(buffers, i, 0), rb.structInt64(buffers, i, 1)
+		if offset < 0 || length < 0 || offset > int64(len(body)) || length > int64(len(body))-offset {
+			panic(errArrowBounds)
+		}
+		return body[offset : offset+length]
+	}
+
+	var next int
+	for i := range fields {
+		f := &fields[i]
+		c := arrowColumn{field: f, nullCount: rb.structInt64(nodes, i, 1)}
+		if rows := rb.structInt64(nodes, i, 0); rows != b.length {
+			return b, fmt.Errorf("%w: arrow column %q has %d rows but the batch has %d", ErrCorrupt, f.name, rows, b.length)
+		}
+		c.validity = buffer(next)
+		next++
+		if f.variable {
+			c.offsets = buffer(next)
+			next++
+		}
+		c.data = buffer(next)
+		next++
+
+		// Check the buffers are big enough for every row
+		switch {
+		case f.variable && f.large:
+			c.needs(c.offsets, b.length+1, 8)
+		case f.variable:
+			c.needs(c.offsets, b.length+1, 4)
+		case f.bits:
+			c.needs(c.data, (b.length+7)/8, 1)
+		default:
+			c.needs(c.data, b.length, int64(f.width))
+		}
+		if c.nullCount > 0 {
+			c.needs(c.validity, (b.length+7)/8, 1)
+		}
+		b.columns = append(b.columns, c)
+	}
+	return b, nil
+}
+
+// needs panics unless buf holds n items of the given size
+func (c *arrowColumn) needs(buf []byte, n, size int64) {
+	if n > int64(len(buf))/size {
+		panic(errArrowBounds)
+	}
+}
+
+// bytes returns the bytes of row j of a string or binary column
+func (c *arrowColumn) bytes(j int64) []byte {
+	var start, end int64
+	if c.field.large {
+		start, end = int64(binary.LittleEndian.Uint64(c.offsets[j*8:])), int64(binary.LittleEndian.Uint64(c.offsets[j*8+8:]))
+	} else {
+		start, end = int64(int32(binary.LittleEndian.Uint32(c.offsets[j*4:]))), int64(int32(binary.LittleEndian.Uint32(c.offsets[j*4+4:])))
+	}
+	if start < 0 || start > end || end > int64(len(c.data)) {
+		panic(errArrowBounds)
+	}
+	return c.data[start:end]
+}
+
+// value copies row j of a fixed-width column into v
+func (c *arrowColumn) value(j int64, v []byte, swap bool) {
+	if c.nullCount > 0 && c.validity[j/8]&(1<<(j%8)) == 0 {
+		clear(v)
+		return
+	}
+	switch {
+	case c.field.bits:
+		v[0] = c.data[j/8] >> (j % 8) & 1
+	case !swap:
+		copy(v, c.data[j*int64(len(v)):])
+	case len(v) == 2:
+		binary.NativeEndian.PutUint16(v, binary.LittleEndian.Uint16(c.data[j*2:]))
+	case len(v) == 4:
+		binary.NativeEndian.PutUint32(v, binary.LittleEndian.Uint32(c.data[j*4:]))
+	default:
+		binary.NativeEndian.PutUint64(v, binary.LittleEndian.Uint64(c.data[j*8:]))
+	}
+}
+
+// fbTable is a table within a flatbuffer. Its methods panic with errArrowBounds if the buffer is too short.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+// fbRoot returns the root table of a flatbuffer
+func fbRoot(buf []byte) fbTable {
+	t := fbTable{buf: buf}
+	return fbTable{buf: buf, pos: t.offset(0)}
+}
+
+// check panics unless there are n bytes at pos
+func (t fbTable) check(pos, n int) {
+	if pos < 0 || n < 0 || pos > len(t.buf) || n > len(t.buf)-pos {
+		panic(errArrowBounds)
+	}
+}
+
+// offset follows the uoffset at pos
+func (t fbTable) offset(pos int) int {
+	t.check(pos, 4)
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+// field returns the position of field i of the table, or 0 if the field isn't present
+func (t fbTable) field(i int) int {
+	t.check(t.pos, 4)
+	vt := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	t.check(vt, 4)
+	entry := 4 + 2*i
+	if entry+2 > int(binary.LittleEndian.Uint16(t.buf[vt:])) {
+		return 0
+	}
+	t.check(vt+entry, 2)
+	if off := int(binary.LittleEndian.Uint16(t.buf[vt+entry:])); off != 0 {
+		return t.pos + off
+	}
+	return 0
+}
+
+func (t fbTable) has(i int) bool {
+	return t.field(i) != 0
+}
+
+func (t fbTable) scalar(i, size int) []byte {
+	pos := t.field(i)
+	if pos == 0 {
+		return nil
+	}
+	t.check(pos, size)
+	return t.buf[pos : pos+size]
+}
+
+func (t fbTable) uint8(i int, def uint8) uint8 {
+	if b := t.scalar(i, 1); b != nil {
+		return b[0]
+	}
+	return def
+}
+
+func (t fbTable) int16(i int, def int16) int16 {
+	if b := t.scalar(i, 2); b != nil {
+		return int16(binary.LittleEndian.Uint16(b))
+	}
+	return def
+}
+
+func (t fbTable) int32(i int, def int32) int32 {
+	if b := t.scalar(i, 4); b != nil {
+		return int32(binary.LittleEndian.Uint32(b))
+	}
+	return def
+}
+
+func (t fbTable) int64(i int, def int64) int64 {
+	if b := t.scalar(i, 8); b != nil {
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+	return def
+}
+
+// table returns the table in field i. A missing table reads as all defaults.
+func (t fbTable) table(i int) fbTable {
+	pos := t.field(i)
+	if pos == 0 {
+		// An empty table whose vtable has no fields
+		return fbTable{buf: []byte{4, 0, 4, 0, 4, 0, 0, 0}, pos: 4}
+	}
+	return fbTable{buf: t.buf, pos: t.offset(pos)}
+}
+
+// vector returns the position of the first element of the vector in field i, and its length
+func (t fbTable) vector(i int) (start, n int) {
+	pos := t.field(i)
+	if pos == 0 {
+		return 0, 0
+	}
+	pos = t.offset(pos)
+	t.check(pos, 4)
+	n = int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	// Every element is at least a byte, so this stops a corrupt length making us allocate a lot
+	t.check(pos+4, n)
+	return pos + 4, n
+}
+
+// vectorTable returns element i of a vector of tables starting at start
+func (t fbTable) vectorTable(start, i int) fbTable {
+	return fbTable{buf: t.buf, pos: t.offset(start + 4*i)}
+}
+
+// structInt64 returns int64 field f of element i of a vector of structs made of int64s that starts at start
+func (t fbTable) structInt64(start, i, f int) int64 {
+	pos := start + 16*i + 8*f
+	t.check(pos, 8)
+	return int64(binary.LittleEndian.Uint64(t.buf[pos:]))
+}
+
+func (t fbTable) string(i int) string {
+	start, n := t.vector(i)
+	t.check(start, n)
+	return string(t.buf[start : start+n])
+}
@@ -0,0 +1,260 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fbBuilder writes flatbuffers for tests. Children are written after their parents, as offsets must point
+// forwards. Nothing is aligned, which the reader doesn't need.
+type fbBuilder struct {
+	buf []byte
+}
+
+// fbChild writes a child object and returns its position
+type fbChild func(b *fbBuilder) int
+
+// root writes the root table
+func (b *fbBuilder) root(fields ...any) []byte {
+	b.buf = make([]byte, 4)
+	pos := b.table(fields...)
+	binary.LittleEndian.PutUint32(b.buf, uint32(pos))
+	return b.buf
+}
+
+// table writes a table and returns its position. Each field is nil if absent, []byte for a scalar or an
+// fbChild.
+func (b *fbBuilder) table(fields ...any) int {
+	offsets := make([]uint16, len(fields))
+	size := 4
+	for i, f := range fields {
+		switch f := f.(type) {
+		case []byte:
+			offsets[i] = uint16(size)
+			size += len(f)
+		case fbChild:
+			offsets[i] = uint16(size)
+			size += 4
+		}
+	}
+
+	vt := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(4+2*len(fields)))
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(size))
+	for _, o := range offsets {
+		b.buf = binary.LittleEndian.AppendUint16(b.buf, o)
+	}
+	pos := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(pos-vt))
+	for _, f := range fields {
+		switch f := f.(type) {
+		case []byte:
+			b.buf = append(b.buf, f...)
+		case fbChild:
+			b.buf = append(b.buf, 0, 0, 0, 0)
+		}
+	}
+	for i, f := range fields {
+		if f, ok := f.(fbChild); ok {
+			at := pos + int(offsets[i])
+			child := f(b)
+			binary.LittleEndian.PutUint32(b.buf[at:], uint32(child-at))
+		}
+	}
+	return pos
+}
+
+func fbTableOf(fields ...any) fbChild {
+	return func(b *fbBuilder) int { return b.table(fields...) }
+}
+
+func fbString(s string) fbChild {
+	return func(b *fbBuilder) int {
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(s)))
+		b.buf = append(append(b.buf, s...), 0)
+		return pos
+	}
+}
+
+func fbTables(tables ...fbChild) fbChild {
+	return func(b *fbBuilder) int {
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(tables)))
+		start := len(b.buf)
+		b.buf = append(b.buf, make([]byte, 4*len(tables))...)
+		for i, t := range tables {
+			at := start + 4*i
+			child := t(b)
+			binary.LittleEndian.PutUint32(b.buf[at:], uint32(child-at))
+		}
+		return pos
+	}
+}
+
+// fbInt64Structs writes a vector of structs of two int64s
+func fbInt64Structs(values ...int64) fbChild {
+	return func(b *fbBuilder) int {
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(values)/2))
+		for _, v := range values {
+			b.buf = binary.LittleEndian.AppendUint64(b.buf, uint64(v))
+		}
+		return pos
+	}
+}
+
+func le(v any) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, v)
+	return buf.Bytes()
+}
+
+// arrowMessage frames a message with its body
+func arrowMessage(headerType byte, header fbChild, body []byte) []byte {
+	var b fbBuilder
+	meta := b.root(le(int16(4)), []byte{headerType}, header, le(int64(len(body))))
+	out := binary.LittleEndian.AppendUint32(nil, 0xFFFFFFFF)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(meta)))
+	return append(append(out, meta...), body...)
+}
+
+// arrowBody lays out buffers in a record batch body, returning the body and the Buffer structs
+func arrowBody(buffers ...[]byte) (body []byte, structs []int64) {
+	for _, buf := range buffers {
+		structs = append(structs, int64(len(body)), int64(len(buf)))
+		body = append(body, buf...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+	return body, structs
+}
+
+func testArrowStream() []byte {
+	field := func(name string, typeType byte, typ fbChild) fbChild {
+		return fbTableOf(fbString(name), []byte{1}, []byte{typeType}, typ)
+	}
+	schema := fbTableOf(nil, fbTables(
+		field("id", arrowTypeInt, fbTableOf(le(int32(32)), []byte{1})),
+		field("name", arrowTypeUtf8, fbTableOf()),
+		field("score", arrowTypeFloatingPoint, fbTableOf(le(int16(2)))),
+		field("ok", arrowTypeBool, fbTableOf()),
+		field("tag", arrowTypeFixedSizeBinary, fbTableOf(le(int32(3)))),
+	))
+
+	stream := arrowMessage(arrowHeaderSchema, schema, nil)
+
+	body, buffers := arrowBody(
+		nil, le([]int32{1, -2, 3}),
+		nil, le([]int32{0, 1, 3, 6}), []byte("abbccc"),
+		nil, le([]float64{1.5, 2.5, 3.5}),
+		nil, []byte{0b101},
+		[]byte{0b011}, []byte("xxxyyyzzz"),
+	)
+	batch := fbTableOf(le(int64(3)), fbInt64Structs(3, 0, 3, 0, 3, 0, 3, 0, 3, 1), fbInt64Structs(buffers...))
+	stream = append(stream, arrowMessage(arrowHeaderRecordBatch, batch, body)...)
+
+	body, buffers = arrowBody(
+		nil, le([]int32{4}),
+		nil, le([]int32{0, 4}), []byte("dddd"),
+		nil, le([]float64{4.5}),
+		nil, []byte{0},
+		nil, []byte("www"),
+	)
+	batch = fbTableOf(le(int64(1)), fbInt64Structs(1, 0, 1, 0, 1, 0, 1, 0, 1, 0), fbInt64Structs(buffers...))
+	stream = append(stream, arrowMessage(arrowHeaderRecordBatch, batch, body)...)
+
+	return append(stream, 0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0)
+}
+
+func TestFromArrow(t *testing.T) {
+	stream := testArrowStream()
+
+	check := func(t *testing.T, tb *Write, schema Schema) {
+		assert.Equal(t, 4, tb.Len())
+		assert.Equal(t, []string{"id", "score", "ok", "tag"}, fieldNames(schema))
+		assert.Equal(t, 4+8+1+3, tb.ValueSize())
+
+		get := func(key, field string) any {
+			p, ok := tb.GetPtr(key)
+			if !assert.True(t, ok, key) {
+				return nil
+			}
+			value := unsafe.Slice((*byte)(p), tb.ValueSize())
+			return schema.Field(field).Decode(value)
+		}
+		assert.Equal(t, int64(-2), get("bb", "id"))
+		assert.Equal(t, 3.5, get("ccc", "score"))
+		assert.Equal(t, true, get("a", "ok"))
+		assert.Equal(t, false, get("bb", "ok"))
+		assert.Equal(t, []byte("yyy"), get("bb", "tag"))
+		// Nulls are zero
+		assert.Equal(t, []byte{0, 0, 0}, get("ccc", "tag"))
+		assert.Equal(t, int64(4), get("dddd", "id"))
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		tb, schema, err := FromArrow(bytes.NewReader(stream), "name")
+		assert.NoError(t, err)
+		check(t, tb, schema)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		file := append(append([]byte(nil), arrowFileMagic...), stream...)
+		tb, schema, err := FromArrow(bytes.NewReader(file), "name")
+		assert.NoError(t, err)
+		check(t, tb, schema)
+	})
+
+	t.Run("columnar", func(t *testing.T) {
+		tb, schema, err := FromArrow(bytes.NewReader(stream), "name", WithColumns())
+		assert.NoError(t, err)
+		assert.Equal(t, []int{4, 8, 1, 3}, tb.Columns())
+		check(t, tb, schema)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := FromArrow(bytes.NewReader(stream), "missing")
+		assert.EqualError(t, err, `no key column "missing" in arrow schema`)
+		_, _, err = FromArrow(bytes.NewReader(stream), "id")
+		assert.EqualError(t, err, `key column "id" is not a string or binary column`)
+		_, _, err = FromArrow(bytes.NewReader(stream[:len(stream)-40]), "name")
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("huge sizes", func(t *testing.T) {
+		schema := fbTableOf(nil, fbTables(fbTableOf(fbString("name"), []byte{1}, []byte{arrowTypeUtf8}, fbTableOf())))
+		for _, batch := range []fbChild{
+			// The offset and length of the offsets buffer add up to more than fits in an int64
+			fbTableOf(le(int64(1)), fbInt64Structs(1, 0), fbInt64Structs(0, 0, math.MaxInt64, 8, 0, 0)),
+			fbTableOf(le(int64(math.MaxInt64)), fbInt64Structs(math.MaxInt64, 0), fbInt64Structs(0, 0, 0, 8, 0, 0)),
+		} {
+			stream := arrowMessage(arrowHeaderSchema, schema, nil)
+			stream = append(stream, arrowMessage(arrowHeaderRecordBatch, batch, make([]byte, 8))...)
+			_, _, err := FromArrow(bytes.NewReader(stream), "name")
+			assert.ErrorIs(t, err, ErrCorrupt)
+		}
+	})
+}
+
+func FuzzFromArrow(f *testing.F) {
+	stream := testArrowStream()
+	f.Add(stream)
+	f.Add(append(append([]byte(nil), arrowFileMagic...), stream...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		FromArrow(bytes.NewReader(data), "name")
+	})
+}
+
+func fieldNames(s Schema) (names []string) {
+	for _, f := range s.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/philpearl/statichash"
+)
+
+// accessor is the method generated for a field
+type accessor struct {
+	Field  statichash.Field
+	Method string
+	// Type is the Go type the method returns, and Expr the expression that reads it from v
+	Type string
+	Expr string
+}
+
+// generate returns the formatted source of the package
+func generate(pkg string, l layout) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("%q is not a valid package name", pkg)
+	}
+
+	data := struct {
+		Package   string
+		Source    string
+		ValueSize int
+		Accessors []accessor
+		Binary    bool
+		Math      bool
+	}{Package: pkg, Source: l.source, ValueSize: l.valueSize}
+
+	methods := map[string]string{}
+	for _, f := range l.schema.Fields {
+		a := accessor{Field: f, Method: methodName(f.Name)}
+		if other, ok := methods[a.Method]; ok {
+			return nil, fmt.Errorf("fields %s and %s would both have method %s", other, f.Name, a.Method)
+		}
+		methods[a.Method] = f.Name
+
+		off := f.Offset
+		switch f.Type {
+		case statichash.FieldBytes:
+			a.Type, a.Expr = "[]byte", fmt.Sprintf("v[%d:%d:%d]", off, off+f.Size, off+f.Size)
+		case statichash.FieldBool:
+			a.Type, a.Expr = "bool", fmt.Sprintf("v[%d] != 0", off)
+		case statichash.FieldInt8:
+			a.Type, a.Expr = "int8", fmt.Sprintf("int8(v[%d])", off)
+		case statichash.FieldUint8:
+			a.Type, a.Expr = "uint8", fmt.Sprintf("v[%d]", off)
+		case statichash.FieldInt16, statichash.FieldInt32, statichash.FieldInt64:
+			bits := 8 * fieldSize(f.Type)
+			a.Type, a.Expr = fmt.Sprintf("int%d", bits), fmt.Sprintf("int%d(binary.NativeEndian.Uint%d(v[%d:]))", bits, bits, off)
+		case statichash.FieldUint16, statichash.FieldUint32, statichash.FieldUint64:
+			bits := 8 * fieldSize(f.Type)
+			a.Type, a.Expr = fmt.Sprintf("uint%d", bits), fmt.Sprintf("binary.NativeEndian.Uint%d(v[%d:])", bits, off)
+		case statichash.FieldFloat32, statichash.FieldFloat64:
+			bits := 8 * fieldSize(f.Type)
+			a.Type, a.Expr = fmt.Sprintf("float%d", bits), fmt.Sprintf("math.Float%dfrombits(binary.NativeEndian.Uint%d(v[%d:]))", bits, bits, off)
+			data.Math = true
+		default:
+			return nil, fmt.Errorf("field %s has unknown type %s", f.Name, f.Type)
+		}
+		if strings.Contains(a.Expr, "binary.") {
+			data.Binary = true
+		}
+		data.Accessors = append(data.Accessors, a)
+	}
+
+	var buf bytes.Buffer
+	if err := packageTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// fieldSize returns the size in bytes of a fixed-size field type
+func fieldSize(t statichash.FieldType) int {
+	switch t {
+	case statichash.FieldInt16, statichash.FieldUint16:
+		return 2
+	case statichash.FieldInt32, statichash.FieldUint32, statichash.FieldFloat32:
+		return 4
+	}
+	return 8
+}
+
+// methodName turns a field name such as "inner.count" into an exported method name such as "InnerCount"
+func methodName(field string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range field {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
+
+var packageTemplate = template.Must(template.New("package").Parse(`// Code generated by statichash-gen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .Binary}}
+	"encoding/binary"
+{{- end}}
+{{- if .Math}}
+	"math"
+{{- end}}
+
+	"github.com/philpearl/statichash"
+)
+
+// ValueSize is the size in bytes of each value in the table
+const ValueSize = {{.ValueSize}}
+
+// Table is a table whose values are laid out as in {{.Source}}
+type Table struct {
+	r *statichash.Read
+}
+
+// Open opens the table saved in filename. It fails if the values in the table are not ValueSize bytes.
+func Open(filename string, opts ...statichash.ReadOption) (*Table, error) {
+	r, err := statichash.NewFrom(filename, append(opts, statichash.WithValueSize(ValueSize))...)
+	if err != nil {
+		return nil, err
+	}
+	return &Table{r: r}, nil
+}
+
+// Close closes the table. Values from it can't be used afterwards.
+func (t *Table) Close() error {
+	return t.r.Close()
+}
+
+// Read returns the underlying table
+func (t *Table) Read() *statichash.Read {
+	return t.r
+}
+
+// Len returns the number of entries in the table
+func (t *Table) Len() int {
+	return t.r.Len()
+}
+
+// Get returns the value for key, and whether the key was found
+func (t *Table) Get(key string) (Value, bool) {
+	v, ok := t.r.GetValue(key)
+	return Value(v), ok
+}
+
+// Value is a value from the table. It refers directly to the table's memory, so is only valid until the table
+// is closed.
+type Value []byte
+{{range .Accessors}}
+// {{.Method}} returns the {{.Field.Name}} field ({{.Field.Type}} at offset {{.Field.Offset}})
+func (v Value) {{.Method}}() {{.Type}} {
+	return {{.Expr}}
+}
+{{end -}}
+`))
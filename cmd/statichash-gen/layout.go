@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/philpearl/statichash"
+)
+
+// layout describes the values the generated code reads
+type layout struct {
+	// source describes where the layout came from, for the generated comments
+	source    string
+	valueSize int
+	schema    statichash.Schema
+}
+
+// layoutFromSchema reads a JSON schema file
+func layoutFromSchema(filename string) (layout, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return layout{}, err
+	}
+	var s struct {
+		ValueSize int `json:"valueSize"`
+		statichash.Schema
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return layout{}, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	if s.ValueSize <= 0 {
+		return layout{}, fmt.Errorf("%s: valueSize must be positive", filename)
+	}
+	return layout{source: "schema " + filepath.Base(filename), valueSize: s.ValueSize, schema: s.Schema}, nil
+}
+
+// layoutFromStruct finds the type typeName in the Go source file filename and lays it out as the gc compiler
+// does for this architecture, in the same way as statichash.SchemaOf.
+func layoutFromStruct(filename, typeName string) (layout, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return layout{}, err
+	}
+	// Errors elsewhere in the file, such as from packages the importer can't find, don't matter unless they
+	// affect the type we want, which we check as we lay it out
+	conf := types.Config{Importer: importer.Default(), Error: func(error) {}}
+	pkg, _ := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return layout{}, fmt.Errorf("%s has no type %s", filename, typeName)
+	}
+
+	w := walker{sizes: types.SizesFor("gc", runtime.GOARCH)}
+	typ := obj.Type()
+	if st, ok := typ.Underlying().(*types.Struct); ok {
+		err = w.addStruct("", st, 0)
+	} else {
+		err = w.addField("value", typ, 0)
+	}
+	if err != nil {
+		return layout{}, fmt.Errorf("%s: %w", typeName, err)
+	}
+	return layout{
+		source:    fmt.Sprintf("type %s in %s", typeName, filepath.Base(filename)),
+		valueSize: int(w.sizes.Sizeof(typ)),
+		schema:    w.schema,
+	}, nil
+}
+
+// walker builds a schema from a type checked by go/types
+type walker struct {
+	sizes  types.Sizes
+	schema statichash.Schema
+}
+
+func (w *walker) addStruct(prefix string, st *types.Struct, offset int64) error {
+	fields := make([]*types.Var, st.NumFields())
+	for i := range fields {
+		fields[i] = st.Field(i)
+	}
+	offsets := w.sizes.Offsetsof(fields)
+	for i, f := range fields {
+		if f.Name() == "_" {
+			continue
+		}
+		if inner, ok := f.Type().Underlying().(*types.Struct); ok {
+			if err := w.addStruct(prefix+f.Name()+".", inner, offset+offsets[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.addField(prefix+f.Name(), f.Type(), offset+offsets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) addField(name string, typ types.Type, offset int64) error {
+	f := statichash.Field{Name: name, Offset: int(offset)}
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			f.Type = statichash.FieldBool
+		case types.Int8:
+			f.Type = statichash.FieldInt8
+		case types.Int16:
+			f.Type = statichash.FieldInt16
+		case types.Int32:
+			f.Type = statichash.FieldInt32
+		case types.Int64:
+			f.Type = statichash.FieldInt64
+		case types.Int:
+			f.Type = w.intType(typ, statichash.FieldInt32, statichash.FieldInt64)
+		case types.Uint8:
+			f.Type = statichash.FieldUint8
+		case types.Uint16:
+			f.Type = statichash.FieldUint16
+		case types.Uint32:
+			f.Type = statichash.FieldUint32
+		case types.Uint64:
+			f.Type = statichash.FieldUint64
+		case types.Uint, types.Uintptr:
+			f.Type = w.intType(typ, statichash.FieldUint32, statichash.FieldUint64)
+		case types.Float32:
+			f.Type = statichash.FieldFloat32
+		case types.Float64:
+			f.Type = statichash.FieldFloat64
+		case types.Complex64, types.Complex128:
+			f.Type = statichash.FieldBytes
+			f.Size = int(w.sizes.Sizeof(typ))
+		default:
+			return fmt.Errorf("field %s is a %s, which can't be stored in a table", name, typ)
+		}
+	case *types.Array:
+		if !pointerFree(t) {
+			return fmt.Errorf("field %s is a %s, which contains pointers", name, typ)
+		}
+		f.Type = statichash.FieldBytes
+		f.Size = int(w.sizes.Sizeof(typ))
+	default:
+		return fmt.Errorf("field %s is a %s, which can't be stored in a table", name, typ)
+	}
+	w.schema.Fields = append(w.schema.Fields, f)
+	return nil
+}
+
+func (w *walker) intType(typ types.Type, small, large statichash.FieldType) statichash.FieldType {
+	if w.sizes.Sizeof(typ) == 4 {
+		return small
+	}
+	return large
+}
+
+// pointerFree returns true if typ contains no pointers
+func pointerFree(typ types.Type) bool {
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		return t.Info()&(types.IsBoolean|types.IsNumeric) != 0
+	case *types.Array:
+		return pointerFree(t.Elem())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !pointerFree(t.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
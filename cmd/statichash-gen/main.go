@@ -0,0 +1,82 @@
+// Command statichash-gen generates a package of typed accessors for the values of a statichash table. The
+// accessors read each field straight from the value's bytes at its fixed offset, so code using the generated
+// package needs neither unsafe nor a copy of the value type, and the layout is defined in one place.
+//
+// The layout comes either from a Go struct
+//
+//	statichash-gen -struct value.go -type Value -package lookup -o lookup/table.go
+//
+// or from a JSON schema file with the value size and the fields as statichash.Schema encodes them
+//
+//	{"valueSize": 16, "fields": [{"name": "Count", "type": "int64", "offset": 0}, ...]}
+//
+// The generated package has Open, which checks the table's value size, a Table type with Get, and a Value type
+// with a method for each field.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		schemaFile = flag.String("schema", "", "JSON schema file describing the values")
+		structFile = flag.String("struct", "", "Go source file containing the value type")
+		typeName   = flag.String("type", "", "name of the value type in the -struct file")
+		pkg        = flag.String("package", "", "name of the generated package (default the directory of -o)")
+		out        = flag.String("o", "", "file to write (default standard output)")
+	)
+	flag.Parse()
+
+	if err := run(*schemaFile, *structFile, *typeName, *pkg, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "statichash-gen: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+func run(schemaFile, structFile, typeName, pkg, out string) error {
+	var l layout
+	var err error
+	switch {
+	case schemaFile != "" && structFile == "":
+		l, err = layoutFromSchema(schemaFile)
+	case structFile != "" && schemaFile == "":
+		if typeName == "" {
+			return fmt.Errorf("-struct needs -type")
+		}
+		l, err = layoutFromStruct(structFile, typeName)
+	default:
+		return fmt.Errorf("give exactly one of -schema and -struct")
+	}
+	if err != nil {
+		return err
+	}
+	if err := l.schema.Check(l.valueSize); err != nil {
+		return err
+	}
+
+	if pkg == "" {
+		if out == "" {
+			return fmt.Errorf("-package is needed when writing to standard output")
+		}
+		abs, err := filepath.Abs(out)
+		if err != nil {
+			return err
+		}
+		pkg = strings.ReplaceAll(filepath.Base(filepath.Dir(abs)), "-", "_")
+	}
+
+	src, err := generate(pkg, l)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/philpearl/statichash"
+)
+
+func runCmp(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected 2 table files, got %d arguments", len(args))
+	}
+
+	a, err := statichash.NewFrom(args[0])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[0], err)
+	}
+	defer a.Close()
+
+	b, err := statichash.NewFrom(args[1])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[1], err)
+	}
+	defer b.Close()
+
+	if !statichash.Equal(a, b) {
+		fmt.Printf("%s %s differ\n", args[0], args[1])
+		return errDiffer
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/philpearl/statichash"
+	bolt "go.etcd.io/bbolt"
+)
+
+// walkFunc calls fn with each key and value in a store. The key and value are only valid during the call.
+type walkFunc func(fn func(key, value []byte) error) error
+
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	var (
+		from       = fs.String("from", "", "type of store to read: bolt or badger")
+		bucket     = fs.String("bucket", "", "bucket to convert, for bolt")
+		valueSize  = fs.Int("value-size", 0, "size of every value in bytes. Values are stored as blobs of any length if neither this nor -schema is given")
+		schemaFile = fs.String("schema", "", "JSON schema file giving the valueSize and fields of the values")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected a store and an output file, got %d arguments", fs.NArg())
+	}
+	store, out := fs.Arg(0), fs.Arg(1)
+
+	var walk walkFunc
+	switch *from {
+	case "bolt":
+		if *bucket == "" {
+			return errors.New("-from bolt needs -bucket")
+		}
+		walk = walkBolt(store, *bucket)
+	case "badger":
+		walk = walkBadger(store)
+	default:
+		return fmt.Errorf("-from must be bolt or badger, not %q", *from)
+	}
+
+	var opts []statichash.Option
+	size := *valueSize
+	if *schemaFile != "" {
+		if size != 0 {
+			return errors.New("give at most one of -value-size and -schema")
+		}
+		schema, schemaSize, err := readSchema(*schemaFile)
+		if err != nil {
+			return err
+		}
+		size = schemaSize
+		opts = append(opts, statichash.WithSchema(schema))
+	}
+	if size < 0 {
+		return fmt.Errorf("-value-size must be positive, not %d", size)
+	}
+	blobs := size == 0
+	if blobs {
+		opts = append(opts, statichash.WithBlobValues())
+		size = 8
+	}
+
+	// The first pass finds how large the table needs to be, and checks the values fit
+	var count int
+	var keyLength int64
+	if err := walk(func(key, value []byte) error {
+		count++
+		keyLength += int64(len(key))
+		if blobs {
+			// Blobs are stored with the keys, each with its length
+			keyLength += int64(len(value) + len(binary.AppendVarint(nil, int64(len(value)))))
+		} else if len(value) != size {
+			return fmt.Errorf("value for key %q is %d bytes, not %d", key, len(value), size)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("reading %s: %w", store, err)
+	}
+
+	t := statichash.New(max(count, 1), int64(size), keyLength, opts...)
+	if err := walk(func(key, value []byte) error {
+		if blobs {
+			return t.SetBytes(string(key), value)
+		}
+		return t.Set(string(key), unsafe.Pointer(unsafe.SliceData(value)))
+	}); err != nil {
+		return fmt.Errorf("converting %s: %w", store, err)
+	}
+	t.Finalize()
+	fmt.Printf("converted %d entries\n", t.Len())
+
+	return writeTable(t, out)
+}
+
+// readSchema reads a schema file in the format statichash-gen uses, which adds the value size to the Schema
+func readSchema(filename string) (statichash.Schema, int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return statichash.Schema{}, 0, err
+	}
+	var s struct {
+		ValueSize int `json:"valueSize"`
+		statichash.Schema
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return statichash.Schema{}, 0, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	if s.ValueSize <= 0 {
+		return statichash.Schema{}, 0, fmt.Errorf("%s: valueSize must be positive", filename)
+	}
+	if err := s.Schema.Check(s.ValueSize); err != nil {
+		return statichash.Schema{}, 0, fmt.Errorf("%s: %w", filename, err)
+	}
+	return s.Schema, s.ValueSize, nil
+}
+
+// walkBolt walks the keys and values of a bucket in a Bolt database. Nested buckets are skipped.
+func walkBolt(filename, bucket string) walkFunc {
+	return func(fn func(key, value []byte) error) error {
+		db, err := bolt.Open(filename, 0, &bolt.Options{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(bucket))
+			if b == nil {
+				return fmt.Errorf("no bucket %q", bucket)
+			}
+			return b.ForEach(func(k, v []byte) error {
+				if v == nil {
+					return nil
+				}
+				return fn(k, v)
+			})
+		})
+	}
+}
+
+// walkBadger walks the keys and values of a Badger database
+func walkBadger(dir string) walkFunc {
+	return func(fn func(key, value []byte) error) error {
+		db, err := badger.Open(badger.DefaultOptions(dir).WithReadOnly(true).WithLogger(nil))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+			for it.Rewind(); it.Valid(); it.Next() {
+				item := it.Item()
+				if err := item.Value(func(v []byte) error {
+					return fn(item.Key(), v)
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/philpearl/statichash"
+)
+
+func runDiff(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected 2 table files, got %d arguments", len(args))
+	}
+
+	a, err := statichash.NewFrom(args[0])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[0], err)
+	}
+	defer a.Close()
+
+	b, err := statichash.NewFrom(args[1])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[1], err)
+	}
+	defer b.Close()
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	var differ bool
+	prefix := map[statichash.DiffKind]string{
+		statichash.Added:   "+",
+		statichash.Removed: "-",
+		statichash.Changed: "~",
+	}
+	if err := statichash.Diff(a, b, func(kind statichash.DiffKind, key string) bool {
+		differ = true
+		fmt.Fprintf(w, "%s %s\n", prefix[kind], key)
+		return true
+	}); err != nil {
+		return err
+	}
+
+	if differ {
+		return errDiffer
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Command statichash inspects and manipulates hash table files created with github.com/philpearl/statichash.
+//
+//	statichash <command> [arguments]
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"apply":    {usage: "apply <old> <patch> <out>\tapply a patch to a table file, writing the result to out", run: runApply},
+	"cmp":      {usage: "cmp <a> <b>\tcheck whether two table files hold the same keys and values", run: runCmp},
+	"convert":  {usage: "convert -from bolt|badger [-bucket B] [-value-size N | -schema F] <store> <out>\tconvert a Bolt bucket or Badger database into a table file", run: runConvert},
+	"diff":     {usage: "diff <old> <new>\tlist keys added, removed and changed between two table files", run: runDiff},
+	"migrate":  {usage: "migrate [-reset] [-load-factor F] [-seed N] [-strategy S] [layout flags] <in> <out>\trewrite a table file in the current format, optionally with a new seed, load factor or layout", run: runMigrate},
+	"patch":    {usage: "patch <old> <new> <patch>\twrite a patch that turns old into new", run: runPatch},
+	"rekey":    {usage: "rekey <table> <out> <transform>...\trewrite the keys of a table with lower, upper, trim-space, trim-prefix=P, trim-suffix=S, add-prefix=P, add-suffix=S or sha256", run: runRekey},
+	"salvage":  {usage: "salvage <damaged> <out>\trecover the intact entries of a truncated or corrupt table file", run: runSalvage},
+	"stats":    {usage: "stats <table>\tprint capacity, load factor, probe and key length distributions and section sizes", run: runStats},
+	"validate": {usage: "validate <table>\tcheck every entry of a table file for corruption", run: runValidate},
+}
+
+// errDiffer is returned by commands that compare tables when they find a difference. It results in an exit
+// status of 1 with no further message, like diff(1).
+var errDiffer = errors.New("tables differ")
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		if errors.Is(err, errDiffer) {
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "statichash %s: %v\n", os.Args[1], err)
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: statichash <command> [arguments]\n\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
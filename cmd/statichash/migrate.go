@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+
+	"github.com/philpearl/statichash"
+)
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	var (
+		reset          = fs.Bool("reset", false, "start from the default options rather than those of the input table")
+		loadFactor     = fs.Float64("load-factor", 0, "fill at most this fraction of the slots. By default the table keeps its number of slots")
+		seed           = fs.Uint64("seed", 0, "hash keys with this seed rather than as the input table does")
+		strategy       = fs.String("strategy", "", "how to place keys in slots: linear, cuckoo or hopscotch")
+		fingerprints   = fs.Bool("fingerprints", false, "store a fingerprint of each key's hash")
+		insertionOrder = fs.Bool("insertion-order", false, "record the order of the entries")
+		sortedIndex    = fs.Bool("sorted-index", false, "store the entries in key order")
+		pageAligned    = fs.Bool("page-aligned", false, "start each section on a 64KB boundary")
+		interleaved    = fs.Bool("interleaved", false, "store each slot's hash, fingerprint and key offset together")
+		inlineValues   = fs.Bool("inline-values", false, "store values of up to 16 bytes in their slots")
+		hashOnly       = fs.Bool("hash-only", false, "store the hash of each key rather than the key")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected an input table and an output file, got %d arguments", fs.NArg())
+	}
+	in, out := fs.Arg(0), fs.Arg(1)
+
+	r, err := statichash.NewFrom(in)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", in, err)
+	}
+	defer r.Close()
+
+	var opts []statichash.Option
+	target := statichash.StrategyLinear
+	if !*reset {
+		opts = r.Options()
+		target = r.Info().Strategy
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if set["strategy"] {
+		s, err := parseStrategy(*strategy)
+		if err != nil {
+			return err
+		}
+		if s != target && target != statichash.StrategyLinear {
+			return fmt.Errorf("%s is a %s table. Use -reset to change its strategy", in, target)
+		}
+		target = s
+	}
+	switch target {
+	case statichash.StrategyCuckoo:
+		opts = append(opts, statichash.WithCuckoo())
+	case statichash.StrategyHopscotch:
+		// A key may not fit near enough to its home slot in a table of the same size
+		opts = append(opts, statichash.WithHopscotch(), statichash.WithAutoGrow())
+	}
+
+	if set["seed"] {
+		opts = append(opts, statichash.WithSeed(*seed))
+	}
+	for _, f := range []struct {
+		on  bool
+		opt func() statichash.Option
+	}{
+		{*fingerprints, statichash.WithFingerprints},
+		{*insertionOrder, statichash.WithInsertionOrder},
+		{*sortedIndex, statichash.WithSortedIndex},
+		{*pageAligned, statichash.WithPageAlignedSections},
+		{*interleaved, statichash.WithInterleavedSlots},
+		{*inlineValues, statichash.WithInlineValues},
+		{*hashOnly, statichash.WithHashOnly},
+	} {
+		if f.on {
+			opts = append(opts, f.opt())
+		}
+	}
+
+	// New sizes tables by the entries they hold, except that it gives cuckoo tables enough slots for their
+	// lower load
+	capacity := r.NumSlots()
+	if target == statichash.StrategyCuckoo {
+		capacity = r.Len()
+	}
+	if set["load-factor"] {
+		if *loadFactor <= 0 || *loadFactor > 1 {
+			return fmt.Errorf("-load-factor must be more than 0 and at most 1, not %g", *loadFactor)
+		}
+		if target == statichash.StrategyCuckoo {
+			return errors.New("cuckoo tables have a fixed load factor")
+		}
+		capacity = int(math.Ceil(float64(r.Len()) / *loadFactor))
+	}
+
+	t, err := statichash.Migrate(r, capacity, opts...)
+	if err != nil {
+		return fmt.Errorf("migrating %s: %w", in, err)
+	}
+	fmt.Printf("migrated %d entries into %d slots\n", t.Len(), t.NumSlots())
+
+	return writeTable(t, out)
+}
+
+// parseStrategy returns the Strategy called name
+func parseStrategy(name string) (statichash.Strategy, error) {
+	for _, s := range []statichash.Strategy{statichash.StrategyLinear, statichash.StrategyCuckoo, statichash.StrategyHopscotch} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("-strategy must be linear, cuckoo or hopscotch, not %q", name)
+}
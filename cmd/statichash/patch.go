@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/philpearl/statichash"
+)
+
+func runPatch(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("expected <old> <new> <patch>, got %d arguments", len(args))
+	}
+
+	a, err := statichash.NewFrom(args[0])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[0], err)
+	}
+	defer a.Close()
+
+	b, err := statichash.NewFrom(args[1])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[1], err)
+	}
+	defer b.Close()
+
+	f, err := os.Create(args[2])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := statichash.WritePatch(f, a, b); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func runApply(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("expected <old> <patch> <out>, got %d arguments", len(args))
+	}
+
+	base, err := statichash.NewFrom(args[0])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[0], err)
+	}
+	defer base.Close()
+
+	p, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	t, err := statichash.ApplyPatch(base, bufio.NewReader(p))
+	if err != nil {
+		return fmt.Errorf("applying %s: %w", args[1], err)
+	}
+
+	return writeTable(t, args[2])
+}
+
+// writeTable saves t to a new file called name
+func writeTable(t *statichash.Write, name string) error {
+	return t.WriteFile(name)
+}
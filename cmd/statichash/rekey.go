@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/philpearl/statichash"
+)
+
+// keyTransform returns the transformation described by spec. Those that take an argument are given as
+// name=arg.
+func keyTransform(spec string) (func(key string) string, error) {
+	name, arg, _ := strings.Cut(spec, "=")
+	switch name {
+	case "lower":
+		return strings.ToLower, nil
+	case "upper":
+		return strings.ToUpper, nil
+	case "trim-space":
+		return strings.TrimSpace, nil
+	case "trim-prefix":
+		return func(key string) string { return strings.TrimPrefix(key, arg) }, nil
+	case "trim-suffix":
+		return func(key string) string { return strings.TrimSuffix(key, arg) }, nil
+	case "add-prefix":
+		return func(key string) string { return arg + key }, nil
+	case "add-suffix":
+		return func(key string) string { return key + arg }, nil
+	case "sha256":
+		return func(key string) string {
+			sum := sha256.Sum256([]byte(key))
+			return hex.EncodeToString(sum[:])
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", name)
+}
+
+func runRekey(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("expected <table> <out> and at least one transform, got %d arguments", len(args))
+	}
+
+	var fns []func(string) string
+	for _, spec := range args[2:] {
+		fn, err := keyTransform(spec)
+		if err != nil {
+			return err
+		}
+		fns = append(fns, fn)
+	}
+
+	r, err := statichash.NewFrom(args[0])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[0], err)
+	}
+	defer r.Close()
+
+	t, err := statichash.Rekey(r, func(key string) string {
+		for _, fn := range fns {
+			key = fn(key)
+		}
+		return key
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("rekeying %s: %w", args[0], err)
+	}
+	return writeTable(t, args[1])
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/philpearl/statichash"
+)
+
+func runSalvage(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a damaged table file and an output file, got %d arguments", len(args))
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	t, lost, err := statichash.Salvage(data)
+	if err != nil {
+		return fmt.Errorf("salvaging %s: %w", args[0], err)
+	}
+	fmt.Printf("recovered %d entries, lost %d\n", t.Len(), lost)
+
+	return writeTable(t, args[1])
+}
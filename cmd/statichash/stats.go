@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/philpearl/statichash"
+)
+
+func runStats(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 table file, got %d arguments", len(args))
+	}
+
+	r, err := statichash.NewFrom(args[0])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[0], err)
+	}
+	defer r.Close()
+
+	s := r.Stats()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "capacity\t%d\n", s.Slots)
+	fmt.Fprintf(tw, "items\t%d\n", s.Entries)
+	fmt.Fprintf(tw, "load factor\t%.3f\n", s.LoadFactor)
+	fmt.Fprintf(tw, "file length\t%d\n", r.FileLen())
+
+	// Short probes are listed individually, and longer ones in powers of two so a badly clustered table
+	// doesn't produce pages of output
+	fmt.Fprintf(tw, "probe lengths\tmax %d\n", max(len(s.ProbeLengths)-1, 0))
+	for lo := 1; lo < len(s.ProbeLengths); {
+		hi := lo
+		if lo > 8 {
+			hi = min(2*(lo-1), len(s.ProbeLengths)-1)
+		}
+		var n int
+		for _, c := range s.ProbeLengths[lo : hi+1] {
+			n += c
+		}
+		if n > 0 {
+			if hi == lo {
+				fmt.Fprintf(tw, "  %d\t%d\t%.1f%%\n", lo, n, percent(n, s.Entries))
+			} else {
+				fmt.Fprintf(tw, "  %d-%d\t%d\t%.1f%%\n", lo, hi, n, percent(n, s.Entries))
+			}
+		}
+		lo = hi + 1
+	}
+
+	fmt.Fprintf(tw, "key lengths\tmin %d\tmax %d\tmean %.1f\n", s.MinKeyLength, s.MaxKeyLength, s.MeanKeyLength)
+	for i, n := range s.KeyLengths {
+		if n == 0 {
+			continue
+		}
+		if i == 0 {
+			fmt.Fprintf(tw, "  0\t%d\t%.1f%%\n", n, percent(n, s.Entries))
+		} else {
+			fmt.Fprintf(tw, "  %d-%d\t%d\t%.1f%%\n", 1<<(i-1), 1<<i-1, n, percent(n, s.Entries))
+		}
+	}
+
+	fmt.Fprintf(tw, "sections\n")
+	for _, sec := range s.Sections {
+		fmt.Fprintf(tw, "  %s\t%d bytes\t%.1f%%\n", sec.Section, sec.Length, 100*float64(sec.Length)/float64(r.FileLen()))
+	}
+	return tw.Flush()
+}
+
+// percent returns n as a percentage of total
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/philpearl/statichash"
+)
+
+func runValidate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 table file, got %d arguments", len(args))
+	}
+
+	r, err := statichash.NewFrom(args[0])
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", args[0], err)
+	}
+	defer r.Close()
+
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
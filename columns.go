@@ -0,0 +1,101 @@
+package statichash
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// columnOffset returns the offset within the values section of column c of slot index
+func (t *table) columnOffset(c, index int) int {
+	return t.numItems*t.columnStart[c] + index*t.columns[c]
+}
+
+// column returns the bytes of column c of the value in slot index. If the table is mapped in windows and the
+// column can't be read the bytes are zero and the error is returned.
+func (t *table) column(c, index int) ([]byte, error) {
+	offset := t.columnOffset(c, index)
+	if t.win != nil {
+		return t.win.copy(t.layout.values+int64(offset), int64(t.columns[c]))
+	}
+	return t.values[offset : offset+t.columns[c]], nil
+}
+
+// setValue sets the value in slot index
+func (t *Write) setValue(index int, value []byte) {
+	if t.columns == nil {
+		copy(t.values[index*t.valueStride:index*t.valueStride+t.valueSize], value)
+		return
+	}
+	for c, start := range t.columnStart {
+		copy(t.values[t.columnOffset(c, index):], value[start:start+t.columns[c]])
+	}
+}
+
+// Columns returns the width of each column of the values of a table built WithColumns, or nil if the table
+// isn't columnar
+func (t *table) Columns() []int {
+	return t.columns
+}
+
+// GetColumn returns a pointer to column col of the value for key in a table built WithColumns. Only that
+// column's memory is touched, besides the hash and key. If the table is mapped in windows the pointer is to a
+// copy of the column. GetColumn panics if col is not a column of the table.
+func (t *table) GetColumn(key string, col int) (val unsafe.Pointer, ok bool) {
+	if t == nil {
+		return nil, false
+	}
+	if col < 0 || col >= len(t.columns) {
+		panic(fmt.Sprintf("statichash: column %d out of range for table with %d columns", col, len(t.columns)))
+	}
+	index, found := t.find(key, t.hashKey(key))
+	if !found {
+		return nil, false
+	}
+	v, err := t.column(col, index)
+	if err != nil {
+		return nil, false
+	}
+	return unsafe.Pointer(unsafe.SliceData(v)), true
+}
+
+// ColumnIterator walks a single column of a table built WithColumns. Create one with ScanColumn.
+//
+//	it := t.ScanColumn(2)
+//	for it.Next() {
+//	   total += *(*float64)(it.Value())
+//	}
+type ColumnIterator struct {
+	t     *table
+	col   int
+	index int
+}
+
+// ScanColumn returns a ColumnIterator over column col of every entry in the table. The entries are visited in
+// slot order, so the column is read sequentially. ScanColumn panics if col is not a column of the table.
+func (t *table) ScanColumn(col int) *ColumnIterator {
+	if col < 0 || col >= len(t.columns) {
+		panic(fmt.Sprintf("statichash: column %d out of range for table with %d columns", col, len(t.columns)))
+	}
+	return &ColumnIterator{t: t, col: col, index: -1}
+}
+
+// Next moves the iterator on to the next entry. It returns false when there are no more entries.
+func (it *ColumnIterator) Next() bool {
+	for it.index++; it.index < it.t.numItems; it.index++ {
+		if it.t.hashAt(it.index) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns a pointer to the column of the current entry
+func (it *ColumnIterator) Value() unsafe.Pointer {
+	v, _ := it.t.column(it.col, it.index)
+	return unsafe.Pointer(unsafe.SliceData(v))
+}
+
+// Key returns the key of the current entry. Calling it means reading the key data as well as the column.
+func (it *ColumnIterator) Key() string {
+	return it.t.getKey(it.t.keyAt(it.index))
+}
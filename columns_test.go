@@ -0,0 +1,142 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type columnValue struct {
+	A int32
+	B int32
+	C float64
+}
+
+func buildColumnar(t *testing.T, n int, opts ...Option) *Write {
+	tb := New(n, int64(unsafe.Sizeof(columnValue{})), int64(n*10), append(opts, WithColumns(4, 4, 8))...)
+	for i := 0; i < n; i++ {
+		v := columnValue{A: int32(i), B: int32(-i), C: float64(i) / 2}
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&v)))
+	}
+	return tb
+}
+
+func TestColumns(t *testing.T) {
+	tb := buildColumnar(t, 3)
+	assert.Equal(t, []int{4, 4, 8}, tb.Columns())
+	assert.Equal(t, minColumnarSlots, tb.NumSlots())
+
+	// Each column is stored contiguously
+	index, found := tb.find("key2", tb.hashKey("key2"))
+	assert.True(t, found)
+	assert.Equal(t, float64(1), *(*float64)(unsafe.Pointer(&tb.values[8*minColumnarSlots+index*8])))
+
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 4, 8}, tr.Columns())
+
+	for i := 0; i < 3; i++ {
+		p, ok := tr.GetPtr(fmt.Sprintf("key%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, columnValue{A: int32(i), B: int32(-i), C: float64(i) / 2}, *(*columnValue)(p))
+	}
+
+	tw, err := NewFrom(writeTempTable(t, buildColumnar(t, 1000)), WithWindowedMapping(4096, 2))
+	assert.NoError(t, err)
+	defer tw.Close()
+	p, ok := tw.GetPtr("key999")
+	assert.True(t, ok)
+	assert.Equal(t, columnValue{A: 999, B: -999, C: 499.5}, *(*columnValue)(p))
+
+	assert.Nil(t, buildTable(t, 2).Columns())
+}
+
+func TestColumnsGrow(t *testing.T) {
+	tb := buildColumnar(t, 100, WithAutoGrow())
+	tb.grow(256, 2000)
+	assert.Equal(t, []int{4, 4, 8}, tb.Columns())
+	for i := 0; i < 100; i++ {
+		p, ok := tb.GetPtr(fmt.Sprintf("key%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, columnValue{A: int32(i), B: int32(-i), C: float64(i) / 2}, *(*columnValue)(p))
+	}
+}
+
+func TestColumnsInvalid(t *testing.T) {
+	assert.Panics(t, func() { New(10, 16, 100, WithColumns(4, 4)) })
+	assert.Panics(t, func() { New(10, 16, 100, WithColumns()) })
+	assert.Panics(t, func() { New(10, 16, 100, WithColumns(20, -4)) })
+
+	var buf bytes.Buffer
+	tb := buildColumnar(t, 3)
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	editHeader(t, buf.Bytes(), func(h *header) { h.columns[0] = 5 })
+	_, err = NewFromBytes(buf.Bytes())
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestColumnsPatch(t *testing.T) {
+	read := func(tb *Write) *Read {
+		var buf bytes.Buffer
+		tb.Finalize()
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		r, err := NewFromBytes(buf.Bytes())
+		assert.NoError(t, err)
+		return r
+	}
+	a, b := read(buildColumnar(t, 10)), read(buildColumnar(t, 20))
+
+	var patch bytes.Buffer
+	assert.NoError(t, WritePatch(&patch, a, b))
+	w, err := ApplyPatch(a, &patch)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 4, 8}, w.Columns())
+	assert.True(t, Equal(b, read(w)))
+
+	assert.Error(t, WritePatch(&patch, a, read(New(10, 16, 100))))
+}
+
+func TestGetColumn(t *testing.T) {
+	tb := buildColumnar(t, 100)
+	tr, err := NewFrom(writeTempTable(t, tb))
+	assert.NoError(t, err)
+	defer tr.Close()
+	tw, err := NewFrom(writeTempTable(t, tb), WithWindowedMapping(4096, 1))
+	assert.NoError(t, err)
+	defer tw.Close()
+
+	for _, r := range []*Read{tr, tw} {
+		p, ok := r.GetColumn("key42", 2)
+		assert.True(t, ok)
+		assert.Equal(t, 21.0, *(*float64)(p))
+		p, ok = r.GetColumn("key42", 1)
+		assert.True(t, ok)
+		assert.Equal(t, int32(-42), *(*int32)(p))
+		_, ok = r.GetColumn("key100", 0)
+		assert.False(t, ok)
+
+		var total float64
+		var n int
+		for it := r.ScanColumn(2); it.Next(); n++ {
+			total += *(*float64)(it.Value())
+			var i int
+			fmt.Sscanf(it.Key(), "key%d", &i)
+			assert.Equal(t, float64(i)/2, *(*float64)(it.Value()))
+		}
+		assert.Equal(t, 100, n)
+		assert.Equal(t, 2475.0, total)
+	}
+
+	assert.Panics(t, func() { tr.GetColumn("key1", 3) })
+	assert.Panics(t, func() { buildTable(t, 2).ScanColumn(0) })
+}
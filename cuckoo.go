@@ -0,0 +1,213 @@
+package statichash
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+/*
+In a table built WithCuckoo every key is in one of two slots. The first is its home slot, as for linear probing,
+and the second is chosen by seededHash with the cuckoo seed from the header. A lookup examines those two slots
+and no others.
+
+When Set adds a key and both its slots are taken, it evicts the entry in one of them, which then moves to its
+own other slot, possibly evicting another entry, and so on until an entry lands in an empty slot. If that goes on
+for too long the table is rehashed with a new cuckoo seed. If a few seeds all fail the table is doubled in size.
+Two-choice cuckoo hashing rarely fails while the table is less than half full, so we keep cuckoo tables below
+45% full.
+*/
+
+const (
+	// cuckooLoadNum/cuckooLoadDen is the proportion of slots a cuckoo table may fill. It is a fraction rather
+	// than a float so that sizes convert exactly between slots and entries.
+	cuckooLoadNum, cuckooLoadDen = 9, 20
+	// cuckooMaxKicks is how many entries Set moves to make room for a key before giving up and rehashing
+	cuckooMaxKicks = 500
+	// cuckooRehashes is how many cuckoo seeds we try before growing the table
+	cuckooRehashes = 16
+)
+
+// cuckooSize returns the number of slots a cuckoo table needs for the given number of entries. New rounds this
+// up to a power of 2.
+func cuckooSize(entries int) int {
+	return (entries*cuckooLoadDen + cuckooLoadNum - 1) / cuckooLoadNum
+}
+
+// cuckooCapacity returns the number of entries a cuckoo table with the given number of slots can hold
+func cuckooCapacity(slots int) int {
+	return slots * cuckooLoadNum / cuckooLoadDen
+}
+
+// cuckooSlots returns the two slots a key with slot hash hashVal may be in
+func (t *table) cuckooSlots(key string, hashVal hash) (int, int) {
+	mask := t.numItems - 1
+	return int(hashVal) & mask, int(seededHash(t.cuckooSeed, key)) & mask
+}
+
+// findCuckoo is find for a table built WithCuckoo. If the key isn't present it always returns -1, as where a
+// new key goes depends on moving other entries.
+func (t *table) findCuckoo(key string, h uint64) (int, bool) {
+	mask := t.numItems - 1
+	// We only hash the key again if it isn't in its home slot
+	if first := int(slotHash(h)) & mask; t.matches(first, key, h) {
+		return first, true
+	}
+	if second := int(seededHash(t.cuckooSeed, key)) & mask; t.matches(second, key, h) {
+		return second, true
+	}
+	return -1, false
+}
+
+// cuckooEntry is an entry that is being placed in a cuckoo table
+type cuckooEntry struct {
+	hash        hash
+	fingerprint uint8
+	key         keyOffset
+	value       []byte
+}
+
+// addCuckoo is Set for a key that isn't yet in a table built WithCuckoo
+func (t *Write) addCuckoo(key string, h uint64, val unsafe.Pointer) error {
+	if t.count >= cuckooCapacity(t.numItems) {
+		if !t.autoGrow {
+			return fmt.Errorf("%w: no slot for key %q", ErrTableFull, key)
+		}
+		t.grow(t.numItems*2, int64(len(t.keyData))*2)
+	}
+	if !t.hasKeySpace(key) {
+		if !t.autoGrow {
+			return fmt.Errorf("%w: no room for key %q", ErrKeySpaceExhausted, key)
+		}
+		t.growKeyData(len(key))
+	}
+
+	e := cuckooEntry{
+		hash:        slotHash(h),
+		fingerprint: fingerprint(h),
+		key:         t.addKey(key),
+		value:       unsafe.Slice((*byte)(val), t.valueSize),
+	}
+	t.count++
+	if homeless, ok := t.placeCuckoo(e); !ok {
+		t.refillCuckoo(append(t.cuckooEntries(), homeless))
+	}
+	t.ingested()
+	return nil
+}
+
+// placeCuckoo puts e in one of its two slots, moving the entries in the way to their other slots. If it gives
+// up it returns the entry left without a slot, which may not be e.
+func (t *Write) placeCuckoo(e cuckooEntry) (cuckooEntry, bool) {
+	first, second := t.cuckooSlots(t.getKey(e.key), e.hash)
+	slot := first
+	if t.hashAt(first) != 0 && t.hashAt(second) == 0 {
+		slot = second
+	}
+	for range cuckooMaxKicks {
+		if t.hashAt(slot) == 0 {
+			t.putCuckoo(slot, e)
+			return cuckooEntry{}, true
+		}
+		evicted := t.entryAt(slot)
+		t.putCuckoo(slot, e)
+		e = evicted
+
+		// The evicted entry moves to whichever of its slots it wasn't in
+		first, second = t.cuckooSlots(t.getKey(e.key), e.hash)
+		if slot == first {
+			slot = second
+		} else {
+			slot = first
+		}
+	}
+	return e, false
+}
+
+// putCuckoo writes e into slot
+func (t *Write) putCuckoo(slot int, e cuckooEntry) {
+	t.setSlot(slot, e.hash, e.fingerprint, e.key)
+	t.setValue(slot, e.value)
+}
+
+// entryAt returns the entry in slot, with a copy of its value
+func (t *Write) entryAt(slot int) cuckooEntry {
+	e := cuckooEntry{
+		hash:  t.hashAt(slot),
+		key:   t.keyAt(slot),
+		value: append([]byte(nil), t.value(slot)...),
+	}
+	if t.hasFingerprints() {
+		e.fingerprint = t.fingerprintAt(slot)
+	}
+	return e
+}
+
+// cuckooEntries returns every entry in the table
+func (t *Write) cuckooEntries() []cuckooEntry {
+	entries := make([]cuckooEntry, 0, t.count)
+	for slot := range t.numItems {
+		if t.hashAt(slot) != 0 {
+			entries = append(entries, t.entryAt(slot))
+		}
+	}
+	return entries
+}
+
+// refillCuckoo empties the slots and places entries in them again, trying new cuckoo seeds until every entry
+// fits. If none of cuckooRehashes seeds work the table is doubled in size, even if it wasn't built
+// WithAutoGrow.
+func (t *Write) refillCuckoo(entries []cuckooEntry) {
+	for {
+		for range cuckooRehashes {
+			t.cuckooSeed++
+			if t.fillCuckoo(entries) {
+				return
+			}
+		}
+		t.resizeCuckoo(t.numItems*2, int64(len(t.keyData)))
+	}
+}
+
+// fillCuckoo empties the slots and places entries in them, returning false if they don't all fit
+func (t *Write) fillCuckoo(entries []cuckooEntry) bool {
+	t.clearSlots()
+	clear(t.values)
+	for _, e := range entries {
+		if _, ok := t.placeCuckoo(e); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// resizeCuckoo replaces the table with an empty one with numItems slots and totalKeyLength bytes for keys,
+// keeping the key data and the count. The entries must then be placed again with refillCuckoo.
+func (t *Write) resizeCuckoo(numItems int, totalKeyLength int64) {
+	n := New(cuckooCapacity(numItems), int64(t.valueSize), totalKeyLength, t.options()...)
+	n.created = t.created
+	n.version = t.version
+	n.keyOffset = copy(n.keyData, t.keyData[:t.keyOffset])
+	n.count = t.count
+	n.cuckooSeed = t.cuckooSeed
+	t.table = n.table
+}
+
+// growCuckoo is grow for a table built WithCuckoo
+func (t *Write) growCuckoo(numItems int, totalKeyLength int64) {
+	entries := t.cuckooEntries()
+	t.resizeCuckoo(numItems, totalKeyLength)
+	t.refillCuckoo(entries)
+}
+
+// probeLength returns the number of slots a lookup examines to find the entry in slot i
+func (t *table) probeLength(i int) int {
+	mask := t.numItems - 1
+	home := int(t.hashAt(i)) & mask
+	if t.flags&flagCuckoo != 0 {
+		if home == i {
+			return 1
+		}
+		return 2
+	}
+	return (i-home)&mask + 1
+}
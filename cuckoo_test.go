@@ -0,0 +1,113 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCuckoo(t *testing.T) {
+	const n = 10000
+	tb := buildTable(t, n, WithCuckoo(), WithSeed(1), WithFingerprints())
+	assert.Equal(t, n, tb.Len())
+	assert.LessOrEqual(t, n, cuckooCapacity(tb.NumSlots()))
+	report := tb.Finalize()
+	assert.LessOrEqual(t, report.MaxProbeLength, 2)
+
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, tb.cuckooSeed, r.cuckooSeed)
+	assert.Equal(t, StrategyCuckoo, r.Info().Strategy)
+	assert.NoError(t, r.Validate())
+
+	for i := 0; i < n; i++ {
+		v, ok := r.GetPtr(fmt.Sprintf("key%d", n-i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+	_, ok := r.GetPtr("missing")
+	assert.False(t, ok)
+
+	s := r.Stats()
+	assert.Len(t, s.ProbeLengths, 3)
+	assert.Equal(t, n, s.ProbeLengths[1]+s.ProbeLengths[2])
+
+	// Moving an entry out of its slots is detected
+	bad := append([]byte(nil), buf.Bytes()...)
+	br, err := NewFromBytes(bad)
+	assert.NoError(t, err)
+	for i, h := range br.hashes {
+		if h != 0 {
+			j := (i + 1) & (br.numItems - 1)
+			br.hashes[i], br.hashes[j] = br.hashes[j], br.hashes[i]
+			br.keys[i], br.keys[j] = br.keys[j], br.keys[i]
+			br.fingerprints[i], br.fingerprints[j] = br.fingerprints[j], br.fingerprints[i]
+			break
+		}
+	}
+	assert.ErrorIs(t, br.Validate(), ErrCorrupt)
+}
+
+func TestCuckooRehash(t *testing.T) {
+	// Six entries can't all fit in four slots, so every seed fails and the table has to grow
+	tb := New(1, 8, 100, WithCuckoo(), WithSeed(2))
+	assert.Equal(t, 4, tb.NumSlots())
+	seed := tb.cuckooSeed
+	var entries []cuckooEntry
+	for i := 0; i < 6; i++ {
+		key := fmt.Sprintf("key%d", i)
+		h := tb.hashKey(key)
+		entries = append(entries, cuckooEntry{
+			hash:  slotHash(h),
+			key:   tb.addKey(key),
+			value: unsafe.Slice((*byte)(unsafe.Pointer(&i)), 8),
+		})
+		tb.count++
+	}
+	tb.refillCuckoo(entries)
+
+	assert.Greater(t, tb.NumSlots(), 4)
+	assert.NotEqual(t, seed, tb.cuckooSeed)
+	for i := 0; i < 6; i++ {
+		v, ok := tb.GetPtr(fmt.Sprintf("key%d", i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+}
+
+func TestCuckooGrow(t *testing.T) {
+	tb := New(4, 8, 10, WithCuckoo(), WithAutoGrow())
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&i)))
+	}
+	// Setting an existing key replaces its value
+	i := -1
+	assert.NoError(t, tb.Set("key7", unsafe.Pointer(&i)))
+	assert.Equal(t, 1000, tb.Len())
+	assert.Equal(t, 1, tb.Duplicates())
+	for i := 0; i < 1000; i++ {
+		v, ok := tb.GetPtr(fmt.Sprintf("key%d", i))
+		if assert.True(t, ok) && i != 7 {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+}
+
+func TestCuckooErrors(t *testing.T) {
+	tb := New(2, 8, 100, WithCuckoo())
+	var v int64
+	for i := 0; i < cuckooCapacity(tb.NumSlots()); i++ {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&v)))
+	}
+	assert.ErrorIs(t, tb.Set("one more", unsafe.Pointer(&v)), ErrTableFull)
+
+	assert.Panics(t, func() { New(10, 8, 100, WithCuckoo(), WithInsertionOrder()) })
+}
@@ -0,0 +1,75 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// The checks in this file make sure that every offset into a table's memory is in bounds and every pointer cast
+// is aligned, and panic with a description of what went wrong if not. They are only made if the package is
+// built with the statichash_debug tag, which is meant for tests and staging. Calls are wrapped in "if debug" so
+// that they cost nothing otherwise.
+
+// debugCheckSection checks that a section of count elements of size bytes each, starting at offset, fits within
+// a table of length bytes starting at dataStart, and is aligned for elements that need align.
+func debugCheckSection(name string, dataStart unsafe.Pointer, offset, count, size int64, align uintptr, length int64) {
+	if offset < 0 || count < 0 || offset > length || (size > 0 && count > (length-offset)/size) {
+		panic(fmt.Sprintf("statichash: debug: %s section of %d x %d bytes at offset %d overruns table of %d bytes", name, count, size, offset, length))
+	}
+	if p := uintptr(unsafe.Add(dataStart, offset)); p%align != 0 {
+		panic(fmt.Sprintf("statichash: debug: %s section at offset %d is at address %#x, which isn't aligned to %d bytes", name, offset, p, align))
+	}
+}
+
+// debugCheckLayout checks every section of l, as set up by setSections
+func (t *table) debugCheckLayout(dataStart unsafe.Pointer, l layout) {
+	n := int64(t.numItems)
+	if t.flags&flagInterleaved != 0 {
+		debugCheckSection("slots", dataStart, l.hashes, n, slotSize(t.flags, int64(t.valueSize)), unsafe.Alignof(slotRecord{}), l.length)
+	} else {
+		debugCheckSection("hashes", dataStart, l.hashes, n, int64(unsafe.Sizeof(hash(0))), unsafe.Alignof(hash(0)), l.length)
+		if t.flags&flagFingerprints != 0 {
+			debugCheckSection("fingerprints", dataStart, l.fingerprints, n, 1, 1, l.length)
+		}
+		debugCheckSection("keys", dataStart, l.keys, n, int64(unsafe.Sizeof(keyOffset(0))), unsafe.Alignof(keyOffset(0)), l.length)
+	}
+	if t.flags&flagInsertionOrder != 0 {
+		debugCheckSection("order", dataStart, l.order, n, int64(unsafe.Sizeof(slotIndex(0))), unsafe.Alignof(slotIndex(0)), l.length)
+	}
+	if t.flags&flagSortedIndex != 0 {
+		debugCheckSection("sorted", dataStart, l.sorted, n, int64(unsafe.Sizeof(slotIndex(0))), unsafe.Alignof(slotIndex(0)), l.length)
+	}
+	if t.flags&flagInlineValues == 0 {
+		debugCheckSection("values", dataStart, l.values, n, int64(t.valueSize), 1, l.length)
+	}
+	debugCheckSection("key data", dataStart, l.keyData, l.length-l.keyData, 1, 1, l.length)
+}
+
+// debugCheckSlot checks that index is a slot of the table
+func (t *table) debugCheckSlot(index int) {
+	if index < 0 || index >= t.numItems {
+		panic(fmt.Sprintf("statichash: debug: slot %d out of range for table of %d slots", index, t.numItems))
+	}
+}
+
+// debugCheckKey checks that a whole key, length and all, is stored at offset in the key data
+func (t *table) debugCheckKey(offset keyOffset) {
+	if offset < 0 || int64(offset) >= int64(len(t.keyData)) {
+		panic(fmt.Sprintf("statichash: debug: key offset %d out of range for %d bytes of key data", offset, len(t.keyData)))
+	}
+	length, n := binary.Varint(t.keyData[offset:])
+	if n <= 0 {
+		panic(fmt.Sprintf("statichash: debug: bad key length at key offset %d", offset))
+	}
+	if length < 0 || length > int64(len(t.keyData))-int64(offset)-int64(n) {
+		panic(fmt.Sprintf("statichash: debug: key of %d bytes at key offset %d overruns %d bytes of key data", length, offset, len(t.keyData)))
+	}
+}
+
+// debugCheckKeySpace checks that there is room to add key at the end of the key data
+func (t *table) debugCheckKeySpace(key string) {
+	if need := binary.PutVarint(make([]byte, binary.MaxVarintLen64), int64(len(key))) + len(key); t.keyOffset+need > len(t.keyData) {
+		panic(fmt.Sprintf("statichash: debug: no room for key %q of %d bytes at key offset %d in %d bytes of key data", key, need, t.keyOffset, len(t.keyData)))
+	}
+}
@@ -0,0 +1,6 @@
+//go:build statichash_debug
+
+package statichash
+
+// debug turns on the checks in debug.go. It is set by building with the statichash_debug tag.
+const debug = true
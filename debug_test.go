@@ -0,0 +1,40 @@
+//go:build statichash_debug
+
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugChecks(t *testing.T) {
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	t.Run("slot", func(t *testing.T) {
+		assert.PanicsWithValue(t, "statichash: debug: slot 16 out of range for table of 16 slots", func() { r.valuePtr(16) })
+	})
+
+	t.Run("key", func(t *testing.T) {
+		assert.PanicsWithValue(t, fmt.Sprintf("statichash: debug: key offset 1000 out of range for %d bytes of key data", len(r.keyData)), func() { r.getKey(1000) })
+		// A key offset part way through a key reads part of it as the length of the key
+		assert.Panics(t, func() { r.getKey(4) })
+	})
+
+	t.Run("section", func(t *testing.T) {
+		l := r.layout
+		l.values = l.length
+		assert.PanicsWithValue(t, fmt.Sprintf("statichash: debug: values section of 16 x %d bytes at offset %d overruns table of %d bytes", r.valueSize, l.length, l.length), func() { r.debugCheckLayout(nil, l) })
+		l = r.layout
+		l.keys++
+		assert.Panics(t, func() { r.debugCheckLayout(nil, l) })
+	})
+}
@@ -0,0 +1,120 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DiffKind describes how an entry differs between two tables
+type DiffKind int
+
+const (
+	// Added means the key is only present in the new table
+	Added DiffKind = iota + 1
+	// Removed means the key is only present in the old table
+	Removed
+	// Changed means the key is present in both tables but the values differ
+	Changed
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// Diff compares two tables, calling fn for each key that has been added, removed or whose value has changed
+// going from a to b. Each table is walked once and keys are looked up in the other, so neither key set needs
+// to be held in memory. Return false from fn to stop the comparison early. Tables built WithHashOnly can't be
+// diffed.
+func Diff(a, b *Read, fn func(kind DiffKind, key string) bool) error {
+	for _, t := range []*Read{a, b} {
+		if err := t.needKeys("diff"); err != nil {
+			return err
+		}
+	}
+	if a.valueSize != b.valueSize {
+		return fmt.Errorf("%w: cannot diff tables with value sizes %d and %d", ErrValueSizeMismatch, a.valueSize, b.valueSize)
+	}
+
+	it := a.Iterate()
+	for it.Next() {
+		key := it.Key()
+		index, found := b.find(key, b.hashKey(key))
+		if !found {
+			if !fn(Removed, key) {
+				return nil
+			}
+			continue
+		}
+		if !a.valueEqual(it.index, &b.table, index) {
+			if !fn(Changed, key) {
+				return nil
+			}
+		}
+	}
+
+	it = b.Iterate()
+	for it.Next() {
+		key := it.Key()
+		if _, found := a.find(key, a.hashKey(key)); !found {
+			if !fn(Added, key) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// valueEqual reports whether the value in slot i of t is the same as the value in slot j of o. String values
+// are compared as strings, as their offsets depend on how the table was built.
+func (t *table) valueEqual(i int, o *table, j int) bool {
+	if t.flags&flagStringValues != 0 && o.flags&flagStringValues != 0 {
+		return t.stringValue(i) == o.stringValue(j)
+	}
+	return bytes.Equal(t.value(i), o.value(j))
+}
+
+// Equal reports whether two tables hold the same keys with the same values. Tables built in a different order
+// or with different options or capacities can still be equal. Tables built WithHashOnly are compared by the
+// hashes of their keys, so two of them can only be equal if they hash keys in the same way.
+func Equal(a, b *Read) bool {
+	// String tables can have different value sizes, as strings may be stored in the values WithInlineStrings
+	strings := a.flags&b.flags&flagStringValues != 0
+	if (a.valueSize != b.valueSize && !strings) || a.count != b.count {
+		return false
+	}
+
+	if a.flags&flagHashOnly != 0 && b.flags&flagHashOnly == 0 {
+		// The keys of b can be looked up in a
+		a, b = b, a
+	}
+	hashOnly := a.flags&flagHashOnly != 0
+	if hashOnly && (a.flags&flagSeeded != b.flags&flagSeeded || a.seed != b.seed) {
+		// The hashes in a mean nothing to b
+		return false
+	}
+
+	// As the tables have the same number of entries, if every entry in a is in b then b has no others
+	it := a.Iterate()
+	for it.Next() {
+		key := it.Key()
+		var h uint64
+		if hashOnly {
+			h = it.Hash()
+		} else {
+			h = b.hashKey(key)
+		}
+		index, found := b.find(key, h)
+		if !found || !a.valueEqual(it.index, &b.table, index) {
+			return false
+		}
+	}
+	return true
+}
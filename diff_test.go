@@ -0,0 +1,72 @@
+package statichash
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFromMap(t testing.TB, m map[string]int) *Read {
+	t.Helper()
+	tb := New(len(m), int64(unsafe.Sizeof(int(0))), 100)
+	for k, v := range m {
+		v := v
+		tb.Set(k, unsafe.Pointer(&v))
+	}
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	return tr
+}
+
+func TestDiff(t *testing.T) {
+	a := readFromMap(t, map[string]int{"a": 1, "b": 2, "c": 3, "d": 4})
+	b := readFromMap(t, map[string]int{"a": 1, "b": 20, "d": 4, "e": 5})
+
+	got := map[string]DiffKind{}
+	assert.NoError(t, Diff(a, b, func(kind DiffKind, key string) bool {
+		got[key] = kind
+		return true
+	}))
+	assert.Equal(t, map[string]DiffKind{"b": Changed, "c": Removed, "e": Added}, got)
+
+	var count int
+	assert.NoError(t, Diff(a, b, func(kind DiffKind, key string) bool {
+		count++
+		return false
+	}))
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, Diff(a, a, func(kind DiffKind, key string) bool {
+		t.Errorf("unexpected difference %s %s", kind, key)
+		return true
+	}))
+}
+
+func TestEqual(t *testing.T) {
+	a := readFromMap(t, map[string]int{"a": 1, "b": 2, "c": 3})
+
+	tests := []struct {
+		name string
+		b    map[string]int
+		exp  bool
+	}{
+		{name: "same", b: map[string]int{"c": 3, "b": 2, "a": 1}, exp: true},
+		{name: "changed", b: map[string]int{"a": 1, "b": 20, "c": 3}},
+		{name: "missing", b: map[string]int{"a": 1, "b": 2}},
+		{name: "extra", b: map[string]int{"a": 1, "b": 2, "c": 3, "d": 4}},
+		{name: "replaced", b: map[string]int{"a": 1, "b": 2, "d": 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := readFromMap(t, test.b)
+			assert.Equal(t, test.exp, Equal(a, b))
+			assert.Equal(t, test.exp, Equal(b, a))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package statichash
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// flagNames names each of the header flags, for debugging output
+var flagNames = []struct {
+	flag int64
+	name string
+}{
+	{flagInsertionOrder, "insertion-order"},
+	{flagSeeded, "seeded"},
+	{flagFingerprints, "fingerprints"},
+	{flagSortedIndex, "sorted-index"},
+	{flagStringValues, "string-values"},
+	{flagColumnar, "columnar"},
+	{flagPageAligned, "page-aligned"},
+	{flagCuckoo, "cuckoo"},
+	{flagHopscotch, "hopscotch"},
+	{flagInterleaved, "interleaved"},
+	{flagInlineValues, "inline-values"},
+	{flagInlineStrings, "inline-strings"},
+	{flagHashOnly, "hash-only"},
+}
+
+func describeFlags(flags int64) string {
+	var names []string
+	for _, f := range flagNames {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+			flags &^= f.flag
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("unknown(%#x)", flags))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// Dump writes a description of the table to w for debugging. It shows the header, where each section is and how
+// big it is, how full the table is, and the hash, key and raw value of the first sample occupied slots.
+func (r *Read) Dump(w io.Writer, sample int) error {
+	bw := bufio.NewWriter(w)
+	tw := tabwriter.NewWriter(bw, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "header\n")
+	fmt.Fprintf(tw, "  slots\t%d\n", r.numItems)
+	fmt.Fprintf(tw, "  entries\t%d\n", r.count)
+	fmt.Fprintf(tw, "  value size\t%d\n", r.valueSize)
+	fmt.Fprintf(tw, "  flags\t%s\n", describeFlags(r.flags))
+	if r.columns != nil {
+		fmt.Fprintf(tw, "  columns\t%v\n", r.columns)
+	}
+	if r.flags&flagSeeded != 0 {
+		fmt.Fprintf(tw, "  seed\t%#x\n", r.seed)
+	}
+	if r.flags&flagCuckoo != 0 {
+		fmt.Fprintf(tw, "  cuckoo seed\t%#x\n", r.cuckooSeed)
+	}
+	info := r.Info()
+	if !info.Created.IsZero() {
+		fmt.Fprintf(tw, "  created\t%s\n", info.Created.UTC().Format(time.RFC3339))
+	}
+	if info.Version != "" {
+		fmt.Fprintf(tw, "  version\t%q\n", info.Version)
+	}
+	if r.maxProbe != 0 {
+		fmt.Fprintf(tw, "  max probe\t%d\n", r.maxProbe)
+	}
+	fmt.Fprintf(tw, "  file length\t%d\n", r.length)
+
+	fmt.Fprintf(tw, "sections\n")
+	for _, s := range coreSections {
+		start, end := r.layout.section(s)
+		fmt.Fprintf(tw, "  %s\toffset %d\tlength %d\n", s, start, end-start)
+	}
+	for _, e := range r.sections {
+		fmt.Fprintf(tw, "  %s\toffset %d\tlength %d\n", Section(e.kind), e.offset, e.length)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var occupied int
+	for i := range r.numItems {
+		if r.hashAt(i) != 0 {
+			occupied++
+		}
+	}
+	fmt.Fprintf(bw, "occupancy %d/%d (%.1f%%)\n", occupied, r.numItems, 100*float64(occupied)/float64(r.numItems))
+
+	if sample > 0 {
+		fmt.Fprintf(bw, "first %d occupied slots\n", sample)
+		tw = tabwriter.NewWriter(bw, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "  slot\thash\tkey\tvalue\n")
+		for i := range r.numItems {
+			h := r.hashAt(i)
+			if sample == 0 {
+				break
+			}
+			if h == 0 {
+				continue
+			}
+			sample--
+			key := strconv.Quote(r.getKey(r.keyAt(i)))
+			if r.flags&flagHashOnly != 0 {
+				// There is no key, but the full hash identifies it
+				key = fmt.Sprintf("%#016x", r.entryHash(i))
+			}
+			if r.flags&flagStringValues != 0 {
+				fmt.Fprintf(tw, "  %d\t%#08x\t%s\t%q\n", i, uint32(h), key, r.stringValue(i))
+				continue
+			}
+			fmt.Fprintf(tw, "  %d\t%#08x\t%s\t%s\n", i, uint32(h), key, hex.EncodeToString(r.value(i)))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
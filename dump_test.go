@@ -0,0 +1,31 @@
+package statichash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	tb := buildTable(t, 10, WithSeed(7), WithInsertionOrder())
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	var out strings.Builder
+	assert.NoError(t, tr.Dump(&out, 3))
+	dump := out.String()
+
+	assert.Contains(t, dump, "  slots        16\n")
+	assert.Contains(t, dump, "  entries      10\n")
+	assert.Contains(t, dump, "  flags        insertion-order,seeded\n")
+	assert.Contains(t, dump, "  seed         0x7\n")
+	assert.Contains(t, dump, "occupancy 10/16 (62.5%)\n")
+	assert.Contains(t, dump, "first 3 occupied slots\n")
+	assert.Equal(t, 3, strings.Count(dump, `"key`))
+}
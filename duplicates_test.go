@@ -0,0 +1,31 @@
+package statichash
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicates(t *testing.T) {
+	var dups []string
+	tb := New(4, 8, 100, WithAutoGrow(), WithDuplicateHandler(func(key string) {
+		dups = append(dups, key)
+	}))
+	for i, key := range []string{"a", "b", "a", "c", "d", "e", "b", "a"} {
+		assert.NoError(t, tb.Set(key, unsafe.Pointer(&i)))
+	}
+	assert.Equal(t, []string{"a", "b", "a"}, dups)
+	assert.Equal(t, 3, tb.Duplicates())
+	assert.Equal(t, 5, tb.Len())
+
+	v, _ := tb.GetPtr("a")
+	assert.Equal(t, 7, *(*int)(v))
+
+	tb.Reset(4, 8, 100)
+	assert.Equal(t, 0, tb.Duplicates())
+	tb.Set("a", unsafe.Pointer(&tb))
+	tb.Set("a", unsafe.Pointer(&tb))
+	assert.Equal(t, 1, tb.Duplicates())
+	assert.Len(t, dups, 3)
+}
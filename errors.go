@@ -0,0 +1,43 @@
+package statichash
+
+import "errors"
+
+// These errors are returned, wrapped with more detail, by the functions in this package. Use errors.Is to
+// check for them.
+var (
+	// ErrBadMagic means the data is not a table file, or is a table file written by an incompatible version of
+	// this package
+	ErrBadMagic = errors.New("statichash: bad magic number")
+	// ErrUnsupportedFormat means the data is a table file written by a newer version of this package
+	ErrUnsupportedFormat = errors.New("statichash: unsupported file format")
+	// ErrTruncated means the data is shorter than the header says it should be
+	ErrTruncated = errors.New("statichash: data truncated")
+	// ErrCorrupt means the header or sections of a table file are inconsistent
+	ErrCorrupt = errors.New("statichash: data corrupt")
+	// ErrValueSizeMismatch means the size of a value does not match the table
+	ErrValueSizeMismatch = errors.New("statichash: value size mismatch")
+	// ErrKeySpaceExhausted means there is no room left for a key in the space for key data given to New
+	ErrKeySpaceExhausted = errors.New("statichash: key space exhausted")
+	// ErrKeyCollision means two keys would become the same key
+	ErrKeyCollision = errors.New("statichash: key collision")
+	// ErrTableFull means there are no free slots left for a new key
+	ErrTableFull = errors.New("statichash: table full")
+	// ErrReadOnly means a table opened for reading was written to
+	ErrReadOnly = errors.New("statichash: table is read-only")
+	// ErrFinalized means a table was changed after Finalize was called
+	ErrFinalized = errors.New("statichash: table is finalized")
+	// ErrNotFinalized means a table was saved before Finalize was called
+	ErrNotFinalized = errors.New("statichash: table is not finalized")
+	// ErrNotStringTable means a file opened as a StringTable was not written by one
+	ErrNotStringTable = errors.New("statichash: not a string table")
+	// ErrHasPointers means a value type contains pointers, so can't be stored in a table
+	ErrHasPointers = errors.New("statichash: value type contains pointers")
+	// ErrLocked means a table file is locked by another process, so the lock asked for can't be taken
+	ErrLocked = errors.New("statichash: file is locked")
+	// ErrTooLarge means a table has more slots, or bigger sections, than this package or platform can index
+	ErrTooLarge = errors.New("statichash: table too large")
+	// ErrSchemaMismatch means a table's schema is not the one the caller expects
+	ErrSchemaMismatch = errors.New("statichash: schema mismatch")
+	// ErrNoKeys means a table built WithHashOnly, which keeps no keys, was used for something that needs them
+	ErrNoKeys = errors.New("statichash: table has no keys")
+)
@@ -0,0 +1,178 @@
+package statichash
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	var buf bytes.Buffer
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+
+	t.Run("bad magic", func(t *testing.T) {
+		bad := append([]byte(nil), data...)
+		copy(bad, "notatable")
+		_, err := NewFromBytes(bad)
+		assert.ErrorIs(t, err, ErrBadMagic)
+	})
+
+	t.Run("truncated header", func(t *testing.T) {
+		_, err := NewFromBytes(data[:20])
+		assert.ErrorIs(t, err, ErrTruncated)
+	})
+
+	t.Run("truncated sections", func(t *testing.T) {
+		_, err := NewFromBytes(data[:200])
+		assert.ErrorIs(t, err, ErrTruncated)
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "table")
+		assert.NoError(t, os.WriteFile(name, data[:200], 0o644))
+		_, err := NewFrom(name)
+		assert.ErrorIs(t, err, ErrTruncated)
+		_, err = NewFrom(name, WithWindowedMapping(4096, 1))
+		assert.ErrorIs(t, err, ErrTruncated)
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		bad := append([]byte(nil), data...)
+		editHeader(t, bad, func(h *header) { h.numItems = 3 })
+		_, err := NewFromBytes(bad)
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("key offset", func(t *testing.T) {
+		bad := append([]byte(nil), data...)
+		r, err := NewFromBytes(bad)
+		assert.NoError(t, err)
+		for i, h := range r.hashes {
+			if h != 0 {
+				r.keys[i] = 1 << 40
+				break
+			}
+		}
+		_, err = NewFromBytes(bad)
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("unaligned", func(t *testing.T) {
+		unaligned := make([]byte, len(data)+1)[1:]
+		copy(unaligned, data)
+		r, err := NewFromBytes(unaligned)
+		assert.NoError(t, err)
+		assert.NoError(t, r.Validate())
+		_, ok := r.GetPtr("key3")
+		assert.True(t, ok)
+	})
+
+	t.Run("hostile", func(t *testing.T) {
+		// Whatever bytes we change, NewFromBytes either fails or returns a table that is safe to use
+		rnd := rand.New(rand.NewSource(1))
+		for range 10000 {
+			bad := append([]byte(nil), data...)
+			for range 1 + rnd.Intn(4) {
+				bad[rnd.Intn(len(bad))] = byte(rnd.Intn(256))
+			}
+			useHostile(bad)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		bad := append([]byte(nil), data...)
+		editHeader(t, bad, func(h *header) { h.numItems = 1 << 40 })
+		_, err := NewFromBytes(bad)
+		assert.ErrorIs(t, err, ErrTooLarge)
+
+		bad = append([]byte(nil), data...)
+		editHeader(t, bad, func(h *header) { h.valueSize = 1 << 61 })
+		_, err = NewFromBytes(bad)
+		assert.ErrorIs(t, err, ErrTooLarge)
+
+		if math.MaxInt > maxSlots {
+			// We can't ask for this many slots on 32 bit platforms
+			n := int64(maxSlots + 1)
+			assert.PanicsWithValue(t, "statichash: table too large: 4294967297 slots is more than the maximum of 4294967296", func() { New(int(n), 8, 0) })
+		}
+		assert.Panics(t, func() { New(16, 1<<60, 0) })
+	})
+
+	t.Run("value size", func(t *testing.T) {
+		_, err := NewFromBytes(data, WithValueSize(4))
+		assert.ErrorIs(t, err, ErrValueSizeMismatch)
+		_, err = NewFromBytes(data, WithValueSize(int(unsafe.Sizeof(int(0)))))
+		assert.NoError(t, err)
+	})
+
+	t.Run("table full", func(t *testing.T) {
+		tb := New(2, 8, 20)
+		var v int64
+		assert.NoError(t, tb.Set("a", unsafe.Pointer(&v)))
+		assert.NoError(t, tb.Set("b", unsafe.Pointer(&v)))
+		assert.NoError(t, tb.Set("a", unsafe.Pointer(&v)))
+		assert.ErrorIs(t, tb.Set("c", unsafe.Pointer(&v)), ErrTableFull)
+	})
+
+	t.Run("key space exhausted", func(t *testing.T) {
+		// 4 bytes of key data plus 4 per slot
+		tb := New(4, 8, 4)
+		var v int64
+		assert.NoError(t, tb.Set("key001", unsafe.Pointer(&v)))
+		assert.NoError(t, tb.Set("key002", unsafe.Pointer(&v)))
+		err := tb.Set("key003", unsafe.Pointer(&v))
+		assert.ErrorIs(t, err, ErrKeySpaceExhausted)
+		assert.ErrorContains(t, err, `"key003"`)
+		assert.Equal(t, 2, tb.Len())
+		_, ok := tb.GetPtr("key002")
+		assert.True(t, ok)
+
+		st := New(4, 8, 4)
+		assert.ErrorIs(t, st.SetString("a", "a long string value"), ErrKeySpaceExhausted)
+	})
+}
+
+// useHostile opens data with NewFromBytes and, if that works, uses the table in every way that reads it
+func useHostile(data []byte) {
+	r, err := NewFromBytes(data)
+	if err != nil {
+		return
+	}
+	r.GetPtr("key3")
+	r.GetPtr("missing")
+	for it := r.Iterate(); it.Next(); {
+		it.Key()
+	}
+	if r.sorted != nil {
+		for it := r.SortedIterate(); it.Next(); {
+			it.Key()
+		}
+	}
+	r.Validate()
+	r.Stats()
+}
+
+func FuzzNewFromBytes(f *testing.F) {
+	for _, opts := range [][]Option{nil, {WithInsertionOrder(), WithSortedIndex()}, {WithFingerprints(), WithCuckoo()}, {WithInterleavedSlots()}, {WithInlineValues()}} {
+		tb := buildTable(nil, 10, opts...)
+		tb.Finalize()
+		var buf bytes.Buffer
+		if _, err := tb.WriteTo(&buf); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		useHostile(data)
+	})
+}
@@ -0,0 +1,43 @@
+package statichash
+
+import "fmt"
+
+// Evict releases the memory holding the table, so that a table that is rarely used stops counting against the
+// memory of the process. The pages are read back in from the file as lookups need them. If no sections are
+// given the whole table is evicted. Evicted sections are no longer locked into memory.
+//
+// Evict does nothing for tables created with NewFromBytes. Memory is only actually released on Linux, but
+// sections are unlocked on every platform.
+func (r *Read) Evict(sections ...Section) error {
+	if !r.mapped {
+		return nil
+	}
+
+	if len(sections) == 0 {
+		sections = coreSections
+	}
+
+	for _, s := range sections {
+		if r.win != nil && (s == SectionValues || s == SectionKeyData) {
+			// These sections are only mapped in windows, and we can simply drop them
+			if err := r.win.close(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, data := r.sectionPages(s)
+		if len(data) == 0 {
+			continue
+		}
+		// The kernel won't drop locked pages
+		if err := unlockMemory(data); err != nil {
+			return fmt.Errorf("unlocking %s section: %w", s, err)
+		}
+		r.clearLocked(s)
+		if err := adviseDontNeed(data); err != nil {
+			return fmt.Errorf("evicting %s section: %w", s, err)
+		}
+	}
+	return nil
+}
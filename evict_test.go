@@ -0,0 +1,29 @@
+package statichash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvict(t *testing.T) {
+	tr, err := NewFrom(writeTempTable(t, buildTable(t, 1000)))
+	assert.NoError(t, err)
+	defer tr.Close()
+
+	check := func() {
+		for i := 0; i < 1000; i++ {
+			v, ok := tr.GetPtr(fmt.Sprintf("key%d", 1000-i))
+			if assert.True(t, ok) {
+				assert.Equal(t, i, *(*int)(v))
+			}
+		}
+	}
+
+	check()
+	assert.NoError(t, tr.Evict(SectionValues, SectionKeyData))
+	check()
+	assert.NoError(t, tr.Evict())
+	check()
+}
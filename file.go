@@ -1,23 +1,192 @@
 package statichash
 
-import "unsafe"
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+)
 
 /*
 File is
 
-Header
-Hashes - 32 bit.
+Header - encoded as described in layout.go, as is every number in the file
+Hashes - 32 bit. If flagInterleaved is set this is instead a slotRecord for each slot, holding its hash,
+	fingerprint and key offset, and the Fingerprints and Keys sections are empty. If flagInlineValues is also
+	set each record is followed by the slot's value, and the Values section is empty.
+Fingerprints - optional. A byte per slot taken from the hash bits above those in Hashes
 Keys - corresponding to each hash. Offset to key data
-Values - corresponding to each hash
+Order - optional. Slot index of each entry in the order it was added
+Sorted - optional. Slot index of each entry in key order
+Values - corresponding to each hash. If the table has columns, the values are split into one array per column
 Key data
+Extra sections - optional. Each starts on an 8-byte boundary
+Directory - the type, offset and length of every section, as described in sections.go
 
 */
 
+// header is the header of the current version of the file format. It is encoded in the file field by field as
+// layout.go describes. Files written by older versions are read by converting their header to this one, as in
+// format.go.
 type header struct {
-	numItems  int64
-	valueSize int64
+	magic [8]byte
+	// format is the version of the file format
+	format uint32
+	// headerSize is the size of the header in the file. The hashes start immediately after it.
+	headerSize uint32
+	numItems   int64
+	valueSize  int64
+	// count is the number of entries actually stored in the table
+	count int64
+	flags int64
+	// seed is the seed for the hash function if flagSeeded is set
+	seed uint64
+	// created is when the table was built, in nanoseconds since the Unix epoch
+	created int64
+	// version is a caller-supplied description of the data or the program that wrote it
+	version [32]byte
+	// columns holds the width of each column of the values if flagColumnar is set. Unused entries are zero.
+	columns [maxColumns]uint16
+	// directory is the offset of the section directory, and sections the number of entries in it. Files
+	// written before formatV2 have no directory.
+	directory int64
+	sections  uint32
+	// maxProbe is the most slots a lookup of a key in the table examines, or zero if it wasn't recorded. Lookups
+	// give up after examining this many.
+	maxProbe uint32
+	// cuckooSeed is the seed for the hash that chooses each key's second slot if flagCuckoo is set. Files
+	// written before formatV3 don't have it.
+	cuckooSeed uint64
 }
 
+// maxColumns is the maximum number of columns a value can be split into
+const maxColumns = 16
+
+// minColumnarSlots is the minimum number of slots in a columnar table. Each column then occupies a multiple of
+// 8 bytes, so every column starts on an 8-byte boundary.
+const minColumnarSlots = 8
+
+// maxSlots is the most slots a table can have. Only the low 32 bits of a key's hash are stored in its slot and
+// they choose its home slot, so any further slots would never be used. Bigger datasets need NewPartitioned.
+const maxSlots = 1 << 32
+
+// checkSize returns an error wrapping ErrTooLarge if a table with numItems slots of valueSize bytes can't be
+// indexed. The values section must fit in an int, so the limit is lower on 32-bit platforms.
+func checkSize(numItems, valueSize int64) error {
+	if numItems > maxSlots {
+		return fmt.Errorf("%w: %d slots is more than the maximum of %d", ErrTooLarge, numItems, int64(maxSlots))
+	}
+	if numItems > math.MaxInt/int64(unsafe.Sizeof(keyOffset(0))) {
+		return fmt.Errorf("%w: %d slots can't be indexed on this platform", ErrTooLarge, numItems)
+	}
+	if hi, lo := bits.Mul64(uint64(numItems), uint64(valueSize)); hi != 0 || lo > math.MaxInt/2 {
+		return fmt.Errorf("%w: %d values of %d bytes", ErrTooLarge, numItems, valueSize)
+	}
+	return nil
+}
+
+// checkMappable returns an error wrapping ErrTooLarge if length bytes can't be mapped into memory in one piece
+// on this platform. Open such tables WithWindowedMapping instead.
+func checkMappable(length int64) error {
+	if length > math.MaxInt {
+		return fmt.Errorf("%w: %d bytes can't be mapped into memory on this platform", ErrTooLarge, length)
+	}
+	return nil
+}
+
+// validate checks the header is consistent with itself and a file of the given length
+func (h *header) validate(fileLength int64) error {
+	if !hostLittleEndian {
+		return fmt.Errorf("%w: tables can only be read on little-endian platforms", ErrUnsupportedFormat)
+	}
+	if h.headerSize < headerV0Size || h.headerSize%8 != 0 {
+		return fmt.Errorf("%w: header size %d", ErrCorrupt, h.headerSize)
+	}
+	if h.numItems <= 0 || h.numItems&(h.numItems-1) != 0 {
+		return fmt.Errorf("%w: slot count %d is not a power of 2", ErrCorrupt, h.numItems)
+	}
+	if h.valueSize < 0 {
+		return fmt.Errorf("%w: negative value size %d", ErrCorrupt, h.valueSize)
+	}
+	if err := checkSize(h.numItems, h.valueSize); err != nil {
+		return err
+	}
+	if h.count < 0 || h.count > h.numItems {
+		return fmt.Errorf("%w: %d entries in %d slots", ErrCorrupt, h.count, h.numItems)
+	}
+	if h.flags&^knownFlags != 0 {
+		return fmt.Errorf("%w: unknown flags %#x", ErrUnsupportedFormat, h.flags&^knownFlags)
+	}
+	if h.flags&flagInlineValues != 0 && (h.flags&(flagInterleaved|flagColumnar) != flagInterleaved || h.valueSize > maxInlineValueSize) {
+		return fmt.Errorf("%w: inline values of %d bytes with flags %#x", ErrCorrupt, h.valueSize, h.flags)
+	}
+	if h.flags&flagInlineStrings != 0 && (h.flags&(flagStringValues|flagColumnar) != flagStringValues ||
+		h.valueSize%8 != 0 || h.valueSize < 2*int64(stringValueSize) || h.valueSize > maxInlineStringSize) {
+		return fmt.Errorf("%w: inline strings in values of %d bytes with flags %#x", ErrCorrupt, h.valueSize, h.flags)
+	}
+	if h.flags&flagHashOnly != 0 && h.flags&(flagCuckoo|flagSortedIndex|flagStringValues) != 0 {
+		return fmt.Errorf("%w: hash-only table with flags %#x", ErrCorrupt, h.flags)
+	}
+	if h.flags&flagColumnar != 0 {
+		var width int64
+		for _, w := range h.columns {
+			width += int64(w)
+		}
+		if width != h.valueSize || h.numItems < minColumnarSlots {
+			return fmt.Errorf("%w: columns are %d bytes wide in %d slots, but the value size is %d", ErrCorrupt, width, h.numItems, h.valueSize)
+		}
+	}
+	if l := h.offsets(); l.keyData > fileLength {
+		return fmt.Errorf("%w: file is %d bytes but the sections need at least %d", ErrTruncated, fileLength, l.keyData)
+	}
+	return nil
+}
+
+const (
+	// flagInsertionOrder indicates the file has an Order section recording the slot of each entry in the order
+	// it was first added
+	flagInsertionOrder = 1 << iota
+	// flagSeeded indicates keys are hashed with seededHash using the seed in the header rather than with
+	// aeshash
+	flagSeeded
+	// flagFingerprints indicates the file has a Fingerprints section
+	flagFingerprints
+	// flagSortedIndex indicates the file has a Sorted section
+	flagSortedIndex
+	// flagStringValues indicates the values are the offsets of strings in the key data, as written by a
+	// StringTable
+	flagStringValues
+	// flagColumnar indicates the values are stored in a separate array for each column
+	flagColumnar
+	// flagPageAligned indicates each section starts on a sectionAlignment boundary
+	flagPageAligned
+	// flagCuckoo indicates each key is in one of two slots, as described in cuckoo.go, rather than on a linear
+	// probe sequence from its home slot
+	flagCuckoo
+	// flagHopscotch indicates every key is within hopscotchNeighbourhood slots of its home slot. The slots are
+	// otherwise laid out as for linear probing, so lookups need do nothing different.
+	flagHopscotch
+	// flagInterleaved indicates the hash, fingerprint and key offset of each slot are stored together in a
+	// slotRecord, as described in slots.go.
+	flagInterleaved
+	// flagInlineValues indicates each value is stored after the slotRecord of its slot, and the Values section
+	// is empty. It is only set with flagInterleaved.
+	flagInlineValues
+	// flagInlineStrings indicates strings shorter than the value size are stored in the values themselves, as
+	// described in strings.go. It is only set with flagStringValues.
+	flagInlineStrings
+	// flagHashOnly indicates each slot stores the full 64-bit hash of its key where the key offset would be, and
+	// there is no key data, as described in hashonly.go
+	flagHashOnly
+
+	// knownFlags has every flag this version of the package understands
+	knownFlags = flagHashOnly<<1 - 1
+)
+
+// sectionAlignment is where sections start in a table built WithPageAlignedSections. It is a multiple of the
+// page size on every platform we know of, so the file is portable between them.
+const sectionAlignment = 64 << 10
+
 // Hash is the type of a hash in the table
 type hash uint32
 
@@ -28,20 +197,162 @@ type keyOffset int64
 // use this, but it gives us a size estimate for the string lengths
 type stringLength int32
 
+// slotIndex is the type used to record the position of an entry in the slot arrays
+type slotIndex int64
+
+// layout describes where each section starts within the hash table file
+type layout struct {
+	hashes       int64
+	fingerprints int64
+	keys         int64
+	order        int64
+	sorted       int64
+	values       int64
+	keyData      int64
+	length       int64
+}
+
 // Offsets calculates the offsets within the hash table file of the various sections within the file
-func offsets(numItems, valueSize, totalKeyLength int64) (hashes, keys, values, keyData, length int64) {
+func offsets(numItems, valueSize, totalKeyLength, flags int64) (l layout) {
+	return offsetsFrom(headerSize, numItems, valueSize, totalKeyLength, flags)
+}
+
+// offsets returns the layout of the file the header is from. The length is only correct for the index
+// sections.
+func (h *header) offsets() layout {
+	return offsetsFrom(int64(h.headerSize), h.numItems, h.valueSize, 0, h.flags)
+}
+
+// offsetsFrom calculates the offsets of the sections of a file whose header is headerSize bytes
+func offsetsFrom(headerSize, numItems, valueSize, totalKeyLength, flags int64) (l layout) {
+	// start returns where a section may start given where the previous one ended
+	start := func(offset int64) int64 {
+		if flags&flagPageAligned != 0 {
+			return roundUp(offset, sectionAlignment)
+		}
+		return offset
+	}
 
-	hashes = int64(unsafe.Sizeof(header{}))
-	// Need to round this up to the next KeyOffset alignment
-	keys = roundUp(hashes+int64(unsafe.Sizeof(hash(0)))*numItems, unsafe.Alignof(keyOffset(0)))
+	l.hashes = start(headerSize)
+	if flags&flagInterleaved != 0 {
+		// The hashes section holds a slotRecord for each slot, and the fingerprints and keys sections are empty
+		l.fingerprints = l.hashes + slotSize(flags, valueSize)*numItems
+		l.keys = l.fingerprints
+		l.order = start(l.keys)
+	} else {
+		l.fingerprints = start(l.hashes + int64(unsafe.Sizeof(hash(0)))*numItems)
+		l.keys = l.fingerprints
+		if flags&flagFingerprints != 0 {
+			l.keys += numItems
+		}
+		// Need to round this up to the next KeyOffset alignment
+		l.keys = start(roundUp(l.keys, unsafe.Alignof(keyOffset(0))))
 
-	// Safest to make this 8 byte aligned. Within the values the valueSize should then take care of the natural
-	// alignment of the items
-	values = keys + int64(unsafe.Sizeof(keyOffset(0)))*numItems
-	keyData = values + valueSize*numItems
-	length = keyData + totalKeyLength + int64(unsafe.Sizeof(stringLength(0)))*numItems
+		// Safest to make this 8 byte aligned. Within the values the valueSize should then take care of the
+		// natural alignment of the items
+		l.order = start(l.keys + int64(unsafe.Sizeof(keyOffset(0)))*numItems)
+	}
+	l.sorted = l.order
+	if flags&flagInsertionOrder != 0 {
+		l.sorted += int64(unsafe.Sizeof(slotIndex(0))) * numItems
+	}
+	l.sorted = start(l.sorted)
+	l.values = l.sorted
+	if flags&flagSortedIndex != 0 {
+		l.values += int64(unsafe.Sizeof(slotIndex(0))) * numItems
+	}
+	l.values = start(l.values)
+	l.keyData = l.values
+	if flags&flagInlineValues == 0 {
+		l.keyData += valueSize * numItems
+	}
+	l.keyData = start(l.keyData)
+	l.length = l.keyData
+	if flags&flagHashOnly == 0 {
+		l.length += totalKeyLength + int64(unsafe.Sizeof(stringLength(0)))*numItems
+	}
+
+	return l
+}
+
+// Section identifies one of the sections of a table file. It is also the type recorded for the section in the
+// file's directory.
+type Section int
+
+const (
+	// SectionHashes is the array of slot hashes
+	SectionHashes Section = iota
+	// SectionKeys is the array of offsets to each slot's key
+	SectionKeys
+	// SectionOrder records the slot of each entry in insertion order. It is empty unless the table was built
+	// WithInsertionOrder
+	SectionOrder
+	// SectionValues is the array of values
+	SectionValues
+	// SectionKeyData holds the bytes of the keys
+	SectionKeyData
+	// SectionFingerprints is the array of slot fingerprints. It is empty unless the table was built
+	// WithFingerprints
+	SectionFingerprints
+	// SectionSorted records the slot of each entry in key order. It is empty unless the table was built
+	// WithSortedIndex
+	SectionSorted
+	// SectionSchema holds the JSON encoding of the Schema given to WithSchema. It follows the key data, and only
+	// files built WithSchema have one.
+	SectionSchema
+	// SectionSymbols holds the strings interned with Write.Intern. It follows the key data, and only files with
+	// interned strings have one.
+	SectionSymbols
+	// SectionValueLayout holds the hash of the layout of the value type given to WithValueType. It follows the
+	// key data, and only files built WithValueType or with FromMapT have one.
+	SectionValueLayout
+)
+
+func (s Section) String() string {
+	switch s {
+	case SectionHashes:
+		return "hashes"
+	case SectionKeys:
+		return "keys"
+	case SectionOrder:
+		return "order"
+	case SectionValues:
+		return "values"
+	case SectionKeyData:
+		return "key data"
+	case SectionFingerprints:
+		return "fingerprints"
+	case SectionSorted:
+		return "sorted index"
+	case SectionSchema:
+		return "schema"
+	case SectionSymbols:
+		return "symbols"
+	case SectionValueLayout:
+		return "value layout"
+	}
+	return fmt.Sprintf("Section(%d)", int(s))
+}
 
-	return hashes, keys, values, keyData, length
+// section returns the start and end offsets of section s
+func (l *layout) section(s Section) (start, end int64) {
+	switch s {
+	case SectionHashes:
+		return l.hashes, l.fingerprints
+	case SectionFingerprints:
+		return l.fingerprints, l.keys
+	case SectionKeys:
+		return l.keys, l.order
+	case SectionOrder:
+		return l.order, l.sorted
+	case SectionSorted:
+		return l.sorted, l.values
+	case SectionValues:
+		return l.values, l.keyData
+	case SectionKeyData:
+		return l.keyData, l.length
+	}
+	return 0, 0
 }
 
 // roundUp increases length to the next alignment boundary required by align.
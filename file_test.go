@@ -1,6 +1,7 @@
 package statichash
 
 import (
+	"fmt"
 	"testing"
 	"time"
 	"unsafe"
@@ -30,15 +31,12 @@ func TestOffsets(t *testing.T) {
 		numItems       int64
 		valueSize      int64
 		totalKeyLength int64
+		flags          int64
 	}
 	tests := []struct {
-		name        string
-		args        args
-		wantHashes  int64
-		wantKeys    int64
-		wantValues  int64
-		wantKeyData int64
-		wantLength  int64
+		name string
+		args args
+		want layout
 	}{
 		{
 			name: "basic",
@@ -47,11 +45,16 @@ func TestOffsets(t *testing.T) {
 				valueSize:      1,
 				totalKeyLength: 1,
 			},
-			wantHashes:  16, // must be 4 byte aligned
-			wantKeys:    24, // must be 8 byte aligned
-			wantValues:  32, // must be 8 byte aligned
-			wantKeyData: 33, // no alignment requirement
-			wantLength:  38, // no alignment requirement
+			want: layout{
+				hashes:       152, // must be 4 byte aligned
+				fingerprints: 156, // no alignment requirement
+				keys:         160, // must be 8 byte aligned
+				order:        168, // must be 8 byte aligned
+				sorted:       168, // must be 8 byte aligned
+				values:       168, // must be 8 byte aligned
+				keyData:      169, // no alignment requirement
+				length:       174, // no alignment requirement
+			},
 		},
 		{
 			name: "bigger",
@@ -60,30 +63,80 @@ func TestOffsets(t *testing.T) {
 				valueSize:      17,
 				totalKeyLength: 40,
 			},
-			wantHashes:  16,  // must be 4 byte aligned
-			wantKeys:    40,  // must be 8 byte aligned
-			wantValues:  80,  // must be 8 byte aligned
-			wantKeyData: 165, // no alignment requirement
-			wantLength:  225, // no alignment requirement
+			want: layout{
+				hashes:       152, // must be 4 byte aligned
+				fingerprints: 172, // no alignment requirement
+				keys:         176, // must be 8 byte aligned
+				order:        216, // must be 8 byte aligned
+				sorted:       216, // must be 8 byte aligned
+				values:       216, // must be 8 byte aligned
+				keyData:      301, // no alignment requirement
+				length:       361, // no alignment requirement
+			},
+		},
+		{
+			name: "insertion order",
+			args: args{
+				numItems:       5,
+				valueSize:      17,
+				totalKeyLength: 40,
+				flags:          flagInsertionOrder,
+			},
+			want: layout{
+				hashes:       152, // must be 4 byte aligned
+				fingerprints: 172, // no alignment requirement
+				keys:         176, // must be 8 byte aligned
+				order:        216, // must be 8 byte aligned
+				sorted:       256, // must be 8 byte aligned
+				values:       256, // must be 8 byte aligned
+				keyData:      341, // no alignment requirement
+				length:       401, // no alignment requirement
+			},
+		},
+		{
+			name: "fingerprints",
+			args: args{
+				numItems:       5,
+				valueSize:      17,
+				totalKeyLength: 40,
+				flags:          flagFingerprints,
+			},
+			want: layout{
+				hashes:       152, // must be 4 byte aligned
+				fingerprints: 172, // no alignment requirement
+				keys:         184, // must be 8 byte aligned
+				order:        224, // must be 8 byte aligned
+				sorted:       224, // must be 8 byte aligned
+				values:       224, // must be 8 byte aligned
+				keyData:      309, // no alignment requirement
+				length:       369, // no alignment requirement
+			},
+		},
+		{
+			name: "sorted index",
+			args: args{
+				numItems:       5,
+				valueSize:      17,
+				totalKeyLength: 40,
+				flags:          flagInsertionOrder | flagSortedIndex,
+			},
+			want: layout{
+				hashes:       152, // must be 4 byte aligned
+				fingerprints: 172, // no alignment requirement
+				keys:         176, // must be 8 byte aligned
+				order:        216, // must be 8 byte aligned
+				sorted:       256, // must be 8 byte aligned
+				values:       296, // must be 8 byte aligned
+				keyData:      381, // no alignment requirement
+				length:       441, // no alignment requirement
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotHashes, gotKeys, gotValues, gotKeyData, gotLength := offsets(tt.args.numItems, tt.args.valueSize, tt.args.totalKeyLength)
-			if gotHashes != tt.wantHashes {
-				t.Errorf("Offsets() gotHashes = %v, want %v", gotHashes, tt.wantHashes)
-			}
-			if gotKeys != tt.wantKeys {
-				t.Errorf("Offsets() gotKeys = %v, want %v", gotKeys, tt.wantKeys)
-			}
-			if gotValues != tt.wantValues {
-				t.Errorf("Offsets() gotValues = %v, want %v", gotValues, tt.wantValues)
-			}
-			if gotKeyData != tt.wantKeyData {
-				t.Errorf("Offsets() gotKeyData = %v, want %v", gotKeyData, tt.wantKeyData)
-			}
-			if gotLength != tt.wantLength {
-				t.Errorf("Offsets() gotLength = %v, want %v", gotLength, tt.wantLength)
+			got := offsets(tt.args.numItems, tt.args.valueSize, tt.args.totalKeyLength, tt.args.flags)
+			if got != tt.want {
+				t.Errorf("offsets() = %+v, want %+v", got, tt.want)
 			}
 		})
 	}
@@ -129,3 +182,26 @@ func TestRoundUp(t *testing.T) {
 		})
 	}
 }
+
+func TestPageAlignedSections(t *testing.T) {
+	tb := buildTable(t, 100, WithPageAlignedSections(), WithFingerprints(), WithInsertionOrder())
+	tb.Finalize()
+	name := writeTempTable(t, tb)
+
+	for _, opts := range [][]ReadOption{nil, {WithWindowedMapping(4096, 2)}} {
+		tr, err := NewFrom(name, opts...)
+		assert.NoError(t, err)
+		for _, s := range coreSections {
+			start, _ := tr.layout.section(s)
+			assert.Zero(t, start%sectionAlignment, s)
+		}
+		for i := 0; i < 100; i++ {
+			v, ok := tr.GetPtr(fmt.Sprintf("key%d", 100-i))
+			if assert.True(t, ok) {
+				assert.Equal(t, i, *(*int)(v))
+			}
+		}
+		assert.NoError(t, tr.Evict(SectionValues))
+		assert.NoError(t, tr.Close())
+	}
+}
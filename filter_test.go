@@ -0,0 +1,84 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	const n = 10000
+	tb := buildTable(t, n)
+	tb.Finalize()
+
+	var buf bytes.Buffer
+	written, err := tb.WriteFilter(&buf, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), written)
+	assert.Less(t, buf.Len(), n*10/8+100)
+
+	name := filepath.Join(t.TempDir(), "filter")
+	assert.NoError(t, os.WriteFile(name, buf.Bytes(), 0o644))
+	f, err := OpenFilter(name)
+	assert.NoError(t, err)
+
+	for i := 1; i <= n; i++ {
+		assert.True(t, f.MayContain(fmt.Sprintf("key%d", i)))
+	}
+	var falsePositives int
+	for i := 0; i < n; i++ {
+		if f.MayContain(fmt.Sprintf("other%d", i)) {
+			falsePositives++
+		}
+	}
+	// We expect about 1%
+	assert.Less(t, falsePositives, n*3/100)
+
+	empty := New(1, 8, 0)
+	buf.Reset()
+	_, err = empty.WriteFilter(&buf, 10)
+	assert.NoError(t, err)
+	f, err = FilterFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.False(t, f.MayContain("key1"))
+}
+
+func TestFilterErrors(t *testing.T) {
+	tb := buildTable(t, 10)
+	var buf bytes.Buffer
+	_, err := tb.WriteFilter(&buf, 0)
+	assert.Error(t, err)
+
+	_, err = tb.WriteFilter(&buf, 8)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+
+	_, err = FilterFromBytes(data[:10])
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, err = FilterFromBytes(data[:len(data)-1])
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, err = FilterFromBytes(append([]byte("notafilt"), data[8:]...))
+	assert.ErrorIs(t, err, ErrBadMagic)
+	bad := append([]byte(nil), data...)
+	bad[16] = 0
+	_, err = FilterFromBytes(bad)
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+func FuzzFilterFromBytes(f *testing.F) {
+	tb := buildTable(nil, 10)
+	var buf bytes.Buffer
+	if _, err := tb.WriteFilter(&buf, 8); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if filter, err := FilterFromBytes(data); err == nil {
+			filter.MayContain("key1")
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package statichash
+
+// BuildReport describes a table once it has been built
+type BuildReport struct {
+	// Entries is the number of keys in the table
+	Entries int
+	// Slots is the number of slots in the table
+	Slots int
+	// FillFactor is the proportion of slots that are occupied
+	FillFactor float64
+	// MaxProbeLength is the largest number of slots a lookup of a key in the table has to examine
+	MaxProbeLength int
+	// KeyDataUsed is the number of bytes of key data used, and KeyDataAllocated the number available
+	KeyDataUsed      int64
+	KeyDataAllocated int64
+	// Duplicates is the number of times Set overwrote an existing key
+	Duplicates int
+}
+
+// Finalize seals the table so it can be saved with WriteTo, and reports on how it was built. The table can't be
+// changed once it is finalized. Calling Finalize again just returns the report.
+func (t *Write) Finalize() BuildReport {
+	if !t.finalized {
+		if t.sorted != nil {
+			copy(t.sorted, t.sortSlots())
+		}
+		t.probeLength = t.maxProbeLength()
+		if t.symbols != nil {
+			t.addSection(SectionSymbols, t.encodeSymbols())
+		}
+		t.finalized = true
+		// The table can't change now, so its keys need only be sorted once
+		t.sortCache = &sortCache{}
+	}
+
+	return BuildReport{
+		Entries:          t.count,
+		Slots:            t.numItems,
+		FillFactor:       float64(t.count) / float64(t.numItems),
+		MaxProbeLength:   t.probeLength,
+		KeyDataUsed:      int64(t.keyOffset),
+		KeyDataAllocated: int64(len(t.keyData)),
+		Duplicates:       t.duplicates,
+	}
+}
+
+// maxProbeLength returns the largest number of slots examined to find a key in the table
+func (t *table) maxProbeLength() int {
+	var longest int
+	for i := range t.numItems {
+		h := t.hashAt(i)
+		if h == 0 {
+			continue
+		}
+		if l := t.probeLength(i); l > longest {
+			longest = l
+		}
+	}
+	return longest
+}
@@ -0,0 +1,82 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinalize(t *testing.T) {
+	tb := New(4, 8, 10)
+	for i, key := range []string{"a", "b", "c", "a"} {
+		assert.NoError(t, tb.Set(key, unsafe.Pointer(&i)))
+	}
+
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.ErrorIs(t, err, ErrNotFinalized)
+	assert.Zero(t, buf.Len())
+
+	report := tb.Finalize()
+	assert.Equal(t, 3, report.Entries)
+	assert.Equal(t, 4, report.Slots)
+	assert.Equal(t, 0.75, report.FillFactor)
+	assert.GreaterOrEqual(t, report.MaxProbeLength, 1)
+	assert.LessOrEqual(t, report.MaxProbeLength, 3)
+	assert.Equal(t, int64(6), report.KeyDataUsed)
+	assert.Equal(t, int64(26), report.KeyDataAllocated)
+	assert.Equal(t, 1, report.Duplicates)
+	assert.Equal(t, report, tb.Finalize())
+
+	assert.ErrorIs(t, tb.Set("d", unsafe.Pointer(&report)), ErrFinalized)
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	tb.Reset(4, 8, 10)
+	_, err = tb.WriteTo(&buf)
+	assert.ErrorIs(t, err, ErrNotFinalized)
+}
+
+func TestMaxProbeLength(t *testing.T) {
+	tb := New(8, 0, 100)
+	tb.hashes[7] = 7
+	tb.hashes[0] = 7
+	tb.hashes[1] = 15
+	tb.hashes[4] = 4
+	assert.Equal(t, 3, tb.maxProbeLength())
+}
+
+func TestMaxProbe(t *testing.T) {
+	// A full table has some long probe sequences
+	tb := buildTable(t, 128, WithSeed(1))
+	report := tb.Finalize()
+	assert.Greater(t, report.MaxProbeLength, 1)
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, report.MaxProbeLength, r.maxProbe)
+	for i := 0; i < 128; i++ {
+		_, ok := r.GetPtr(fmt.Sprintf("key%d", 128-i))
+		assert.True(t, ok)
+	}
+	// With every slot full, a miss would examine every slot without the limit
+	_, ok := r.GetPtr("missing")
+	assert.False(t, ok)
+
+	// The limit is honoured. Keys further than one slot from home aren't found.
+	r.maxProbe = 1
+	var found int
+	for i := 0; i < 128; i++ {
+		if _, ok := r.GetPtr(fmt.Sprintf("key%d", 128-i)); ok {
+			found++
+		}
+	}
+	assert.Less(t, found, 128)
+	assert.Greater(t, found, 0)
+}
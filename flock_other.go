@@ -0,0 +1,25 @@
+//go:build aix || solaris
+
+package statichash
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory lock on f without waiting. These platforms don't have flock, so we use fcntl
+// locks instead. These are held by the process rather than the open file, so they don't protect one part of a
+// process from another.
+func lockFile(f *os.File, exclusive bool) error {
+	lk := syscall.Flock_t{Type: syscall.F_RDLCK, Whence: io.SeekStart}
+	if exclusive {
+		lk.Type = syscall.F_WRLCK
+	}
+	err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk)
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EACCES) {
+		return ErrLocked
+	}
+	return err
+}
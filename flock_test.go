@@ -0,0 +1,43 @@
+package statichash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocking(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "table")
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(name))
+
+	r, err := NewFrom(name, WithSharedLock())
+	assert.NoError(t, err)
+	assert.Equal(t, 10, r.Len())
+
+	// Readers can share the file, but it can't be rewritten while they have it open
+	r2, err := NewFrom(name, WithSharedLock(), WithWindowedMapping(4096, 1))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, tb.WriteFile(name), ErrLocked)
+	assert.NoError(t, r2.Close())
+	assert.ErrorIs(t, tb.WriteFile(name), ErrLocked)
+	assert.NoError(t, r.Close())
+	assert.NoError(t, tb.WriteFile(name))
+
+	// A reader can't open the file while it is being written
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, lockFile(f, true))
+	_, err = NewFrom(name, WithSharedLock())
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// Readers that don't ask for the lock aren't affected
+	r, err = NewFrom(name)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, r.Len())
+	assert.NoError(t, r.Close())
+}
@@ -0,0 +1,77 @@
+package statichash
+
+import "fmt"
+
+// The file format has changed over time. The current version is written with fileMagic and the header type.
+// Older versions are read by converting their headers to the current header type. The sections after the
+// header have not changed, although they start at a different offset. Options that change how the sections are
+// laid out, such as interleaved slots, are marked by a flag rather than a new version, as readers reject files
+// with flags they don't know.
+const (
+	// formatV0 files have no magic number, and a header with only the number of slots and the value size, as
+	// described in layout.go. They have no entry count, flags or sections.
+	formatV0 = 0
+	// formatV1 added the format version and header size to the header
+	formatV1 = 1
+	// formatV2 added the section directory. The header gained the directory's offset and size.
+	formatV2 = 2
+	// formatV3 added the cuckoo seed to the header
+	formatV3 = 3
+
+	// currentFormat is the version of the format written by this package
+	currentFormat = formatV3
+)
+
+// fileMagic marks the start of a file with a format version in its header
+var fileMagic = [8]byte{'S', 'T', 'A', 'T', 'H', 'A', 'S', 'H'}
+
+// minHeaderSize is the size of the smallest header with a magic number, that of formatV1, which ends where
+// formatV2 added the directory
+const minHeaderSize = headerDirectory
+
+// readHeader reads the header at the start of data, whatever version of the format it is. It returns the
+// header converted to the current version, but does not validate it.
+func readHeader(data []byte) (h header, err error) {
+	if len(data) < len(fileMagic) {
+		return h, fmt.Errorf("%w: data is only %d bytes long", ErrTruncated, len(data))
+	}
+
+	switch [8]byte(data) {
+	case fileMagic:
+		if len(data) < 16 {
+			return h, fmt.Errorf("%w: data is only %d bytes long", ErrTruncated, len(data))
+		}
+		if format := fileByteOrder.Uint32(data[headerFormat:]); format > currentFormat {
+			return h, fmt.Errorf("%w: file format version %d is newer than this package supports (%d)", ErrUnsupportedFormat, format, currentFormat)
+		}
+		// Fields are only ever added to the end of the header, so the header of an older version is a prefix
+		// of the current one. Fields the file doesn't have are left zero.
+		size := min(int(fileByteOrder.Uint32(data[headerHeaderSize:])), headerSize)
+		if size < minHeaderSize {
+			return h, fmt.Errorf("%w: header size %d", ErrCorrupt, size)
+		}
+		if len(data) < size {
+			return h, fmt.Errorf("%w: data is only %d bytes long, but the header is %d", ErrTruncated, len(data), size)
+		}
+		return decodeHeader(data, size), nil
+	}
+
+	if len(data) >= headerV0Size && isHeaderV0(data) {
+		return decodeHeaderV0(data), nil
+	}
+	return h, ErrBadMagic
+}
+
+// countV0 sets the number of entries of a table read from a formatV0 file, whose header doesn't record it, by
+// counting the occupied slots. Its slots must be set.
+func (t *table) countV0(h *header) {
+	if h.format != formatV0 {
+		return
+	}
+	t.count = 0
+	for i := range t.numItems {
+		if t.hashAt(i) != 0 {
+			t.count++
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeV0 returns a formatV0 file holding keys, with the value of each its index times 3, laid out as the
+// first version of this package wrote it: a 16 byte header of the number of slots and the value size, then a
+// copy of an arena whose sections were placed as if after a second copy of the header.
+func writeV0(keys []string) []byte {
+	const valueSize = 8
+	numItems := 1
+	for numItems < len(keys) {
+		numItems *= 2
+	}
+	var totalKeyLength int
+	for _, key := range keys {
+		totalKeyLength += len(key)
+	}
+	hashes := 16
+	keyOffsets := int(roundUp(int64(hashes+4*numItems), 8))
+	values := keyOffsets + 8*numItems
+	keyData := values + valueSize*numItems
+	arena := make([]byte, keyData+totalKeyLength+4*numItems)
+
+	var used int
+	for i, key := range keys {
+		h := uint32(Hash(key))
+		cursor := int(h) & (numItems - 1)
+		for fileByteOrder.Uint32(arena[hashes+4*cursor:]) != 0 {
+			cursor = (cursor + 1) & (numItems - 1)
+		}
+		fileByteOrder.PutUint32(arena[hashes+4*cursor:], h)
+		fileByteOrder.PutUint64(arena[keyOffsets+8*cursor:], uint64(used))
+		fileByteOrder.PutUint64(arena[values+valueSize*cursor:], uint64(i*3))
+		used += binary.PutVarint(arena[keyData+used:], int64(len(key)))
+		used += copy(arena[keyData+used:], key)
+	}
+
+	header := make([]byte, 16)
+	fileByteOrder.PutUint64(header, uint64(numItems))
+	fileByteOrder.PutUint64(header[8:], valueSize)
+	return append(header, arena...)
+}
+
+func TestFormatV0(t *testing.T) {
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	data := writeV0(keys)
+
+	check := func(t *testing.T, r *Read) {
+		assert.Equal(t, 50, r.Len())
+		assert.NoError(t, r.Validate())
+		for i, key := range keys {
+			v, ok := r.GetPtr(key)
+			if assert.True(t, ok, key) {
+				assert.Equal(t, int64(i*3), *(*int64)(v))
+			}
+		}
+		_, ok := r.GetPtr("missing")
+		assert.False(t, ok)
+	}
+
+	t.Run("bytes", func(t *testing.T) {
+		r, err := NewFromBytes(data)
+		assert.NoError(t, err)
+		check(t, r)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "table")
+		assert.NoError(t, os.WriteFile(name, data, 0o644))
+		r, err := NewFrom(name)
+		assert.NoError(t, err)
+		defer r.Close()
+		check(t, r)
+
+		w, err := NewFrom(name, WithWindowedMapping(4096, 2))
+		assert.NoError(t, err)
+		defer w.Close()
+		check(t, w)
+	})
+
+	t.Run("salvage", func(t *testing.T) {
+		s, lost, err := Salvage(data)
+		assert.NoError(t, err)
+		assert.Zero(t, lost)
+		assert.Equal(t, 50, s.Len())
+	})
+
+	t.Run("not v0", func(t *testing.T) {
+		// The unused bytes after the header must be zero
+		bad := bytes.Clone(data)
+		bad[20] = 1
+		_, err := NewFromBytes(bad)
+		assert.ErrorIs(t, err, ErrBadMagic)
+	})
+}
+
+// toV1 rewrites a current table file as a formatV1 file, which has no section directory
+func toV1(t *testing.T, data []byte) []byte {
+	h, body := splitFile(t, data)
+	h.format = formatV1
+	h.headerSize = 128
+	h.directory, h.sections = 0, 0
+	b := make([]byte, headerSize)
+	h.encode(b)
+	return append(b[:h.headerSize], body...)
+}
+
+// splitFile returns a copy of the header of a table file and the sections up to the end of the key data
+func splitFile(t *testing.T, data []byte) (header, []byte) {
+	h, err := readHeader(data)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(data)
+	assert.NoError(t, err)
+	return h, append([]byte(nil), data[h.headerSize:r.layout.length]...)
+}
+
+func TestFormatOldVersions(t *testing.T) {
+	tb := New(100, 8, 1000, WithInsertionOrder(), WithFingerprints(), WithVersion("v0 table"))
+	for i := 0; i < 50; i++ {
+		v := int64(i * 3)
+		assert.NoError(t, tb.Set(string(rune('A'+i)), unsafe.Pointer(&v)))
+	}
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	current, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{name: "v1", data: toV1(t, buf.Bytes())},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data := test.data
+			check := func(t *testing.T, r *Read) {
+				assert.True(t, Equal(current, r))
+				assert.Equal(t, "v0 table", r.Info().Version)
+				assert.NoError(t, r.Validate())
+				v, ok := r.GetPtr("C")
+				if assert.True(t, ok) {
+					assert.Equal(t, int64(6), *(*int64)(v))
+				}
+			}
+
+			t.Run("bytes", func(t *testing.T) {
+				r, err := NewFromBytes(data)
+				assert.NoError(t, err)
+				check(t, r)
+			})
+
+			t.Run("file", func(t *testing.T) {
+				name := filepath.Join(t.TempDir(), "table")
+				assert.NoError(t, os.WriteFile(name, data, 0o644))
+				r, err := NewFrom(name)
+				assert.NoError(t, err)
+				defer r.Close()
+				check(t, r)
+
+				w, err := NewFrom(name, WithWindowedMapping(4096, 2))
+				assert.NoError(t, err)
+				defer w.Close()
+				check(t, w)
+			})
+
+			t.Run("salvage", func(t *testing.T) {
+				s, lost, err := Salvage(data)
+				assert.NoError(t, err)
+				assert.Zero(t, lost)
+				assert.Equal(t, 50, s.Len())
+			})
+		})
+	}
+}
+
+func TestFormatUnsupported(t *testing.T) {
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	data := buf.Bytes()
+	fileByteOrder.PutUint32(data[headerFormat:], currentFormat+1)
+	_, err = NewFromBytes(data)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	fileByteOrder.PutUint32(data[headerFormat:], currentFormat)
+	fileByteOrder.PutUint32(data[headerHeaderSize:], 7)
+	_, err = NewFromBytes(data)
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
@@ -0,0 +1,147 @@
+package statichash
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// FromMap builds a finalized table containing the entries of m. Every value must be the same length, otherwise it
+// returns an error wrapping ErrValueSizeMismatch. Keys are added in sorted order, so a table built WithSeed
+// from the same map is always identical.
+func FromMap(m map[string][]byte, opts ...Option) (*Write, error) {
+	return FromMapContext(context.Background(), m, opts...)
+}
+
+// FromMapContext is like FromMap, but stops and returns the context's error if ctx is cancelled
+func FromMapContext(ctx context.Context, m map[string][]byte, opts ...Option) (*Write, error) {
+	keys, keyLength := sortedKeys(m)
+
+	var valueSize int
+	if len(keys) > 0 {
+		valueSize = len(m[keys[0]])
+	}
+
+	t := New(len(keys), int64(valueSize), keyLength, opts...)
+	for i, key := range keys {
+		if i%contextCheckEntries == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		value := m[key]
+		if len(value) != valueSize {
+			return nil, fmt.Errorf("%w: value for %q is %d bytes, expected %d", ErrValueSizeMismatch, key, len(value), valueSize)
+		}
+		if err := t.Set(key, bytesPointer(value)); err != nil {
+			return nil, err
+		}
+	}
+	t.Finalize()
+	return t, nil
+}
+
+// FromMapT builds a finalized table containing the entries of m, with values of type T. T must not contain any pointers,
+// as the bytes of each value are copied into the table. The table is built WithValueType[T], so NewTyped can
+// check it is read with the same type.
+func FromMapT[T any](m map[string]T, opts ...Option) (*Write, error) {
+	return FromMapTContext(context.Background(), m, opts...)
+}
+
+// FromMapTContext is like FromMapT, but stops and returns the context's error if ctx is cancelled
+func FromMapTContext[T any](ctx context.Context, m map[string]T, opts ...Option) (*Write, error) {
+	keys, keyLength := sortedKeys(m)
+
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	gaps := padding(reflect.TypeFor[T]())
+	buf := make([]byte, size)
+
+	t := New(len(keys), int64(size), keyLength, append(opts[:len(opts):len(opts)], WithValueType[T]())...)
+	for i, key := range keys {
+		if i%contextCheckEntries == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		value := m[key]
+		copy(buf, unsafe.Slice((*byte)(unsafe.Pointer(&value)), size))
+		// Padding isn't necessarily zeroed when values are copied, so we clear it to keep the output
+		// reproducible
+		for _, gap := range gaps {
+			clear(buf[gap[0]:gap[1]])
+		}
+		if err := t.Set(key, bytesPointer(buf)); err != nil {
+			return nil, err
+		}
+	}
+	t.Finalize()
+	return t, nil
+}
+
+// padding returns the start and end offsets of the runs of padding bytes in values of type typ
+func padding(typ reflect.Type) (gaps [][2]int) {
+	used := make([]bool, typ.Size())
+	markUsed(typ, used)
+	for i := 0; i < len(used); i++ {
+		if used[i] {
+			continue
+		}
+		start := i
+		for i < len(used) && !used[i] {
+			i++
+		}
+		gaps = append(gaps, [2]int{start, i})
+	}
+	return gaps
+}
+
+// markUsed marks the bytes of used that hold data rather than padding
+func markUsed(typ reflect.Type, used []bool) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			markUsed(f.Type, used[f.Offset:f.Offset+f.Type.Size()])
+		}
+	case reflect.Array:
+		size := int(typ.Elem().Size())
+		for i := 0; i < typ.Len(); i++ {
+			markUsed(typ.Elem(), used[i*size:(i+1)*size])
+		}
+	default:
+		for i := range used {
+			used[i] = true
+		}
+	}
+}
+
+// FromMapOf is like FromMapT, but first checks that T contains no pointers. If it does it returns an error
+// wrapping ErrHasPointers that names the offending field.
+func FromMapOf[T any](m map[string]T, opts ...Option) (*Write, error) {
+	return FromMapOfContext(context.Background(), m, opts...)
+}
+
+// FromMapOfContext is like FromMapOf, but stops and returns the context's error if ctx is cancelled
+func FromMapOfContext[T any](ctx context.Context, m map[string]T, opts ...Option) (*Write, error) {
+	if err := checkPointerFree(reflect.TypeFor[T]()); err != nil {
+		return nil, err
+	}
+	return FromMapTContext(ctx, m, opts...)
+}
+
+// contextCheckEntries is how many entries the FromMap functions add between checks of the context
+const contextCheckEntries = 1 << 10
+
+// sortedKeys returns the keys of m in order, and the total key length to pass to New
+func sortedKeys[T any](m map[string]T) (keys []string, keyLength int64) {
+	keys = make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+		keyLength += int64(len(key))
+	}
+	sort.Strings(keys)
+	return keys, keyLength
+}
@@ -0,0 +1,145 @@
+package statichash
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromMap(t *testing.T) {
+	tb, err := FromMap(map[string][]byte{
+		"a":   []byte("one"),
+		"bb":  []byte("two"),
+		"ccc": []byte("six"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, tb.Len())
+	assert.Equal(t, 3, tb.ValueSize())
+
+	var buf bytes.Buffer
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	for k, v := range map[string]string{"a": "one", "bb": "two", "ccc": "six"} {
+		p, ok := tr.GetPtr(k)
+		assert.True(t, ok)
+		assert.Equal(t, v, string((*[3]byte)(p)[:]))
+	}
+
+	_, err = FromMap(map[string][]byte{"a": []byte("one"), "b": []byte("three")})
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+
+	tb, err = FromMap(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, tb.Len())
+}
+
+func TestFromMapT(t *testing.T) {
+	type value struct {
+		A int32
+		B float64
+	}
+	m := map[string]value{
+		"hat":   {A: 1, B: 2.5},
+		"coat":  {A: 2, B: 3.5},
+		"scarf": {A: 3, B: 4.5},
+	}
+
+	build := func() []byte {
+		tb, err := FromMapT(m, WithSeed(7))
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		_, err = tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		return buf.Bytes()
+	}
+
+	data := build()
+	assert.Equal(t, data, build())
+
+	tr, err := NewFromBytes(data)
+	assert.NoError(t, err)
+	for k, v := range m {
+		p, ok := tr.GetPtr(k)
+		assert.True(t, ok)
+		assert.Equal(t, v, *(*value)(p))
+	}
+}
+
+func TestFromMapOf(t *testing.T) {
+	type good struct {
+		A [4]int16
+		B struct{ C uint64 }
+	}
+	tb, err := FromMapOf(map[string]good{"a": {A: [4]int16{1, 2, 3, 4}}})
+	assert.NoError(t, err)
+	p, ok := tb.GetPtr("a")
+	assert.True(t, ok)
+	assert.Equal(t, int16(3), (*good)(p).A[2])
+
+	type bad struct {
+		A int
+		B [2]struct {
+			C float64
+			D string
+		}
+	}
+	_, err = FromMapOf(map[string]bad{"a": {}})
+	assert.ErrorIs(t, err, ErrHasPointers)
+	assert.EqualError(t, err, "statichash: value type contains pointers: statichash.bad.B[].D is a string")
+
+	_, err = FromMapOf(map[string]*int{})
+	assert.ErrorIs(t, err, ErrHasPointers)
+}
+
+func TestFromMapContext(t *testing.T) {
+	m := map[string]int64{"a": 1, "b": 2}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tb, err := FromMapOfContext(ctx, m)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tb.Len())
+
+	cancel()
+	_, err = FromMapOfContext(ctx, m)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = FromMapContext(ctx, map[string][]byte{"a": {1}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFromMapEmpty(t *testing.T) {
+	tb, err := FromMap(map[string][]byte{})
+	assert.NoError(t, err)
+	_, ok := tb.GetPtr("a")
+	assert.False(t, ok)
+	tr := readBack(t, tb)
+	assert.Zero(t, tr.Len())
+	_, ok = tr.GetPtr("a")
+	assert.False(t, ok)
+
+	tb, err = FromMapT(map[string]int64{})
+	assert.NoError(t, err)
+	tr = readBack(t, tb)
+	assert.Zero(t, tr.Len())
+	_, ok = tr.GetPtr("a")
+	assert.False(t, ok)
+}
+
+func TestPadding(t *testing.T) {
+	type inner struct {
+		A int8
+		B int16
+	}
+	type value struct {
+		A int32
+		B float64
+		C [2]inner
+		D bool
+	}
+	assert.Equal(t, [][2]int{{4, 8}, {17, 18}, {21, 22}, {25, 32}}, padding(reflect.TypeFor[value]()))
+	assert.Nil(t, padding(reflect.TypeFor[[4]int64]()))
+}
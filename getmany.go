@@ -0,0 +1,53 @@
+package statichash
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// getManyBatch is how many keys GetMany hashes and prefetches at a time
+const getManyBatch = 64
+
+// prefetchSink stops the compiler optimising away the reads GetMany uses to prefetch slots
+var prefetchSink uint32
+
+// GetMany looks up several keys at once. The value for keys[i] is put in out[i], and the returned slice
+// records whether each key was found. out must be at least as long as keys.
+//
+// All the keys are hashed and their slots touched before any are probed, so the cache misses for the slots
+// overlap rather than being taken one after another. This is considerably faster than calling GetPtr for each
+// key when the table is much larger than the CPU cache.
+func (t *table) GetMany(keys []string, out []unsafe.Pointer) []bool {
+	found := make([]bool, len(keys))
+	out = out[:len(keys)]
+
+	var hashes [getManyBatch]uint64
+	for start := 0; start < len(keys); start += getManyBatch {
+		batch := keys[start:]
+		if len(batch) > getManyBatch {
+			batch = batch[:getManyBatch]
+		}
+
+		for i, key := range batch {
+			hashes[i] = t.hashKey(key)
+		}
+
+		var sink hash
+		for i := range batch {
+			sink ^= t.hashAt(int(slotHash(hashes[i])) & (t.numItems - 1))
+		}
+		atomic.StoreUint32(&prefetchSink, uint32(sink))
+
+		for i, key := range batch {
+			index, ok := t.find(key, hashes[i])
+			var val unsafe.Pointer
+			if ok {
+				val, ok = t.lookupPtr(index)
+			}
+			out[start+i] = val
+			found[start+i] = ok
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,57 @@
+package statichash
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMany(t *testing.T) {
+	tb := buildTable(t, 200)
+
+	keys := make([]string, 150)
+	for i := range keys {
+		// Every third key is missing
+		if i%3 == 0 {
+			keys[i] = fmt.Sprintf("missing%d", i)
+		} else {
+			keys[i] = fmt.Sprintf("key%d", i)
+		}
+	}
+
+	out := make([]unsafe.Pointer, len(keys))
+	found := tb.GetMany(keys, out)
+	assert.Len(t, found, len(keys))
+	for i, key := range keys {
+		v, ok := tb.GetPtr(key)
+		assert.Equal(t, ok, found[i], key)
+		assert.Equal(t, v, out[i], key)
+	}
+}
+
+func BenchmarkGetMany(b *testing.B) {
+	tb := New(1_000_000, 8, 20_000_000)
+	keys := make([]string, 1_000_000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		tb.Set(keys[i], unsafe.Pointer(&i))
+	}
+	out := make([]unsafe.Pointer, 64)
+
+	b.Run("GetMany", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			start := (i * 64) % (len(keys) - 64)
+			tb.GetMany(keys[start:start+64], out)
+		}
+	})
+	b.Run("GetPtr", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			start := (i * 64) % (len(keys) - 64)
+			for j, key := range keys[start : start+64] {
+				out[j], _ = tb.GetPtr(key)
+			}
+		}
+	})
+}
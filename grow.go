@@ -0,0 +1,70 @@
+package statichash
+
+import "encoding/binary"
+
+// hasKeySpace returns true if there's room in the key data for key
+func (t *Write) hasKeySpace(key string) bool {
+	var buf [binary.MaxVarintLen64]byte
+	return t.keyOffset+binary.PutVarint(buf[:], int64(len(key)))+len(key) <= len(t.keyData)
+}
+
+// growKeyData at least doubles the space for key data, making sure there's room for a string of length n
+func (t *Write) growKeyData(n int) {
+	keyLength := int64(len(t.keyData)) * 2
+	for keyLength < int64(t.keyOffset+binary.MaxVarintLen64+n) {
+		keyLength *= 2
+	}
+	t.grow(t.numItems, keyLength)
+}
+
+// grow rehashes the table into a new arena with numItems slots and totalKeyLength bytes for keys. The key data
+// is copied as is, so offsets into it remain valid. Entries keep their insertion order.
+func (t *Write) grow(numItems int, totalKeyLength int64) {
+	if t.flags&flagCuckoo != 0 {
+		t.growCuckoo(numItems, totalKeyLength)
+		return
+	}
+	for {
+		n := New(numItems, int64(t.valueSize), totalKeyLength, t.options()...)
+		if t.rehashInto(n) {
+			t.table = n.table
+			return
+		}
+		// Some key didn't fit in its hopscotch neighbourhood
+		numItems *= 2
+	}
+}
+
+// rehashInto adds every entry of t to the empty table n, returning false if one doesn't fit
+func (t *Write) rehashInto(n *Write) bool {
+	n.created = t.created
+	n.version = t.version
+	n.keyOffset = copy(n.keyData, t.keyData[:t.keyOffset])
+
+	add := func(slot int) bool {
+		key := t.getKey(t.keyAt(slot))
+		// n hashes keys as t does, so a table built WithHashOnly can use the hashes it stores
+		h := t.entryHash(slot)
+		index, _ := n.find(key, h)
+		if index = n.hopscotchSlot(h, index); index < 0 {
+			return false
+		}
+		n.insert(index, h, t.keyAt(slot))
+		n.setValue(index, t.value(slot))
+		return true
+	}
+	if t.order != nil {
+		for _, slot := range t.order[:t.count] {
+			if !add(int(slot)) {
+				return false
+			}
+		}
+	} else {
+		for slot := range t.numItems {
+			if t.hashAt(slot) != 0 && !add(slot) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoGrow(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "basic"},
+		{name: "insertion order", opts: []Option{WithInsertionOrder(), WithFingerprints()}},
+		{name: "seeded", opts: []Option{WithSeed(42), WithVersion("v1")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := New(2, 8, 4, append(test.opts, WithAutoGrow())...)
+			for i := 0; i < 1000; i++ {
+				assert.NoError(t, tb.Set(fmt.Sprintf("a rather long key %d", i), unsafe.Pointer(&i)))
+			}
+			assert.Equal(t, 1000, tb.Len())
+			assert.Equal(t, 1024, tb.NumSlots())
+
+			var buf bytes.Buffer
+			tb.Finalize()
+			_, err := tb.WriteTo(&buf)
+			assert.NoError(t, err)
+			tr, err := NewFromBytes(buf.Bytes())
+			assert.NoError(t, err)
+			assert.Equal(t, tb.flags, tr.flags)
+			assert.Equal(t, tb.seed, tr.seed)
+			assert.Equal(t, tb.version, tr.version)
+
+			for i := 0; i < 1000; i++ {
+				v, ok := tr.GetPtr(fmt.Sprintf("a rather long key %d", i))
+				if assert.True(t, ok, i) {
+					assert.Equal(t, i, *(*int)(v))
+				}
+			}
+
+			if tb.order != nil {
+				i := 0
+				for it := tr.Iterate(); it.Next(); i++ {
+					assert.Equal(t, fmt.Sprintf("a rather long key %d", i), it.Key())
+				}
+			}
+		})
+	}
+}
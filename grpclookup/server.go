@@ -0,0 +1,72 @@
+// Package grpclookup defines a small gRPC service for looking keys up in a statichash table, so a table can be
+// served over the network by a sidecar rather than every consumer mapping the file.
+//
+//	s := grpc.NewServer()
+//	grpclookup.RegisterLookupServer(s, grpclookup.NewServer(r))
+//
+// Values are returned as raw bytes. The service is defined in lookup.proto.
+package grpclookup
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative lookup.proto
+
+import (
+	"context"
+	"unsafe"
+
+	"github.com/philpearl/statichash"
+)
+
+// Server implements LookupServer against a table
+type Server struct {
+	UnimplementedLookupServer
+	r *statichash.Read
+}
+
+// NewServer returns a Server that looks keys up in r
+func NewServer(r *statichash.Read) *Server {
+	return &Server{r: r}
+}
+
+// Get looks up a single key
+func (s *Server) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	p, ok := s.r.GetPtr(req.Key)
+	return s.response(p, ok), nil
+}
+
+// MultiGet looks up several keys at once
+func (s *Server) MultiGet(ctx context.Context, req *MultiGetRequest) (*MultiGetResponse, error) {
+	values := make([]unsafe.Pointer, len(req.Keys))
+	found := s.r.GetMany(req.Keys, values)
+
+	resp := &MultiGetResponse{Results: make([]*GetResponse, len(req.Keys))}
+	for i, p := range values {
+		resp.Results[i] = s.response(p, found[i])
+	}
+	return resp, nil
+}
+
+// Stats describes the table
+func (s *Server) Stats(ctx context.Context, req *StatsRequest) (*StatsResponse, error) {
+	info := s.r.Info()
+	resp := &StatsResponse{
+		Count:     int64(s.r.Len()),
+		Slots:     int64(s.r.NumSlots()),
+		ValueSize: int64(s.r.ValueSize()),
+		FileSize:  s.r.FileLen(),
+		Version:   info.Version,
+	}
+	if !info.Created.IsZero() {
+		resp.Created = info.Created.UnixNano()
+	}
+	return resp, nil
+}
+
+// response builds a GetResponse for a value. The value is copied as the response may outlive the table.
+func (s *Server) response(p unsafe.Pointer, found bool) *GetResponse {
+	if !found {
+		return &GetResponse{}
+	}
+	value := make([]byte, s.r.ValueSize())
+	copy(value, unsafe.Slice((*byte)(p), len(value)))
+	return &GetResponse{Found: true, Value: value}
+}
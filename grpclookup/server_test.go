@@ -0,0 +1,69 @@
+package grpclookup
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/philpearl/statichash"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	tb, err := statichash.FromMap(map[string][]byte{
+		"hat":  []byte("red"),
+		"coat": []byte("tan"),
+	}, statichash.WithSeed(1), statichash.WithVersion("v7"), statichash.WithBuildTime(time.Unix(10, 0)))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := statichash.NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 16)
+	s := grpc.NewServer()
+	RegisterLookupServer(s, NewServer(r))
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := NewLookupClient(conn)
+	ctx := context.Background()
+
+	got, err := client.Get(ctx, &GetRequest{Key: "hat"})
+	assert.NoError(t, err)
+	assert.True(t, got.Found)
+	assert.Equal(t, []byte("red"), got.Value)
+
+	got, err = client.Get(ctx, &GetRequest{Key: "gloves"})
+	assert.NoError(t, err)
+	assert.False(t, got.Found)
+
+	many, err := client.MultiGet(ctx, &MultiGetRequest{Keys: []string{"coat", "gloves", "hat"}})
+	assert.NoError(t, err)
+	if assert.Len(t, many.Results, 3) {
+		assert.Equal(t, []byte("tan"), many.Results[0].Value)
+		assert.False(t, many.Results[1].Found)
+		assert.Equal(t, []byte("red"), many.Results[2].Value)
+	}
+
+	stats, err := client.Stats(ctx, &StatsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Count)
+	assert.Equal(t, int64(2), stats.Slots)
+	assert.Equal(t, int64(3), stats.ValueSize)
+	assert.Equal(t, int64(buf.Len()), stats.FileSize)
+	assert.Equal(t, "v7", stats.Version)
+	assert.Equal(t, int64(10e9), stats.Created)
+}
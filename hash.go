@@ -0,0 +1,85 @@
+package statichash
+
+import (
+	"math/bits"
+
+	"github.com/philpearl/aeshash"
+)
+
+// hashKey hashes a key using the hash function the table was built with. The low 32 bits are the hash stored
+// in the slot and the next 8 bits are the fingerprint.
+func (t *table) hashKey(key string) uint64 {
+	if t.flags&flagSeeded != 0 {
+		return seededHash(t.seed, key)
+	}
+	return Hash(key)
+}
+
+// Hash returns the hash of key using the hash function and seed the table was built with. Use it to partition
+// or route keys consistently with the table, or to pass to GetPtrWithHash.
+func (t *table) Hash(key string) uint64 {
+	return t.hashKey(key)
+}
+
+// Hash returns the hash of key as used by tables built without WithSeed. Pass it to GetPtrWithHash if you
+// need the hash for other purposes too.
+func Hash(key string) uint64 {
+	return uint64(aeshash.Hash(key))
+}
+
+// HashWithSeed returns the hash of key as used by tables built WithSeed(seed)
+func HashWithSeed(seed uint64, key string) uint64 {
+	return seededHash(seed, key)
+}
+
+// slotHash returns the hash we store in a slot for the full hash h. Zero marks an empty slot, so we never
+// return it.
+func slotHash(h uint64) hash {
+	if hash(h) == 0 {
+		return 1
+	}
+	return hash(h)
+}
+
+// fingerprint returns the fingerprint we store for the full hash h
+func fingerprint(h uint64) uint8 {
+	return uint8(h >> 32)
+}
+
+const (
+	prime1 = 0xa0761d6478bd642f
+	prime2 = 0xe7037ed1a0b428db
+	prime3 = 0x8ebc6af09c88c6e3
+	prime4 = 0x589965cc75374cc3
+)
+
+// seededHash is a simple multiply-mix hash that gives the same answer in every process for a given seed.
+// aeshash is seeded per process, so we use this when the output file must be reproducible. It is plain Go that
+// reads keys as little-endian, so it gives the same answer on every architecture, and a table built WithSeed on
+// amd64 can be read on arm64.
+func seededHash(seed uint64, key string) uint64 {
+	h := seed ^ prime1 ^ (uint64(len(key)) * prime2)
+	for len(key) >= 8 {
+		h = mix(h^read64(key), prime3)
+		key = key[8:]
+	}
+
+	var tail uint64
+	for i := 0; i < len(key); i++ {
+		tail |= uint64(key[i]) << (8 * uint(i))
+	}
+	h = mix(h^tail, prime4)
+
+	return mix(h, prime1)
+}
+
+// read64 reads the first 8 bytes of s as a little-endian uint64
+func read64(s string) uint64 {
+	return uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+		uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+}
+
+func mix(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
@@ -0,0 +1,127 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeededHash(t *testing.T) {
+	assert.Equal(t, seededHash(1, "hello"), seededHash(1, "hello"))
+	assert.NotEqual(t, seededHash(1, "hello"), seededHash(2, "hello"))
+	assert.NotEqual(t, seededHash(1, "hello"), seededHash(1, "hellp"))
+	assert.NotEqual(t, seededHash(1, "a long key that is more than 8 bytes"), seededHash(1, "a long key that is more than 8 byteS"))
+}
+
+func TestSeededHashValues(t *testing.T) {
+	// These must not change, and must be the same on every architecture, or existing files built WithSeed
+	// can't be read
+	for _, test := range []struct {
+		seed uint64
+		key  string
+		exp  uint64
+	}{
+		{0, "", 0x97e865d7ec7ad94e},
+		{1, "hello", 0xb6e8b6d62722ee34},
+		{12345, "a long key that is more than 8 bytes", 0x9e32c32752dd8a94},
+		{1 << 63, "key7", 0x49c0f1634465920},
+	} {
+		assert.Equal(t, test.exp, seededHash(test.seed, test.key), "%d %q", test.seed, test.key)
+	}
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	build := func() []byte {
+		tb := buildTable(t, 50, WithSeed(12345))
+		var buf bytes.Buffer
+		tb.Finalize()
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		return buf.Bytes()
+	}
+
+	data := build()
+	assert.Equal(t, data, build())
+
+	tr, err := NewFromBytes(data)
+	assert.NoError(t, err)
+	v, ok := tr.GetPtr("key50")
+	assert.True(t, ok)
+	assert.Equal(t, 0, *(*int)(v))
+
+	_, ok = tr.GetPtr("key51")
+	assert.False(t, ok)
+}
+
+func TestFingerprints(t *testing.T) {
+	tb := buildTable(t, 100, WithFingerprints())
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Len(t, tr.fingerprints, 128)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", 100-i)
+		v, ok := tr.GetPtr(key)
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+
+		index, _ := tr.find(key, tr.hashKey(key))
+		assert.Equal(t, fingerprint(tr.hashKey(key)), tr.fingerprints[index])
+	}
+
+	// With the fingerprint wrong the key isn't found, even when we trust the hashes
+	tr.trusted = true
+	index, _ := tr.find("key1", tr.hashKey("key1"))
+	tr.fingerprints[index]++
+	_, ok := tr.GetPtr("key1")
+	assert.False(t, ok)
+}
+
+func TestGetPtrWithHash(t *testing.T) {
+	for _, seeded := range []bool{false, true} {
+		var opts []Option
+		hashFn := Hash
+		if seeded {
+			opts = append(opts, WithSeed(42))
+			hashFn = func(key string) uint64 { return HashWithSeed(42, key) }
+		}
+		tb := buildTable(t, 100, opts...)
+
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%d", 100-i)
+			v, ok := tb.GetPtrWithHash(key, hashFn(key))
+			if assert.True(t, ok) {
+				assert.Equal(t, i, *(*int)(v))
+			}
+		}
+		_, ok := tb.GetPtrWithHash("key101", hashFn("key101"))
+		assert.False(t, ok)
+	}
+}
+
+func TestTableHash(t *testing.T) {
+	tb := buildTable(t, 10)
+	assert.Equal(t, Hash("key1"), tb.Hash("key1"))
+
+	tb = buildTable(t, 10, WithSeed(42))
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	assert.Equal(t, HashWithSeed(42, "key1"), tr.Hash("key1"))
+	v, ok := tr.GetPtrWithHash("key1", tr.Hash("key1"))
+	if assert.True(t, ok) {
+		assert.Equal(t, 9, *(*int)(v))
+	}
+}
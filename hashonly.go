@@ -0,0 +1,50 @@
+package statichash
+
+import "fmt"
+
+/*
+A table built WithHashOnly keeps no keys. Each slot stores the full 64-bit hash of its key where other tables
+store the offset of the key in the key data, and the key data is empty. A lookup hashes the key as usual, finds
+the slot whose hash and fingerprint match, then compares the stored hash with the whole hash of the key rather
+than comparing the keys themselves. Two keys with the same 64-bit hash are treated as the same key, so Set
+counts the second as a duplicate and a lookup of a key that was never Set can very rarely find the value of one
+that was.
+
+Growing the table rehashes the stored hashes rather than the keys, so the table must hash keys in the same way
+it did when they were Set. Anything else that needs the keys themselves, such as diffing, patching or rekeying
+the table, returns an error wrapping ErrNoKeys.
+*/
+
+// newKey saves key, whose hash is h, for a new entry and returns what to store in the entry's slot: the offset
+// of the key in the key data, or the hash itself for a table built WithHashOnly
+func (t *Write) newKey(key string, h uint64) keyOffset {
+	if t.flags&flagHashOnly != 0 {
+		return keyOffset(h)
+	}
+	return t.addKey(key)
+}
+
+// entryHash returns the full hash of the key in the occupied slot i
+func (t *table) entryHash(i int) uint64 {
+	if t.flags&flagHashOnly != 0 {
+		return uint64(t.keyAt(i))
+	}
+	return t.hashKey(t.getKey(t.keyAt(i)))
+}
+
+// sameKey returns true if the occupied slots i and j hold the same key
+func (t *table) sameKey(i, j int) bool {
+	if t.flags&flagHashOnly != 0 {
+		return t.keyAt(i) == t.keyAt(j)
+	}
+	return t.getKey(t.keyAt(i)) == t.getKey(t.keyAt(j))
+}
+
+// needKeys returns an error wrapping ErrNoKeys if t was built WithHashOnly, so can't be used for op, which needs
+// the keys
+func (t *table) needKeys(op string) error {
+	if t.flags&flagHashOnly != 0 {
+		return fmt.Errorf("%w: can't %s a table built WithHashOnly", ErrNoKeys, op)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "plain"},
+		{name: "seeded", opts: []Option{WithSeed(3)}},
+		{name: "fingerprints", opts: []Option{WithFingerprints()}},
+		{name: "insertion order", opts: []Option{WithInsertionOrder()}},
+		{name: "hopscotch", opts: []Option{WithHopscotch(), WithAutoGrow()}},
+		{name: "interleaved", opts: []Option{WithInterleavedSlots()}},
+		{name: "inline values", opts: []Option{WithInlineValues()}},
+		{name: "columns", opts: []Option{WithColumns(2, int(unsafe.Sizeof(int(0)))-2)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := buildTable(t, 500, append(test.opts, WithHashOnly())...)
+			assert.Zero(t, tb.KeyDataLen())
+			tb.Finalize()
+			r := readBack(t, tb)
+			checkEntries(t, r, 500)
+			_, ok := r.GetPtr("missing")
+			assert.False(t, ok)
+			assert.NoError(t, r.Validate())
+
+			hashes := make(map[uint64]bool)
+			for it := r.Iterate(); it.Next(); {
+				assert.Empty(t, it.Key())
+				hashes[it.Hash()] = true
+			}
+			assert.Len(t, hashes, 500)
+			assert.True(t, hashes[r.Hash("key17")])
+		})
+	}
+}
+
+func TestHashOnlySize(t *testing.T) {
+	const n = 1000
+	tb := New(n, int64(unsafe.Sizeof(int(0))), n*100)
+	for i := range n {
+		url := fmt.Sprintf("https://example.com/some/long/path/to/a/page/that/people/visit/often?id=%08d", i)
+		assert.NoError(t, tb.Set(url, unsafe.Pointer(&i)))
+	}
+	tb.Finalize()
+	r := readBack(t, tb)
+
+	w, err := Migrate(r, r.NumSlots(), append(r.Options(), WithHashOnly())...)
+	assert.NoError(t, err)
+	h := readBack(t, w)
+	assert.Less(t, h.FileLen(), r.FileLen()/2)
+
+	for i := range n {
+		url := fmt.Sprintf("https://example.com/some/long/path/to/a/page/that/people/visit/often?id=%08d", i)
+		v, ok := h.GetPtr(url)
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+}
+
+func TestHashOnlyGrow(t *testing.T) {
+	tb := New(10, int64(unsafe.Sizeof(int(0))), 0, WithHashOnly(), WithAutoGrow())
+	for i := range 1000 {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", 1000-i), unsafe.Pointer(&i)))
+	}
+	assert.Greater(t, tb.NumSlots(), 1000)
+	tb.Finalize()
+	checkEntries(t, readBack(t, tb), 1000)
+}
+
+func TestHashOnlyDuplicates(t *testing.T) {
+	tb := New(10, int64(unsafe.Sizeof(int(0))), 0, WithHashOnly())
+	one, two := 1, 2
+	assert.NoError(t, tb.Set("a", unsafe.Pointer(&one)))
+	assert.NoError(t, tb.Set("a", unsafe.Pointer(&two)))
+	assert.Equal(t, 1, tb.Len())
+	assert.Equal(t, 1, tb.Duplicates())
+	v, ok := tb.GetPtr("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, *(*int)(v))
+}
+
+func TestHashOnlyPanics(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"cuckoo":       {WithCuckoo()},
+		"sorted index": {WithSortedIndex()},
+		"blob values":  {WithBlobValues()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Panics(t, func() { New(10, 8, 0, append(opts, WithHashOnly())...) })
+		})
+	}
+}
+
+func TestHashOnlyNoKeys(t *testing.T) {
+	tb := buildTable(t, 100, WithHashOnly())
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	keyed := buildTable(t, 100)
+	keyed.Finalize()
+	k := readBack(t, keyed)
+
+	assert.ErrorIs(t, Diff(k, r, func(DiffKind, string) bool { return true }), ErrNoKeys)
+	assert.ErrorIs(t, WritePatch(io.Discard, r, k), ErrNoKeys)
+	_, err = ApplyPatch(r, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNoKeys)
+	_, err = Migrate(r, 100)
+	assert.ErrorIs(t, err, ErrNoKeys)
+	_, err = Rekey(r, func(key string) string { return key }, nil)
+	assert.ErrorIs(t, err, ErrNoKeys)
+	_, err = MapValues(r, 8, func(key string, old, new []byte) (bool, error) { return true, nil })
+	assert.ErrorIs(t, err, ErrNoKeys)
+	_, err = r.MarshalJSON()
+	assert.ErrorIs(t, err, ErrNoKeys)
+	_, err = r.WriteFilter(io.Discard, 10)
+	assert.ErrorIs(t, err, ErrNoKeys)
+	_, _, err = Salvage(buf.Bytes())
+	assert.ErrorIs(t, err, ErrNoKeys)
+	assert.ErrorIs(t, New(100, 8, 1000).ImportFrom(r, ImportOverwrite), ErrNoKeys)
+}
+
+func TestHashOnlyEqual(t *testing.T) {
+	keyed := buildTable(t, 100)
+	keyed.Finalize()
+	k := readBack(t, keyed)
+	hashOnly := func(opts ...Option) *Read {
+		tb := buildTable(t, 100, append(opts, WithHashOnly())...)
+		tb.Finalize()
+		return readBack(t, tb)
+	}
+
+	// The keys of a keyed table can be looked up in a hash-only one
+	assert.True(t, Equal(k, hashOnly()))
+	assert.True(t, Equal(hashOnly(), k))
+	assert.True(t, Equal(hashOnly(WithFingerprints()), hashOnly(WithInterleavedSlots())))
+	assert.True(t, Equal(hashOnly(WithSeed(1)), hashOnly(WithSeed(1))))
+	// The hashes of differently seeded tables can't be compared
+	assert.False(t, Equal(hashOnly(WithSeed(1)), hashOnly(WithSeed(2))))
+
+	other := New(100, int64(unsafe.Sizeof(int(0))), 0, WithHashOnly())
+	for i := range 100 {
+		v := i + 1
+		assert.NoError(t, other.Set(fmt.Sprintf("key%d", 100-i), unsafe.Pointer(&v)))
+	}
+	other.Finalize()
+	assert.False(t, Equal(hashOnly(), readBack(t, other)))
+}
+
+func TestHashOnlyValidate(t *testing.T) {
+	tb := buildTable(t, 100, WithHashOnly())
+	tb.Finalize()
+	r := readBack(t, tb)
+
+	for i := range r.numItems {
+		if r.hashAt(i) != 0 {
+			// The full hash no longer matches the slot hash
+			r.keys[i] ^= 1
+			break
+		}
+	}
+	assert.ErrorIs(t, r.Validate(), ErrCorrupt)
+}
@@ -0,0 +1,61 @@
+package statichash
+
+/*
+A table built WithHopscotch is laid out exactly as for linear probing, except that no key is more than
+hopscotchNeighbourhood-1 slots past its home slot. Finalize records the longest probe in the header, so lookups
+are bounded by the neighbourhood without needing to know about hopscotch hashing.
+
+To add a key, Set finds the first empty slot after its home slot as usual. If that's too far away, an entry
+between the two that can move into the empty slot while staying in its own neighbourhood is moved there, which
+frees a slot nearer to the key's home. That repeats until the free slot is close enough. The slots between a
+key's home slot and the key are all occupied throughout, so linear probe lookups still work.
+*/
+
+// hopscotchNeighbourhood is how far beyond its home slot a key may be in a table built WithHopscotch. Lookups
+// examine at most this many slots.
+const hopscotchNeighbourhood = 32
+
+// hopscotchSlot returns the slot a new key with hash h should go in, given empty, the first empty slot after
+// its home slot. In a table built WithHopscotch, entries are moved along to free a slot within the key's
+// neighbourhood, and -1 is returned if that isn't possible. The table is left unchanged if so. For other
+// tables, and if empty is -1, empty is returned unchanged.
+func (t *Write) hopscotchSlot(h uint64, empty int) int {
+	if empty < 0 || t.flags&flagHopscotch == 0 {
+		return empty
+	}
+	mask := t.numItems - 1
+	home := int(slotHash(h)) & mask
+
+	// Plan the moves before making any, so that we don't leave a gap if we fail part way
+	var moves []int
+	free := empty
+	for (free-home)&mask >= hopscotchNeighbourhood {
+		// Find the entry furthest before the free slot that can move into it. Every slot between the key's
+		// home slot and the free slot is occupied.
+		from := -1
+		for j := (free - hopscotchNeighbourhood + 1) & mask; j != free; j = (j + 1) & mask {
+			if (free-int(t.hashAt(j))&mask)&mask < hopscotchNeighbourhood {
+				from = j
+				break
+			}
+		}
+		if from < 0 {
+			return -1
+		}
+		moves = append(moves, from)
+		free = from
+	}
+
+	to := empty
+	for _, from := range moves {
+		t.moveEntry(from, to)
+		to = from
+	}
+	return free
+}
+
+// moveEntry moves the entry in slot from into the empty slot to, leaving from empty
+func (t *Write) moveEntry(from, to int) {
+	t.moveSlot(to, from)
+	t.setValue(to, t.value(from))
+}
@@ -0,0 +1,73 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHopscotch(t *testing.T) {
+	// Fill the table to 95%
+	const slots, n = 4096, 3891
+	tb := New(slots, 8, n*10, WithHopscotch(), WithSeed(1))
+	for i := 0; i < n; i++ {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&i)))
+	}
+	assert.Equal(t, slots, tb.NumSlots())
+	report := tb.Finalize()
+	assert.LessOrEqual(t, report.MaxProbeLength, hopscotchNeighbourhood)
+
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, r.Validate())
+	assert.Equal(t, StrategyHopscotch, r.Info().Strategy)
+	for i := 0; i < n; i++ {
+		v, ok := r.GetPtr(fmt.Sprintf("key%d", i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+	_, ok := r.GetPtr("missing")
+	assert.False(t, ok)
+}
+
+func TestHopscotchFull(t *testing.T) {
+	// Every key has the same home slot, so only the first 32 fit
+	tb := New(64, 8, 1000, WithHopscotch())
+	for i := 0; i < 64; i++ {
+		key := fmt.Sprintf("key%d", i)
+		index, _ := tb.find(key, 64)
+		if index = tb.hopscotchSlot(64, index); index < 0 {
+			break
+		}
+		tb.insert(index, 64, tb.addKey(key))
+	}
+	assert.Equal(t, hopscotchNeighbourhood, tb.Len())
+	before := append([]hash(nil), tb.hashes...)
+	index, _ := tb.find("another", 64)
+	assert.Equal(t, -1, tb.hopscotchSlot(64, index))
+	assert.Equal(t, before, tb.hashes)
+}
+
+func TestHopscotchGrow(t *testing.T) {
+	tb := New(8, 8, 10, WithHopscotch(), WithAutoGrow())
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&i)))
+	}
+	assert.Equal(t, 1000, tb.Len())
+	assert.LessOrEqual(t, tb.Finalize().MaxProbeLength, hopscotchNeighbourhood)
+	for i := 0; i < 1000; i++ {
+		v, ok := tb.GetPtr(fmt.Sprintf("key%d", i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+
+	assert.Panics(t, func() { New(10, 8, 100, WithHopscotch(), WithCuckoo()) })
+}
@@ -0,0 +1,70 @@
+// Package httplookup serves lookups against a statichash table over HTTP. It is intended for small debugging
+// and support servers.
+//
+//	http.Handle("/", httplookup.New(r, statichash.JSONEncoder[myType]()))
+//
+// GET /lookup?key=k returns {"key":"k","value":...} with the value encoded by the handler's ValueEncoder, or a
+// 404 if k is not in the table.
+package httplookup
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"unsafe"
+
+	"github.com/philpearl/statichash"
+)
+
+// Handler is an http.Handler that looks keys up in a table
+type Handler struct {
+	r   *statichash.Read
+	enc statichash.ValueEncoder
+	mux *http.ServeMux
+}
+
+// New returns a Handler that looks keys up in r. Values are encoded with enc, or statichash.HexEncoder if enc
+// is nil.
+func New(r *statichash.Read, enc statichash.ValueEncoder) *Handler {
+	if enc == nil {
+		enc = statichash.HexEncoder
+	}
+	h := &Handler{r: r, enc: enc, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /lookup", h.lookup)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mux.ServeHTTP(w, req)
+}
+
+func (h *Handler) lookup(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	if !q.Has("key") {
+		http.Error(w, "key parameter is required", http.StatusBadRequest)
+		return
+	}
+	key := q.Get("key")
+
+	p, ok := h.r.GetPtr(key)
+	if !ok {
+		http.Error(w, "key not found", http.StatusNotFound)
+		return
+	}
+	value, err := h.enc(unsafe.Slice((*byte)(p), h.r.ValueSize()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"key":`)
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteString(`,"value":`)
+	buf.Write(value)
+	buf.WriteString("}\n")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
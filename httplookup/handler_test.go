@@ -0,0 +1,49 @@
+package httplookup
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/philpearl/statichash"
+	"github.com/stretchr/testify/assert"
+)
+
+type value struct {
+	Count int32
+}
+
+func TestHandler(t *testing.T) {
+	tb, err := statichash.FromMapOf(map[string]value{"hat": {Count: 1}, "a&b": {Count: 2}})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := statichash.NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	get := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	h := New(r, statichash.JSONEncoder[value]())
+	w := get(h, "/lookup?key=a%26b")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"key":"a&b","value":{"Count":2}}`, w.Body.String())
+
+	w = get(New(r, nil), "/lookup?key=hat")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"key":"hat","value":"01000000"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get(h, "/lookup?key=gloves").Code)
+	assert.Equal(t, http.StatusBadRequest, get(h, "/lookup").Code)
+	assert.Equal(t, http.StatusNotFound, get(h, "/other").Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/lookup?key=hat", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,76 @@
+package statichash
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// Hybrid combines a base table with a small set of overrides held in memory, which can be changed while the
+// table is in use. Lookups check the overrides first, so individual keys can be corrected or removed without
+// rebuilding the base. A Hybrid is safe for concurrent use.
+type Hybrid struct {
+	base *Read
+
+	mu sync.RWMutex
+	// overrides holds the value for each overridden key, or nil if the key has been deleted. The values are never
+	// changed once stored, so pointers to them stay valid.
+	overrides map[string][]byte
+}
+
+// NewHybrid creates a Hybrid over base, with no overrides
+func NewHybrid(base *Read) *Hybrid {
+	return &Hybrid{base: base, overrides: make(map[string][]byte)}
+}
+
+// Set overrides the value for key. val must point to the base table's value size of bytes, which are copied.
+func (h *Hybrid) Set(key string, val unsafe.Pointer) {
+	value := append([]byte(nil), unsafe.Slice((*byte)(val), h.base.valueSize)...)
+	if value == nil {
+		// Values of zero bytes still need to be distinguished from deletes
+		value = []byte{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.overrides[key] = value
+}
+
+// Delete hides key, whether or not it is in the base table
+func (h *Hybrid) Delete(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.overrides[key] = nil
+}
+
+// Revert removes any override for key, so lookups see the base table's value again
+func (h *Hybrid) Revert(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.overrides, key)
+}
+
+// Overrides returns the number of keys that are overridden or deleted
+func (h *Hybrid) Overrides() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.overrides)
+}
+
+// GetPtr gets the value associated with key, from the overrides if it is overridden and otherwise from the base
+// table. See table.GetPtr. A pointer to an override stays valid even if the override is later changed.
+func (h *Hybrid) GetPtr(key string) (val unsafe.Pointer, ok bool) {
+	h.mu.RLock()
+	value, overridden := h.overrides[key]
+	h.mu.RUnlock()
+	if overridden {
+		if value == nil {
+			return nil, false
+		}
+		return unsafe.Pointer(unsafe.SliceData(value)), true
+	}
+	return h.base.GetPtr(key)
+}
+
+// Close closes the base table
+func (h *Hybrid) Close() error {
+	return h.base.Close()
+}
@@ -0,0 +1,68 @@
+package statichash
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHybrid(t *testing.T) {
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	base, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	h := NewHybrid(base)
+	get := func(key string) (int, bool) {
+		p, ok := h.GetPtr(key)
+		if !ok {
+			return 0, false
+		}
+		return *(*int)(p), true
+	}
+
+	v, ok := get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, 9, v)
+
+	override := 100
+	h.Set("key1", unsafe.Pointer(&override))
+	h.Set("new", unsafe.Pointer(&override))
+	h.Delete("key2")
+	assert.Equal(t, 3, h.Overrides())
+
+	p, ok := h.GetPtr("key1")
+	assert.True(t, ok)
+	assert.Equal(t, 100, *(*int)(p))
+	v, ok = get("new")
+	assert.True(t, ok)
+	assert.Equal(t, 100, v)
+	_, ok = get("key2")
+	assert.False(t, ok)
+	v, ok = get("key3")
+	assert.True(t, ok)
+	assert.Equal(t, 7, v)
+
+	// Changing an override doesn't change values already looked up
+	override = 200
+	h.Set("key1", unsafe.Pointer(&override))
+	assert.Equal(t, 100, *(*int)(p))
+	v, _ = get("key1")
+	assert.Equal(t, 200, v)
+
+	h.Revert("key1")
+	h.Revert("key2")
+	v, ok = get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, 9, v)
+	_, ok = get("key2")
+	assert.True(t, ok)
+	assert.Equal(t, 1, h.Overrides())
+
+	assert.NoError(t, h.Close())
+}
@@ -0,0 +1,63 @@
+package statichash
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ImportPolicy says what ImportFrom does with a key that is already in the table
+type ImportPolicy int
+
+const (
+	// ImportOverwrite replaces the value in the table with the imported one, as Set would
+	ImportOverwrite ImportPolicy = iota
+	// ImportKeep keeps the value already in the table
+	ImportKeep
+	// ImportFail stops the import with an error wrapping ErrKeyCollision
+	ImportFail
+)
+
+// ImportFrom adds every entry of r to the table, in the order Iterate visits them. It lets a new table start
+// from an existing one, for example yesterday's, without going back to the source data. policy says what
+// happens to keys that are already in the table.
+//
+// The table must have the same value size as r, and must be a string table if r is. r can't have been built
+// WithHashOnly, as it has no keys to import. The table needs room for the new keys unless it was built
+// WithAutoGrow. If ImportFrom fails part way, the entries before the failure stay
+// imported.
+func (t *Write) ImportFrom(r *Read, policy ImportPolicy) error {
+	if err := r.needKeys("import from"); err != nil {
+		return err
+	}
+	strings := r.flags&flagStringValues != 0
+	if strings != (t.flags&flagStringValues != 0) {
+		return errors.New("string tables can only be imported into string tables")
+	}
+	// String tables can have different value sizes, as strings may be stored in the values WithInlineStrings
+	if r.valueSize != t.valueSize && !strings {
+		return fmt.Errorf("%w: importing values of %d bytes into a table with values of %d bytes", ErrValueSizeMismatch, r.valueSize, t.valueSize)
+	}
+
+	for it := r.Iterate(); it.Next(); {
+		key := it.Key()
+		if policy != ImportOverwrite {
+			if _, found := t.find(key, t.hashKey(key)); found {
+				if policy == ImportFail {
+					return fmt.Errorf("%w: key %q is already in the table", ErrKeyCollision, key)
+				}
+				continue
+			}
+		}
+
+		var err error
+		if strings {
+			err = t.SetString(key, r.stringValue(it.index))
+		} else {
+			err = t.Set(key, it.Value())
+		}
+		if err != nil {
+			return fmt.Errorf("importing key %q: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportFrom(t *testing.T) {
+	old := buildTable(t, 100)
+	old.Finalize()
+	var buf bytes.Buffer
+	_, err := old.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	// build returns a table holding key1 with the value -1 and a new key
+	build := func() *Write {
+		tb := New(10, int64(unsafe.Sizeof(int(0))), 10, WithAutoGrow())
+		v := -1
+		assert.NoError(t, tb.Set("key1", unsafe.Pointer(&v)))
+		assert.NoError(t, tb.Set("new", unsafe.Pointer(&v)))
+		return tb
+	}
+	value := func(tb *Write, key string) int {
+		p, ok := tb.GetPtr(key)
+		assert.True(t, ok)
+		return *(*int)(p)
+	}
+
+	tb := build()
+	assert.NoError(t, tb.ImportFrom(r, ImportOverwrite))
+	assert.Equal(t, 101, tb.Len())
+	assert.Equal(t, 1, tb.Duplicates())
+	assert.Equal(t, 99, value(tb, "key1"))
+	assert.Equal(t, -1, value(tb, "new"))
+	for i := 1; i <= 100; i++ {
+		assert.Equal(t, 100-i, value(tb, fmt.Sprintf("key%d", i)))
+	}
+
+	tb = build()
+	assert.NoError(t, tb.ImportFrom(r, ImportKeep))
+	assert.Equal(t, 101, tb.Len())
+	assert.Equal(t, -1, value(tb, "key1"))
+	assert.Equal(t, 98, value(tb, "key2"))
+
+	tb = build()
+	assert.ErrorIs(t, tb.ImportFrom(r, ImportFail), ErrKeyCollision)
+
+	assert.ErrorIs(t, New(10, 4, 10).ImportFrom(r, ImportOverwrite), ErrValueSizeMismatch)
+	assert.ErrorIs(t, New(10, int64(unsafe.Sizeof(int(0))), 1000).ImportFrom(r, ImportOverwrite), ErrTableFull)
+}
+
+func TestImportStrings(t *testing.T) {
+	old := NewStringTable(10, 100)
+	assert.NoError(t, old.Set("a", "apple"))
+	assert.NoError(t, old.Set("b", "banana"))
+	old.Finalize()
+	var buf bytes.Buffer
+	_, err := old.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	s := NewStringTable(10, 100)
+	assert.NoError(t, s.Set("c", "cherry"))
+	assert.NoError(t, s.w.ImportFrom(r, ImportOverwrite))
+	v, ok := s.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, "banana", v)
+	assert.Equal(t, 3, s.Len())
+
+	assert.Error(t, New(10, 8, 100).ImportFrom(r, ImportOverwrite))
+}
@@ -0,0 +1,62 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Info describes when and by what a table was built
+type Info struct {
+	// Created is when the table was written. It is the zero time if no time was recorded.
+	Created time.Time
+	// Version is the string given to WithVersion when the table was built
+	Version string
+	// Strategy is how keys were placed in the table's slots
+	Strategy Strategy
+}
+
+// Strategy is how a table places keys in its slots. The builder picks it with an Option, and it is recorded in
+// the file's header.
+type Strategy int
+
+const (
+	// StrategyLinear places each key in the first free slot after its home slot. This is the default.
+	StrategyLinear Strategy = iota
+	// StrategyCuckoo places each key in one of two slots, as chosen WithCuckoo
+	StrategyCuckoo
+	// StrategyHopscotch places each key within a few slots of its home slot, as chosen WithHopscotch
+	StrategyHopscotch
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case StrategyLinear:
+		return "linear"
+	case StrategyCuckoo:
+		return "cuckoo"
+	case StrategyHopscotch:
+		return "hopscotch"
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// Info returns when and by what the table was built
+func (t *table) Info() Info {
+	var info Info
+	if t.created != 0 {
+		info.Created = time.Unix(0, t.created)
+	}
+	if i := bytes.IndexByte(t.version[:], 0); i >= 0 {
+		info.Version = string(t.version[:i])
+	} else {
+		info.Version = string(t.version[:])
+	}
+	switch {
+	case t.flags&flagCuckoo != 0:
+		info.Strategy = StrategyCuckoo
+	case t.flags&flagHopscotch != 0:
+		info.Strategy = StrategyHopscotch
+	}
+	return info
+}
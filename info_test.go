@@ -0,0 +1,37 @@
+package statichash
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo(t *testing.T) {
+	read := func(opts ...Option) *Read {
+		tb := buildTable(t, 10, opts...)
+		var buf bytes.Buffer
+		tb.Finalize()
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		tr, err := NewFromBytes(buf.Bytes())
+		assert.NoError(t, err)
+		return tr
+	}
+
+	before := time.Now()
+	info := read(WithVersion("v1.2.3")).Info()
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.False(t, info.Created.Before(before.Truncate(time.Second)))
+	assert.False(t, info.Created.After(time.Now()))
+
+	info = read(WithSeed(1)).Info()
+	assert.True(t, info.Created.IsZero())
+	assert.Equal(t, "", info.Version)
+
+	built := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	info = read(WithSeed(1), WithBuildTime(built), WithVersion("a version string that is much too long to fit")).Info()
+	assert.True(t, built.Equal(info.Created))
+	assert.Equal(t, "a version string that is much to", info.Version)
+}
@@ -0,0 +1,123 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInlineValues(t *testing.T) {
+	const n = 1000
+	for _, valueSize := range []int64{1, 8, 12, 16} {
+		for _, opts := range [][]Option{
+			nil,
+			{WithInsertionOrder(), WithSortedIndex()},
+			{WithCuckoo()},
+			{WithHopscotch(), WithAutoGrow()},
+			{WithPageAlignedSections()},
+		} {
+			tb := New(n, valueSize, n*10, append(opts, WithInlineValues())...)
+			for i := 0; i < n; i++ {
+				assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), bytesPointer(inlineValue(i, valueSize))))
+			}
+			tb.Finalize()
+			assert.Equal(t, tb.layout.values, tb.layout.keyData)
+
+			var buf bytes.Buffer
+			_, err := tb.WriteTo(&buf)
+			assert.NoError(t, err)
+			r, err := NewFromBytes(buf.Bytes())
+			if !assert.NoError(t, err) {
+				continue
+			}
+			assert.NoError(t, r.Validate())
+			assert.Nil(t, r.RawValues())
+
+			for i := 0; i < n; i++ {
+				v, ok := r.GetValue(fmt.Sprintf("key%d", i))
+				if assert.True(t, ok) {
+					assert.Equal(t, inlineValue(i, valueSize), v)
+				}
+			}
+			_, ok := r.GetValue("missing")
+			assert.False(t, ok)
+		}
+	}
+}
+
+// inlineValue returns a value of valueSize bytes for entry i
+func inlineValue(i int, valueSize int64) []byte {
+	var v [maxInlineValueSize]byte
+	binary.LittleEndian.PutUint64(v[:], uint64(i)*0x0101010101010101+1)
+	binary.LittleEndian.PutUint64(v[8:], ^uint64(i))
+	return v[:valueSize]
+}
+
+func TestInlineValuesLayout(t *testing.T) {
+	tb := New(100, 8, 1000, WithInlineValues())
+	assert.Equal(t, 24, tb.slotSize)
+	start, end := tb.layout.section(SectionHashes)
+	assert.Equal(t, 24*int64(tb.numItems), end-start)
+	start, end = tb.layout.section(SectionValues)
+	assert.Equal(t, start, end)
+
+	tb = New(100, 12, 1000, WithInlineValues())
+	assert.Equal(t, 32, tb.slotSize)
+}
+
+func TestInlineValuesWindowed(t *testing.T) {
+	tb := buildTable(t, 1000, WithInlineValues())
+	tb.Finalize()
+	r, err := NewFrom(writeTempTable(t, tb), WithWindowedMapping(4096, 1))
+	assert.NoError(t, err)
+	defer r.Close()
+	for i := 0; i < 1000; i++ {
+		v, ok := r.GetPtr(fmt.Sprintf("key%d", 1000-i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+}
+
+func TestInlineValuesSalvage(t *testing.T) {
+	tb := buildTable(t, 100, WithInlineValues())
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	w, lost, err := Salvage(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Zero(t, lost)
+	assert.Equal(t, tb.flags, w.flags)
+	for i := 0; i < 100; i++ {
+		v, ok := w.GetPtr(fmt.Sprintf("key%d", 100-i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+}
+
+func TestInlineValuesInvalid(t *testing.T) {
+	assert.Panics(t, func() { New(10, 17, 100, WithInlineValues()) })
+	assert.Panics(t, func() { New(10, 8, 100, WithInlineValues(), WithColumns(4, 4)) })
+
+	tb := buildTable(t, 10, WithInlineValues())
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+
+	// Inline values need interleaved slots
+	editHeader(t, data, func(h *header) { h.flags &^= flagInterleaved })
+	_, err = NewFromBytes(data)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	editHeader(t, data, func(h *header) { h.flags |= knownFlags + 1 })
+	_, err = NewFromBytes(data)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
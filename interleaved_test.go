@@ -0,0 +1,126 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterleavedSlots(t *testing.T) {
+	const n = 1000
+	for _, opts := range [][]Option{
+		nil,
+		{WithFingerprints()},
+		{WithInsertionOrder(), WithSortedIndex()},
+		{WithCuckoo()},
+		{WithHopscotch(), WithAutoGrow()},
+		{WithColumns(4, 4)},
+		{WithPageAlignedSections()},
+	} {
+		tb := buildTable(t, n, append(opts, WithInterleavedSlots())...)
+		tb.Finalize()
+		assert.NotNil(t, tb.slots)
+		assert.Nil(t, tb.hashes)
+		assert.Nil(t, tb.keys)
+
+		var buf bytes.Buffer
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		r, err := NewFromBytes(buf.Bytes())
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.NoError(t, r.Validate())
+		assert.Equal(t, n, r.Len())
+		assert.Nil(t, r.Hashes())
+		assert.Nil(t, r.KeyOffsets())
+
+		for i := 0; i < n; i++ {
+			v, ok := r.GetPtr(fmt.Sprintf("key%d", n-i))
+			if assert.True(t, ok) {
+				assert.Equal(t, i, *(*int)(v))
+			}
+		}
+		_, ok := r.GetPtr("missing")
+		assert.False(t, ok)
+
+		var seen int
+		r.ForEach(func(key string, val unsafe.Pointer) bool {
+			seen++
+			return true
+		})
+		assert.Equal(t, n, seen)
+	}
+}
+
+func TestInterleavedSlotsLayout(t *testing.T) {
+	tb := buildTable(t, 100, WithInterleavedSlots(), WithFingerprints())
+	l := tb.layout
+	assert.Equal(t, l.hashes+slotRecordSize*int64(tb.numItems), l.fingerprints)
+	assert.Equal(t, l.fingerprints, l.keys)
+	assert.Equal(t, l.keys, l.order)
+
+	start, end := l.section(SectionHashes)
+	assert.Equal(t, 16*int64(tb.numItems), end-start)
+	for _, s := range []Section{SectionFingerprints, SectionKeys} {
+		start, end := l.section(s)
+		assert.Equal(t, start, end, s.String())
+	}
+}
+
+func TestInterleavedSlotsUpdate(t *testing.T) {
+	tb := buildTable(t, 100, WithInterleavedSlots())
+	tb.Finalize()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	assert.NoError(t, tb.WriteFile(src))
+
+	value := make([]byte, 8)
+	binary.NativeEndian.PutUint64(value, 1234)
+	changes := []Change{{Key: "key1", Delete: true}, {Key: "key50", Delete: true}, {Key: "new", Value: value}}
+	dst := filepath.Join(dir, "dst")
+	_, err := UpdateFile(dst, src, changes)
+	assert.NoError(t, err)
+
+	r, err := NewFrom(dst)
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.NoError(t, r.Validate())
+	assert.Equal(t, 99, r.Len())
+	_, ok := r.GetPtr("key1")
+	assert.False(t, ok)
+	v, ok := r.GetValue("new")
+	if assert.True(t, ok) {
+		assert.Equal(t, uint64(1234), binary.NativeEndian.Uint64(v))
+	}
+	v, ok = r.GetValue("key2")
+	if assert.True(t, ok) {
+		assert.Equal(t, uint64(98), binary.NativeEndian.Uint64(v))
+	}
+}
+
+func TestInterleavedSlotsGrow(t *testing.T) {
+	tb := New(8, 8, 10, WithInterleavedSlots(), WithAutoGrow())
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, tb.SetValue(fmt.Sprintf("key%d", i), int64(i)))
+	}
+	assert.NotNil(t, tb.slots)
+	tb.Finalize()
+	for i := 0; i < 1000; i++ {
+		v, ok := tb.GetValue(fmt.Sprintf("key%d", i))
+		if assert.True(t, ok) {
+			assert.Equal(t, uint64(i), binary.NativeEndian.Uint64(v))
+		}
+	}
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, r.Validate())
+}
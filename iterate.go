@@ -0,0 +1,122 @@
+package statichash
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Iterator walks the entries of a table. Create one with Iterate.
+//
+//	it := t.Iterate()
+//	for it.Next() {
+//	   key, value := it.Key(), (*myType)(it.Value())
+//	}
+type Iterator struct {
+	t *table
+	// order lists the slots to visit. If it is nil we visit every occupied slot in turn
+	order []slotIndex
+	i     int
+	index int
+}
+
+// Iterate returns an Iterator over the entries in the table. If the table was created WithInsertionOrder the
+// entries are visited in the order they were first Set. Otherwise they are visited in slot order, which is
+// effectively random.
+//
+// Iterate is kept small enough to be inlined, so an Iterator that doesn't outlive the function using it stays
+// on the stack and iterating doesn't allocate. Key and Value don't allocate either unless the table is mapped in
+// windows or is columnar.
+func (t *table) Iterate() *Iterator {
+	it := &Iterator{t: t, i: -1}
+	if t.order != nil {
+		it.order = t.order[:t.count]
+	}
+	return it
+}
+
+// SortedIterate returns an Iterator that visits the entries in the table in lexicographic key order. If the
+// table was built WithSortedIndex the order is read from the file. Otherwise the keys are sorted the first time
+// this is called, which takes a while for a large table.
+func (t *table) SortedIterate() *Iterator {
+	return &Iterator{t: t, i: -1, order: t.sortedSlots()}
+}
+
+// Next moves the iterator on to the next entry. It returns false when there are no more entries.
+func (it *Iterator) Next() bool {
+	t := it.t
+	if it.order != nil {
+		it.i++
+		if it.i >= len(it.order) {
+			return false
+		}
+		it.index = int(it.order[it.i])
+		return true
+	}
+
+	for it.i++; it.i < t.numItems; it.i++ {
+		if t.hashAt(it.i) != 0 {
+			it.index = it.i
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the key of the current entry. It is empty if the table was built WithHashOnly.
+func (it *Iterator) Key() string {
+	return it.t.getKey(it.t.keyAt(it.index))
+}
+
+// Hash returns the table's Hash of the key of the current entry. It is read from the table if the table was
+// built WithHashOnly, so is the only way to tell its entries apart.
+func (it *Iterator) Hash() uint64 {
+	return it.t.entryHash(it.index)
+}
+
+// Value returns a pointer to the value of the current entry
+func (it *Iterator) Value() unsafe.Pointer {
+	return it.t.valuePtr(it.index)
+}
+
+// ForEach calls fn for each entry in the table, in the order Iterate visits them, until fn returns false. The
+// value pointer is only valid during the call if the table is mapped in windows or is columnar.
+func (t *table) ForEach(fn func(key string, val unsafe.Pointer) bool) {
+	for it := t.Iterate(); it.Next(); {
+		if !fn(it.Key(), it.Value()) {
+			return
+		}
+	}
+}
+
+// ForEachParallel divides the slots of the table into n ranges and scans them on n goroutines, calling fn for
+// each entry. worker identifies the goroutine, from 0 to n-1, so fn can accumulate results per worker without
+// locking. If any call returns false the other workers stop as soon as their current call returns.
+// ForEachParallel returns once every worker has finished. Entries are visited in slot order within each range,
+// even if the table was built WithInsertionOrder.
+func (t *table) ForEachParallel(n int, fn func(worker int, key string, val unsafe.Pointer) bool) {
+	n = min(max(n, 1), t.numItems)
+	if n == 0 {
+		return
+	}
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	size := (t.numItems + n - 1) / n
+	for worker := 0; worker < n; worker++ {
+		start, end := worker*size, min((worker+1)*size, t.numItems)
+		wg.Add(1)
+		go func(worker, start, end int) {
+			defer wg.Done()
+			for i := start; i < end && !stop.Load(); i++ {
+				if t.hashAt(i) == 0 {
+					continue
+				}
+				if !fn(worker, t.getKey(t.keyAt(i)), t.valuePtr(i)) {
+					stop.Store(true)
+				}
+			}
+		}(worker, start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,126 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTable(t *testing.T, n int, opts ...Option) *Write {
+	tb := New(n, int64(unsafe.Sizeof(int(0))), int64(n*10), opts...)
+	for i := 0; i < n; i++ {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", n-i), unsafe.Pointer(&i)))
+	}
+	return tb
+}
+
+func TestIterate(t *testing.T) {
+	tb := buildTable(t, 100)
+
+	seen := make(map[string]int)
+	it := tb.Iterate()
+	for it.Next() {
+		seen[it.Key()] = *(*int)(it.Value())
+	}
+
+	assert.Len(t, seen, 100)
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, i, seen[fmt.Sprintf("key%d", 100-i)])
+	}
+}
+
+func TestIterateInsertionOrder(t *testing.T) {
+	tb := buildTable(t, 100, WithInsertionOrder())
+	// Overwriting a key should not change its position
+	val := 42
+	tb.Set("key100", unsafe.Pointer(&val))
+	assert.Equal(t, 100, tb.Len())
+
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 100, tr.Len())
+
+	var i int
+	it := tr.Iterate()
+	for it.Next() {
+		assert.Equal(t, fmt.Sprintf("key%d", 100-i), it.Key())
+		if i == 0 {
+			assert.Equal(t, 42, *(*int)(it.Value()))
+		} else {
+			assert.Equal(t, i, *(*int)(it.Value()))
+		}
+		i++
+	}
+	assert.Equal(t, 100, i)
+}
+
+func TestForEach(t *testing.T) {
+	tb := buildTable(t, 100, WithInsertionOrder())
+
+	var keys []string
+	tb.ForEach(func(key string, val unsafe.Pointer) bool {
+		assert.Equal(t, fmt.Sprintf("key%d", 100-*(*int)(val)), key)
+		keys = append(keys, key)
+		return true
+	})
+	assert.Len(t, keys, 100)
+
+	// Stop at the first key ending in 7
+	var found string
+	var calls int
+	tb.ForEach(func(key string, val unsafe.Pointer) bool {
+		calls++
+		if strings.HasSuffix(key, "7") {
+			found = key
+			return false
+		}
+		return true
+	})
+	assert.Equal(t, "key97", found)
+	assert.Equal(t, 4, calls)
+}
+
+func TestForEachParallel(t *testing.T) {
+	tb := buildTable(t, 1000)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	for _, n := range []int{0, 1, 7, 5000} {
+		sums := make([]int, max(min(n, tr.NumSlots()), 1))
+		counts := make([]int, len(sums))
+		tr.ForEachParallel(n, func(worker int, key string, val unsafe.Pointer) bool {
+			assert.Equal(t, fmt.Sprintf("key%d", 1000-*(*int)(val)), key)
+			sums[worker] += *(*int)(val)
+			counts[worker]++
+			return true
+		})
+		var sum, count int
+		for i := range sums {
+			sum += sums[i]
+			count += counts[i]
+		}
+		assert.Equal(t, 1000, count, n)
+		assert.Equal(t, 999*1000/2, sum, n)
+	}
+
+	var calls atomic.Int64
+	tr.ForEachParallel(4, func(worker int, key string, val unsafe.Pointer) bool {
+		calls.Add(1)
+		return false
+	})
+	assert.LessOrEqual(t, calls.Load(), int64(4))
+}
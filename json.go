@@ -0,0 +1,69 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"unsafe"
+)
+
+// ValueEncoder converts the raw bytes of a value to JSON
+type ValueEncoder func(value []byte) ([]byte, error)
+
+// HexEncoder is a ValueEncoder that encodes a value as a JSON string of hex digits. It is the default.
+func HexEncoder(value []byte) ([]byte, error) {
+	out := make([]byte, hex.EncodedLen(len(value))+2)
+	out[0] = '"'
+	hex.Encode(out[1:], value)
+	out[len(out)-1] = '"'
+	return out, nil
+}
+
+// JSONEncoder returns a ValueEncoder that encodes values as the pointer-free type T using encoding/json
+func JSONEncoder[T any]() ValueEncoder {
+	return func(value []byte) ([]byte, error) {
+		var v T
+		if len(value) != int(unsafe.Sizeof(v)) {
+			return nil, fmt.Errorf("%w: value is %d bytes, %T is %d bytes", ErrValueSizeMismatch, len(value), v, unsafe.Sizeof(v))
+		}
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(&v)), len(value)), value)
+		return json.Marshal(v)
+	}
+}
+
+// MarshalJSON encodes the whole table as a JSON object, with the entries in key order. Values are encoded
+// using the ValueEncoder given with WithValueEncoder, or HexEncoder if none was given. This is intended for
+// small tables in tests and debugging endpoints.
+func (r *Read) MarshalJSON() ([]byte, error) {
+	if err := r.needKeys("encode as JSON"); err != nil {
+		return nil, err
+	}
+	enc := r.encoder
+	if enc == nil {
+		enc = HexEncoder
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	it := r.SortedIterate()
+	for i := 0; it.Next(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(it.Key())
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		val, err := enc(r.value(it.index))
+		if err != nil {
+			return nil, fmt.Errorf("encoding value for %q: %w", it.Key(), err)
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
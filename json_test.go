@@ -0,0 +1,53 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	tb := New(3, 4, 20)
+	for i, key := range []string{"b", "a", "c\""} {
+		v := uint32(i + 1)
+		tb.Set(key, unsafe.Pointer(&v))
+	}
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	data, err := json.Marshal(tr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a":"02000000","b":"01000000","c\"":"03000000"}`, string(data))
+
+	tr, err = NewFromBytes(buf.Bytes(), WithValueEncoder(func(value []byte) ([]byte, error) {
+		return strconv.AppendUint(nil, uint64(binary.LittleEndian.Uint32(value)), 10), nil
+	}))
+	assert.NoError(t, err)
+	data, err = json.Marshal(tr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":2,"b":1,"c\"":3}`, string(data))
+}
+
+func TestJSONEncoder(t *testing.T) {
+	type value struct {
+		A int32
+		B float32
+	}
+	v := value{A: 3, B: 1.5}
+	enc := JSONEncoder[value]()
+	data, err := enc(unsafe.Slice((*byte)(unsafe.Pointer(&v)), unsafe.Sizeof(v)))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"A":3,"B":1.5}`, string(data))
+
+	_, err = enc([]byte{1, 2, 3})
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+}
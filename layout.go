@@ -0,0 +1,207 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"math"
+	"unsafe"
+)
+
+/*
+Every number in a table file is little-endian, whatever platform wrote it. The header, the section directory
+and the header and directory of a partitioned file are encoded field by field at the offsets below, so their
+layout doesn't depend on how the compiler pads the Go types that hold them.
+
+The header of the current format is headerSize bytes:
+
+	offset  size  field
+	0       8     magic, "STATHASH"
+	8       4     format version
+	12      4     header size
+	16      8     number of slots, a power of 2
+	24      8     value size
+	32      8     number of entries
+	40      8     flags
+	48      8     seed
+	56      8     build time, in nanoseconds since the Unix epoch
+	64      32    version string, zero padded
+	96      32    16 column widths of 2 bytes each
+	128     8     offset of the section directory
+	136     4     number of directory entries
+	140     4     longest probe sequence
+	144     8     cuckoo seed
+
+Older formats with a magic number have a prefix of this header. formatV0 files have none, and the headerV0
+layout given below. Each
+directory entry is directoryEntrySize bytes: the section type in 4 bytes, 4 bytes of padding, then the offset
+and length of the section in 8 bytes each.
+
+The slot arrays, values and sorted and order sections are mapped straight from the file rather than decoded,
+so the Go types that hold them are checked below to have exactly the layout the format specifies. Each hash is
+4 bytes, each fingerprint 1 byte, and each key offset and slot index 8 bytes. A slotRecord is 16 bytes: the hash
+at 0, the fingerprint at 4, 3 bytes of padding and the key offset at 8. As these are read in place, tables can
+only be used on little-endian platforms.
+*/
+
+// fileByteOrder is the byte order of every number in a table file
+var fileByteOrder = binary.LittleEndian
+
+// hostLittleEndian is true if this platform stores numbers in fileByteOrder. The slots are read in place, so
+// tables can't be used on other platforms.
+var hostLittleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+// Offsets of the fields of the header in the file
+const (
+	headerMagic      = 0
+	headerFormat     = 8
+	headerHeaderSize = 12
+	headerNumItems   = 16
+	headerValueSize  = 24
+	headerCount      = 32
+	headerFlags      = 40
+	headerSeed       = 48
+	headerCreated    = 56
+	headerVersion    = 64
+	headerColumns    = 96
+	headerDirectory  = 128
+	headerSections   = 136
+	headerMaxProbe   = 140
+	headerCuckooSeed = 144
+
+	// headerSize is the size of the header of the current format
+	headerSize = 152
+)
+
+// Offsets of the fields of a formatV0 header in the file. It has no magic number, and only the number of slots
+// and the value size.
+const (
+	headerV0NumItems  = 0
+	headerV0ValueSize = 8
+
+	// headerV0Size is where the hashes of a formatV0 file start. The slots were laid out as if after a second
+	// copy of the 16 byte header, so the header is followed by 16 zero bytes.
+	headerV0Size = 32
+)
+
+// directoryEntrySize is the size of a directory entry in the file
+const directoryEntrySize = 24
+
+// These fail to compile if the types mapped from the file don't have the layout the format specifies
+var (
+	_ [unsafe.Sizeof(hash(0)) - 4]struct{}
+	_ [4 - unsafe.Sizeof(hash(0))]struct{}
+	_ [unsafe.Sizeof(keyOffset(0)) - 8]struct{}
+	_ [8 - unsafe.Sizeof(keyOffset(0))]struct{}
+	_ [unsafe.Sizeof(slotIndex(0)) - 8]struct{}
+	_ [8 - unsafe.Sizeof(slotIndex(0))]struct{}
+	_ [slotRecordSize - 16]struct{}
+	_ [16 - slotRecordSize]struct{}
+	_ [0 - unsafe.Offsetof(slotRecord{}.hash)]struct{}
+	_ [unsafe.Offsetof(slotRecord{}.fingerprint) - 4]struct{}
+	_ [4 - unsafe.Offsetof(slotRecord{}.fingerprint)]struct{}
+	_ [unsafe.Offsetof(slotRecord{}.key) - 8]struct{}
+	_ [8 - unsafe.Offsetof(slotRecord{}.key)]struct{}
+)
+
+// encode writes h to the start of b, which must be at least headerSize bytes
+func (h *header) encode(b []byte) {
+	b = b[:headerSize]
+	copy(b[headerMagic:], h.magic[:])
+	fileByteOrder.PutUint32(b[headerFormat:], h.format)
+	fileByteOrder.PutUint32(b[headerHeaderSize:], h.headerSize)
+	fileByteOrder.PutUint64(b[headerNumItems:], uint64(h.numItems))
+	fileByteOrder.PutUint64(b[headerValueSize:], uint64(h.valueSize))
+	fileByteOrder.PutUint64(b[headerCount:], uint64(h.count))
+	fileByteOrder.PutUint64(b[headerFlags:], uint64(h.flags))
+	fileByteOrder.PutUint64(b[headerSeed:], h.seed)
+	fileByteOrder.PutUint64(b[headerCreated:], uint64(h.created))
+	copy(b[headerVersion:], h.version[:])
+	for i, w := range h.columns {
+		fileByteOrder.PutUint16(b[headerColumns+2*i:], w)
+	}
+	fileByteOrder.PutUint64(b[headerDirectory:], uint64(h.directory))
+	fileByteOrder.PutUint32(b[headerSections:], h.sections)
+	fileByteOrder.PutUint32(b[headerMaxProbe:], h.maxProbe)
+	fileByteOrder.PutUint64(b[headerCuckooSeed:], h.cuckooSeed)
+}
+
+// decodeHeader reads a header of size bytes from the start of b. Fields beyond size, which the format the file
+// was written in doesn't have, are left zero.
+func decodeHeader(b []byte, size int) (h header) {
+	var full [headerSize]byte
+	copy(full[:], b[:min(size, headerSize)])
+	b = full[:]
+
+	h.magic = [8]byte(b[headerMagic:])
+	h.format = fileByteOrder.Uint32(b[headerFormat:])
+	h.headerSize = fileByteOrder.Uint32(b[headerHeaderSize:])
+	h.numItems = int64(fileByteOrder.Uint64(b[headerNumItems:]))
+	h.valueSize = int64(fileByteOrder.Uint64(b[headerValueSize:]))
+	h.count = int64(fileByteOrder.Uint64(b[headerCount:]))
+	h.flags = int64(fileByteOrder.Uint64(b[headerFlags:]))
+	h.seed = fileByteOrder.Uint64(b[headerSeed:])
+	h.created = int64(fileByteOrder.Uint64(b[headerCreated:]))
+	h.version = [32]byte(b[headerVersion:])
+	for i := range h.columns {
+		h.columns[i] = fileByteOrder.Uint16(b[headerColumns+2*i:])
+	}
+	h.directory = int64(fileByteOrder.Uint64(b[headerDirectory:]))
+	h.sections = fileByteOrder.Uint32(b[headerSections:])
+	h.maxProbe = fileByteOrder.Uint32(b[headerMaxProbe:])
+	h.cuckooSeed = fileByteOrder.Uint64(b[headerCuckooSeed:])
+	return h
+}
+
+// decodeHeaderV0 reads a formatV0 header from the start of b, which must be at least headerV0Size bytes, and
+// converts it to the current header. The header doesn't record the number of entries, so countV0 must set it
+// once the slots are mapped.
+func decodeHeaderV0(b []byte) (h header) {
+	h.magic = fileMagic
+	h.format = formatV0
+	h.headerSize = headerV0Size
+	h.numItems = int64(fileByteOrder.Uint64(b[headerV0NumItems:]))
+	h.valueSize = int64(fileByteOrder.Uint64(b[headerV0ValueSize:]))
+	return h
+}
+
+// isHeaderV0 returns true if b, which must be at least headerV0Size bytes, looks like the start of a formatV0
+// file. As there is no magic number, we check the slot count is a power of 2, the value size is plausible and
+// the unused bytes are zero.
+func isHeaderV0(b []byte) bool {
+	numItems := fileByteOrder.Uint64(b[headerV0NumItems:])
+	valueSize := fileByteOrder.Uint64(b[headerV0ValueSize:])
+	if numItems == 0 || numItems&(numItems-1) != 0 || numItems > math.MaxInt64 || valueSize > math.MaxInt32 {
+		return false
+	}
+	for _, c := range b[16:headerV0Size] {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeDirectory returns the encoding of the directory entries in dir
+func encodeDirectory(dir []directoryEntry) []byte {
+	b := make([]byte, len(dir)*directoryEntrySize)
+	for i, e := range dir {
+		p := b[i*directoryEntrySize:]
+		fileByteOrder.PutUint32(p, e.kind)
+		fileByteOrder.PutUint64(p[8:], uint64(e.offset))
+		fileByteOrder.PutUint64(p[16:], uint64(e.length))
+	}
+	return b
+}
+
+// decodeDirectory decodes the directory entries in b
+func decodeDirectory(b []byte) []directoryEntry {
+	dir := make([]directoryEntry, len(b)/directoryEntrySize)
+	for i := range dir {
+		p := b[i*directoryEntrySize:]
+		dir[i] = directoryEntry{
+			kind:   fileByteOrder.Uint32(p),
+			offset: int64(fileByteOrder.Uint64(p[8:])),
+			length: int64(fileByteOrder.Uint64(p[16:])),
+		}
+	}
+	return dir
+}
@@ -0,0 +1,54 @@
+package statichash
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// editHeader changes the header of the table file in data with fn
+func editHeader(t *testing.T, data []byte, fn func(h *header)) {
+	t.Helper()
+	h, err := readHeader(data)
+	assert.NoError(t, err)
+	fn(&h)
+	h.encode(data)
+}
+
+func TestLayout(t *testing.T) {
+	tb := buildTable(t, 100, WithSeed(0x0102030405060708), WithVersion("v1"), WithFingerprints())
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+
+	// The header fields are little-endian at fixed offsets
+	assert.Equal(t, fileMagic[:], data[:8])
+	assert.Equal(t, []byte{currentFormat, 0, 0, 0, headerSize, 0, 0, 0}, data[8:16])
+	assert.Equal(t, []byte{128, 0, 0, 0, 0, 0, 0, 0}, data[headerNumItems:headerNumItems+8])
+	assert.Equal(t, []byte{8, 0, 0, 0, 0, 0, 0, 0}, data[headerValueSize:headerValueSize+8])
+	assert.Equal(t, []byte{100, 0, 0, 0, 0, 0, 0, 0}, data[headerCount:headerCount+8])
+	assert.Equal(t, []byte{8, 7, 6, 5, 4, 3, 2, 1}, data[headerSeed:headerSeed+8])
+	assert.Equal(t, "v1", string(bytes.TrimRight(data[headerVersion:headerVersion+32], "\x00")))
+
+	h, err := readHeader(data)
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(data)-int(h.sections)*directoryEntrySize, h.directory)
+	dir := decodeDirectory(data[h.directory:])
+	assert.Len(t, dir, int(h.sections))
+	assert.Equal(t, dir, decodeDirectory(encodeDirectory(dir)))
+	assert.Equal(t, SectionHashes, Section(dir[0].kind))
+	assert.EqualValues(t, headerSize, dir[0].offset)
+	assert.EqualValues(t, 128*4, dir[0].length)
+
+	// Fields beyond the header size of an older format are zero
+	b := make([]byte, headerSize)
+	h.encode(b)
+	assert.Equal(t, h, decodeHeader(b, headerSize))
+	old := decodeHeader(b, headerDirectory)
+	assert.Zero(t, old.directory)
+	assert.Zero(t, old.cuckooSeed)
+	assert.Equal(t, h.version, old.version)
+}
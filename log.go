@@ -0,0 +1,335 @@
+package statichash
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"unsafe"
+)
+
+/*
+An append log collects changes to be folded into a table later by CompactLog. It is
+
+Magic - "SHLOG001"
+Header - uvarint value size
+Records - an op byte followed by a uvarint key length and the key. Set records are followed by the value. Each
+          record ends with the CRC-32C of the bytes before it, so a record torn by a crash can be detected.
+
+A torn record can only be at the end of the log. OpenLog truncates it so that new records follow the last
+complete one, and CompactLog ignores it.
+*/
+
+var logMagic = [8]byte{'S', 'H', 'L', 'O', 'G', '0', '0', '1'}
+
+const (
+	logSet    = 's'
+	logDelete = 'd'
+	// maxLogRecord is the longest record we'll read. It stops a corrupt length making us allocate a huge buffer.
+	maxLogRecord = 1 << 30
+)
+
+var logCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Log is an append-only log of sets and deletes, to be compacted into a table with CompactLog. It lets changes
+// be collected as they happen, say during the day, and compacted into a table in one go, say overnight.
+// A Log is not safe for concurrent use.
+type Log struct {
+	f         *os.File
+	w         *bufio.Writer
+	valueSize int
+	// buf holds the record being written
+	buf []byte
+}
+
+// OpenLog opens the log file filename for appending, creating it if it doesn't exist. Every value in the log
+// is valueSize bytes. If the log ends with a record torn by a crash, the record is removed.
+func OpenLog(filename string, valueSize int) (*Log, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	l := Log{f: f, valueSize: valueSize}
+	end, err := l.recover()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening log %s: %w", filename, err)
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	l.w = bufio.NewWriter(f)
+	return &l, nil
+}
+
+// recover writes the log header if the file is empty, or checks it and removes any torn record from the end if
+// not. It returns the length of the log.
+func (l *Log) recover() (int64, error) {
+	fi, err := l.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if fi.Size() == 0 {
+		hdr := binary.AppendUvarint(logMagic[:len(logMagic):len(logMagic)], uint64(l.valueSize))
+		if _, err := l.f.Write(hdr); err != nil {
+			return 0, err
+		}
+		return int64(len(hdr)), nil
+	}
+
+	var end int64
+	if err := readLog(l.f, func(valueSize int) error {
+		if valueSize != l.valueSize {
+			return fmt.Errorf("%w: log has values of %d bytes, expected %d", ErrValueSizeMismatch, valueSize, l.valueSize)
+		}
+		return nil
+	}, func(op byte, key string, value []byte) {}, &end); err != nil {
+		return 0, err
+	}
+	if end < fi.Size() {
+		if err := l.f.Truncate(end); err != nil {
+			return 0, err
+		}
+	}
+	return end, nil
+}
+
+// Set appends a record setting the value of key. val must point to the log's value size of bytes.
+func (l *Log) Set(key string, val unsafe.Pointer) error {
+	return l.append(logSet, key, unsafe.Slice((*byte)(val), l.valueSize))
+}
+
+// Delete appends a record deleting key
+func (l *Log) Delete(key string) error {
+	return l.append(logDelete, key, nil)
+}
+
+func (l *Log) append(op byte, key string, value []byte) error {
+	l.buf = append(l.buf[:0], op)
+	l.buf = binary.AppendUvarint(l.buf, uint64(len(key)))
+	l.buf = append(l.buf, key...)
+	l.buf = append(l.buf, value...)
+	l.buf = binary.LittleEndian.AppendUint32(l.buf, crc32.Checksum(l.buf, logCRCTable))
+	_, err := l.w.Write(l.buf)
+	return err
+}
+
+// Sync writes any buffered records to the file and flushes the file to stable storage. Records are only
+// certain to survive a crash once Sync or Close has returned.
+func (l *Log) Sync() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+// Close writes any buffered records and closes the log
+func (l *Log) Close() error {
+	if err := l.Sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// CompactLog folds the log file filename into a table. If base is not nil, the table starts with the entries of
+// base and has the same options, and the log's records are applied on top. Later records for a key override
+// earlier ones. opts are applied after any options from base. The table is finalized, ready to be saved with
+// WriteTo. String tables and tables built WithHashOnly can't be compacted into.
+func CompactLog(base *Read, filename string, opts ...Option) (*Write, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// final holds the last value set for each key in the log, or nil if its last record was a delete
+	final := make(map[string][]byte)
+	var order []string
+	var valueSize int
+	if err := readLog(f, func(size int) error {
+		valueSize = size
+		if base == nil {
+			return nil
+		}
+		if base.flags&flagStringValues != 0 {
+			return errors.New("logs can't be compacted into string tables")
+		}
+		if err := base.needKeys("compact a log into"); err != nil {
+			return err
+		}
+		if size != base.valueSize {
+			return fmt.Errorf("%w: log has values of %d bytes, table has %d", ErrValueSizeMismatch, size, base.valueSize)
+		}
+		return nil
+	}, func(op byte, key string, value []byte) {
+		if _, ok := final[key]; !ok {
+			order = append(order, key)
+		}
+		if op == logSet {
+			final[key] = value
+		} else {
+			final[key] = nil
+		}
+	}, nil); err != nil {
+		return nil, fmt.Errorf("reading log %s: %w", filename, err)
+	}
+
+	var numItems int
+	var keyLength int64
+	var baseOpts []Option
+	if base != nil {
+		numItems = base.count
+		keyLength = int64(base.usedKeyData())
+		baseOpts = base.options()
+	}
+	for key, value := range final {
+		if value != nil {
+			numItems++
+			keyLength += int64(len(key))
+		}
+	}
+
+	t := New(max(numItems, 1), int64(valueSize), keyLength, append(baseOpts, opts...)...)
+	if base != nil {
+		t.created = base.created
+		t.version = base.version
+		for it := base.Iterate(); it.Next(); {
+			if _, ok := final[it.Key()]; ok {
+				continue
+			}
+			if err := t.Set(it.Key(), it.Value()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, key := range order {
+		if value := final[key]; value != nil {
+			if err := t.Set(key, bytesPointer(value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	t.Finalize()
+	return t, nil
+}
+
+// readLog reads the log from r, calling header with the value size from the header and then record with each
+// complete record. It stops without error at a torn record at the end of the log. If end is not nil it is set
+// to the offset after the last complete record.
+func readLog(r io.Reader, header func(valueSize int) error, record func(op byte, key string, value []byte), end *int64) error {
+	br := bufio.NewReader(r)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil {
+		return fmt.Errorf("%w: reading log magic: %v", ErrTruncated, err)
+	}
+	if magic != logMagic {
+		return fmt.Errorf("%w: not a statichash log", ErrBadMagic)
+	}
+	cr := countingReader{r: br, n: int64(len(magic))}
+	valueSize, err := binary.ReadUvarint(&cr)
+	if err != nil {
+		return fmt.Errorf("%w: reading log header: %v", ErrTruncated, err)
+	}
+	if valueSize > maxLogRecord {
+		return fmt.Errorf("%w: log value size %d", ErrCorrupt, valueSize)
+	}
+	if err := header(int(valueSize)); err != nil {
+		return err
+	}
+	if end != nil {
+		*end = cr.n
+	}
+
+	var buf []byte
+	for {
+		op, err := cr.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		buf = append(buf[:0], op)
+		if op != logSet && op != logDelete {
+			return fmt.Errorf("%w: unexpected log record type %q at offset %d", ErrCorrupt, op, cr.n-1)
+		}
+		keyLen, err := binary.ReadUvarint(&cr)
+		if err != nil {
+			return tornRecord(err)
+		}
+		length := keyLen
+		if op == logSet {
+			length += valueSize
+		}
+		buf = binary.AppendUvarint(buf, keyLen)
+		start := len(buf)
+		if length > maxLogRecord {
+			return fmt.Errorf("%w: log record of %d bytes at offset %d", ErrCorrupt, length, cr.n)
+		}
+		// A corrupt length at the end of the log could be up to maxLogRecord, so we let the data we actually read
+		// set how much we allocate
+		rec, err := io.ReadAll(io.LimitReader(&cr, int64(length)+4))
+		if err != nil {
+			return err
+		}
+		if uint64(len(rec)) != length+4 {
+			// The record is torn
+			return nil
+		}
+		buf = append(buf, rec...)
+		crcStart := len(buf) - 4
+		if binary.LittleEndian.Uint32(buf[crcStart:]) != crc32.Checksum(buf[:crcStart], logCRCTable) {
+			// A crash can only tear the last record, so a bad checksum on a record followed by more means the log
+			// is corrupt
+			if _, err := cr.ReadByte(); err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("%w: bad checksum on log record ending at offset %d", ErrCorrupt, cr.n-1)
+		}
+
+		key := string(buf[start : start+int(keyLen)])
+		var value []byte
+		if op == logSet {
+			value = append([]byte(nil), buf[start+int(keyLen):crcStart]...)
+		}
+		record(op, key, value)
+		if end != nil {
+			*end = cr.n
+		}
+	}
+}
+
+// tornRecord returns nil if err means a record was cut short by the end of the log, and err otherwise
+func tornRecord(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
@@ -0,0 +1,171 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	l, err := OpenLog(name, 8)
+	assert.NoError(t, err)
+	for i := int64(0); i < 100; i++ {
+		assert.NoError(t, l.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&i)))
+	}
+	assert.NoError(t, l.Close())
+
+	// Reopening appends to the log
+	l, err = OpenLog(name, 8)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, l.Delete(fmt.Sprintf("key%d", i)))
+	}
+	v := int64(-1)
+	assert.NoError(t, l.Set("key50", unsafe.Pointer(&v)))
+	assert.NoError(t, l.Set("key5", unsafe.Pointer(&v)))
+	assert.NoError(t, l.Close())
+
+	tb, err := CompactLog(nil, name, WithSeed(1))
+	assert.NoError(t, err)
+	assert.Equal(t, 91, tb.Len())
+	for i := 0; i < 100; i++ {
+		p, ok := tb.GetPtr(fmt.Sprintf("key%d", i))
+		switch {
+		case i == 5 || i == 50:
+			if assert.True(t, ok) {
+				assert.Equal(t, int64(-1), *(*int64)(p))
+			}
+		case i < 10:
+			assert.False(t, ok)
+		default:
+			if assert.True(t, ok) {
+				assert.Equal(t, int64(i), *(*int64)(p))
+			}
+		}
+	}
+
+	// Compacting into yesterday's table keeps its entries and options
+	var buf bytes.Buffer
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	base, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	name2 := filepath.Join(t.TempDir(), "log2")
+	l, err = OpenLog(name2, 8)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Set("new", unsafe.Pointer(&v)))
+	assert.NoError(t, l.Delete("key20"))
+	assert.NoError(t, l.Close())
+
+	tb, err = CompactLog(base, name2)
+	assert.NoError(t, err)
+	assert.Equal(t, 91, tb.Len())
+	assert.Equal(t, base.seed, tb.seed)
+	_, ok := tb.GetPtr("new")
+	assert.True(t, ok)
+	_, ok = tb.GetPtr("key20")
+	assert.False(t, ok)
+	p, ok := tb.GetPtr("key30")
+	if assert.True(t, ok) {
+		assert.Equal(t, int64(30), *(*int64)(p))
+	}
+}
+
+func TestLogTorn(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	l, err := OpenLog(name, 8)
+	assert.NoError(t, err)
+	for i := int64(0); i < 10; i++ {
+		assert.NoError(t, l.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&i)))
+	}
+	assert.NoError(t, l.Close())
+
+	// Cut the last record short, as a crash while appending might
+	fi, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(name, fi.Size()-3))
+
+	tb, err := CompactLog(nil, name)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, tb.Len())
+
+	// Reopening removes the torn record, so new records can be read
+	l, err = OpenLog(name, 8)
+	assert.NoError(t, err)
+	i := int64(9)
+	assert.NoError(t, l.Set("key9", unsafe.Pointer(&i)))
+	assert.NoError(t, l.Close())
+
+	tb, err = CompactLog(nil, name)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, tb.Len())
+
+	// Damage in the middle of the log is corruption
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	data[20]++
+	assert.NoError(t, os.WriteFile(name, data, 0o644))
+	_, err = CompactLog(nil, name)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = OpenLog(name, 8)
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestLogErrors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	l, err := OpenLog(name, 8)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	_, err = OpenLog(name, 4)
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+
+	notLog := filepath.Join(t.TempDir(), "notlog")
+	assert.NoError(t, os.WriteFile(notLog, []byte("this is not a log"), 0o644))
+	_, err = CompactLog(nil, notLog)
+	assert.ErrorIs(t, err, ErrBadMagic)
+
+	small := New(4, 4, 10)
+	small.Finalize()
+	var buf bytes.Buffer
+	_, err = small.WriteTo(&buf)
+	assert.NoError(t, err)
+	base, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	_, err = CompactLog(base, name)
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+}
+
+func FuzzReadLog(f *testing.F) {
+	name := filepath.Join(f.TempDir(), "log")
+	l, err := OpenLog(name, 8)
+	if err != nil {
+		f.Fatal(err)
+	}
+	v := int64(1)
+	l.Set("a", unsafe.Pointer(&v))
+	l.Delete("b")
+	if err := l.Close(); err != nil {
+		f.Fatal(err)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var end int64
+		readLog(bytes.NewReader(data), func(valueSize int) error { return nil }, func(op byte, key string, value []byte) {}, &end)
+		if end > int64(len(data)) {
+			t.Fatalf("end %d is beyond the %d bytes of the log", end, len(data))
+		}
+	})
+}
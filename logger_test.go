@@ -0,0 +1,39 @@
+package statichash
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "table")
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(name))
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	r, err := NewFrom(name, WithLogger(logger))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+
+	_, err = NewFrom(name+".missing", WithLogger(logger))
+	assert.Error(t, err)
+
+	assert.Equal(t, `level=INFO msg="opened table" file=`+name+` entries=10 slots=16 windowed=false
+level=INFO msg="closed table" file=`+name+`
+level=ERROR msg="opening table failed" file=`+name+`.missing error="open `+name+`.missing: no such file or directory"
+`, buf.String())
+}
@@ -0,0 +1,75 @@
+package statichash
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MapValues builds a new table with the keys of r and values of valueSize bytes computed by fn. fn is called for
+// each entry, in the order Iterate visits them, with the key, the old value and a zeroed buffer to fill with the
+// new value. It returns false to leave the entry out of the new table, or an error to stop. Neither slice may be
+// kept after fn returns.
+//
+// The new table has the same options as r plus any in opts, except that a columnar layout is only kept if the
+// value size is unchanged. Tables with string values, or built WithHashOnly, can't be mapped.
+func MapValues(r *Read, valueSize int, fn func(key string, old, new []byte) (bool, error), opts ...Option) (*Write, error) {
+	if r.flags&flagStringValues != 0 {
+		return nil, fmt.Errorf("%w: can't map the values of a string table", ErrValueSizeMismatch)
+	}
+	if err := r.needKeys("map the values of"); err != nil {
+		return nil, err
+	}
+
+	var keyLength int64
+	for it := r.Iterate(); it.Next(); {
+		keyLength += int64(len(it.Key()))
+	}
+
+	base := r.options()
+	if r.flags&flagColumnar != 0 && valueSize != r.valueSize {
+		base = append(base, withoutColumns())
+	}
+	w := New(r.count, int64(valueSize), keyLength, append(base, opts...)...)
+
+	buf := make([]byte, max(valueSize, 1))[:valueSize]
+	for it := r.Iterate(); it.Next(); {
+		clear(buf)
+		key := it.Key()
+		keep, err := fn(key, r.value(it.index), buf)
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			continue
+		}
+		if err := w.Set(key, bytesPointer(buf)); err != nil {
+			return nil, err
+		}
+	}
+	w.Finalize()
+	return w, nil
+}
+
+// MapValuesOf is a typed version of MapValues. It checks that From is the size of the values in r and that
+// neither From nor To contains pointers, then calls fn with each key and value to get the new value.
+func MapValuesOf[From, To any](r *Read, fn func(key string, v From) (To, bool, error), opts ...Option) (*Write, error) {
+	from := reflect.TypeFor[From]()
+	if err := checkPointerFree(from); err != nil {
+		return nil, err
+	}
+	if int(from.Size()) != r.valueSize {
+		return nil, fmt.Errorf("%w: table has values of %d bytes, %s is %d bytes", ErrValueSizeMismatch, r.valueSize, from, from.Size())
+	}
+	to := reflect.TypeFor[To]()
+	if err := checkPointerFree(to); err != nil {
+		return nil, err
+	}
+
+	return MapValues(r, int(to.Size()), func(key string, old, new []byte) (bool, error) {
+		v, keep, err := fn(key, *(*From)(bytesPointer(old)))
+		if keep && err == nil {
+			*(*To)(bytesPointer(new)) = v
+		}
+		return keep, err
+	}, opts...)
+}
@@ -0,0 +1,81 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapValues(t *testing.T) {
+	tb := buildTable(t, 100, WithInsertionOrder())
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	type wide struct {
+		Old     int
+		Doubled int64
+		Odd     bool
+	}
+	w, err := MapValuesOf(r, func(key string, v int) (wide, bool, error) {
+		return wide{Old: v, Doubled: int64(2 * v), Odd: v%2 == 1}, v < 50, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, w.Len())
+	p, ok := w.GetPtr("key91")
+	if assert.True(t, ok) {
+		assert.Equal(t, wide{Old: 9, Doubled: 18, Odd: true}, *(*wide)(p))
+	}
+	_, ok = w.GetPtr("key50")
+	assert.False(t, ok)
+	it := w.Iterate()
+	assert.True(t, it.Next())
+	assert.Equal(t, "key100", it.Key())
+
+	// Shrink the values to a single byte
+	w, err = MapValues(r, 1, func(key string, old, new []byte) (bool, error) {
+		new[0] = old[0] + 1
+		return true, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, w.ValueSize())
+	v, ok := w.GetValue("key1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte{100}, v)
+
+	_, err = MapValues(r, 4, func(key string, old, new []byte) (bool, error) {
+		return false, fmt.Errorf("stop at %s", key)
+	})
+	assert.EqualError(t, err, "stop at key100")
+
+	_, err = MapValuesOf(r, func(key string, v int32) (int32, bool, error) { return v, true, nil })
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+	_, err = MapValuesOf(r, func(key string, v int) (string, bool, error) { return "", true, nil })
+	assert.ErrorIs(t, err, ErrHasPointers)
+}
+
+func TestMapValuesColumnar(t *testing.T) {
+	tb := New(10, 8, 100, WithColumns(4, 4))
+	for i := range 10 {
+		v := [2]int32{int32(i), int32(-i)}
+		assert.NoError(t, tb.SetValue(fmt.Sprint(i), v))
+	}
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	w, err := MapValuesOf(r, func(key string, v [2]int32) (int32, bool, error) { return v[1], true, nil })
+	assert.NoError(t, err)
+	assert.Nil(t, w.Columns())
+	p, ok := w.GetPtr("7")
+	assert.True(t, ok)
+	assert.Equal(t, int32(-7), *(*int32)(p))
+}
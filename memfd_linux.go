@@ -0,0 +1,35 @@
+package statichash
+
+import (
+	"bufio"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// WriteMemfd saves the table to a new anonymous in-memory file made with memfd_create, rather than to disk. The
+// file is sealed so that it can't be changed once written. Send its descriptor to other processes over a Unix
+// socket (see syscall.UnixRights), and open the table there with NewFromFile. name is only used to identify the
+// file in /proc.
+func (t *Write) WriteMemfd(name string) (*os.File, error) {
+	fd, err := unix.MemfdCreate(name, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "memfd:"+name)
+
+	w := bufio.NewWriter(f)
+	if _, err := t.WriteTo(w); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SEAL|unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
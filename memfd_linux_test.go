@@ -0,0 +1,43 @@
+package statichash
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemfd(t *testing.T) {
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	f, err := tb.WriteMemfd("table")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	// The file can't be changed
+	_, err = f.WriteAt([]byte("x"), 0)
+	assert.Error(t, err)
+
+	// Pass the file to "another process" over a Unix socket
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	assert.NoError(t, err)
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+	assert.NoError(t, syscall.Sendmsg(fds[0], []byte{0}, syscall.UnixRights(int(f.Fd())), nil, 0))
+
+	buf := make([]byte, 1)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := syscall.Recvmsg(fds[1], buf, oob, 0)
+	assert.NoError(t, err)
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	assert.NoError(t, err)
+	received, err := syscall.ParseUnixRights(&msgs[0])
+	assert.NoError(t, err)
+
+	r, err := NewFromFile(os.NewFile(uintptr(received[0]), "received"))
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, 10, r.Len())
+	assert.NoError(t, r.Validate())
+}
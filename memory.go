@@ -1,38 +1,64 @@
-// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
 
 package statichash
 
-import "syscall"
-
-func mapMemory(fd, size uintptr) (uintptr, error) {
-	data, _, errno := syscall.Syscall6(
-		syscall.SYS_MMAP,
-		0, // address
-		size,
-		syscall.PROT_READ,
-		syscall.MAP_FILE|syscall.MAP_PRIVATE,
-		uintptr(fd), // No file descriptor
-		0,           // offset
-	)
-	if errno != 0 {
-		// zero errno is not nil!
-		return 0, errno
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// mapMemory maps the first size bytes of the file and locks them into memory
+func mapMemory(fd uintptr, size int) ([]byte, error) {
+	return mapMemoryAt(fd, 0, size)
+}
+
+// mapMemoryAt maps size bytes of the file starting at offset, which must be a multiple of the page size, and
+// locks them into memory
+func mapMemoryAt(fd uintptr, offset int64, size int) ([]byte, error) {
+	data, err := mapFile(fd, offset, size)
+	if err != nil {
+		return nil, err
 	}
 
-	_, _, errno = syscall.Syscall(syscall.SYS_MLOCK, data, size, 0)
-	if errno != 0 {
-		// zero errno is not nil!
-		return 0, errno
+	if err := lockMemory(data); err != nil {
+		syscall.Munmap(data)
+		return nil, fmt.Errorf("locking memory: %w", err)
 	}
 
 	return data, nil
 }
 
-func unmap(data, length uintptr) error {
-	_, _, errno := syscall.Syscall(syscall.SYS_MUNMAP, data, length, 0)
+// mapFile maps size bytes of the file starting at offset, which must be a multiple of the page size
+func mapFile(fd uintptr, offset int64, size int) ([]byte, error) {
+	return syscall.Mmap(int(fd), offset, size, syscall.PROT_READ, syscall.MAP_PRIVATE)
+}
+
+// mapFileWritable maps the first size bytes of the file so that changes to the memory are written to the file
+func mapFileWritable(fd uintptr, size int) ([]byte, error) {
+	return syscall.Mmap(int(fd), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// lockMemory locks data into memory. syscall.Mlock isn't available on every platform, so we make the call
+// ourselves.
+func lockMemory(data []byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MLOCK, uintptr(unsafe.Pointer(unsafe.SliceData(data))), uintptr(len(data)), 0)
 	if errno != 0 {
 		// zero errno is not nil!
 		return errno
 	}
 	return nil
 }
+
+// unlockMemory undoes lockMemory
+func unlockMemory(data []byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MUNLOCK, uintptr(unsafe.Pointer(unsafe.SliceData(data))), uintptr(len(data)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func unmap(data []byte) error {
+	return syscall.Munmap(data)
+}
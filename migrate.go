@@ -0,0 +1,75 @@
+package statichash
+
+import "slices"
+
+// Options returns the options the table was built with, such as WithSeed, WithFingerprints and WithColumns.
+// Pass them to New or Migrate to build another table laid out in the same way.
+func (r *Read) Options() []Option {
+	return r.options()
+}
+
+// Migrate builds a new table holding the entries of r, laid out as opts describe rather than as r is. Use it to
+// move a table to the current file format, or to a different hash function, load factor or set of layout
+// options, without going back to the data it was built from. Pass r.Options() first in opts to keep r's options
+// and change only some of them. A string or blob table stays one whatever opts say.
+//
+// capacity is passed to New, so it sets how full the new table is. It is raised to r.Len() if it is smaller.
+// Entries are copied one at a time in the order Iterate visits them, so the only memory needed besides the two
+// tables is for one entry. The new table keeps r's build time and version, and optional sections such as its
+// schema, unless opts replace them.
+func Migrate(r *Read, capacity int, opts ...Option) (*Write, error) {
+	if err := r.needKeys("migrate"); err != nil {
+		return nil, err
+	}
+	stringValues := r.flags&flagStringValues != 0
+	var keyLength int64
+	for it := r.Iterate(); it.Next(); {
+		keyLength += int64(len(it.Key()))
+		if stringValues {
+			keyLength += int64(len(r.stringValue(it.index)))
+		}
+	}
+
+	info := r.Info()
+	base := []Option{WithVersion(info.Version)}
+	if !info.Created.IsZero() {
+		base = append(base, WithBuildTime(info.Created))
+	}
+	if stringValues {
+		base = append(base, withStringValues())
+	}
+	opts = append(base, opts...)
+	valueSize := r.valueSize
+	if stringValues {
+		// The new table may store strings differently
+		valueSize = stringTableValueSize(opts)
+	}
+	w := New(max(capacity, r.count), int64(valueSize), keyLength, opts...)
+
+	for it := r.Iterate(); it.Next(); {
+		var err error
+		if stringValues {
+			err = w.SetString(it.Key(), r.stringValue(it.index))
+		} else {
+			err = w.Set(it.Key(), r.valuePtr(it.index))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Growing the table drops its sections, so they are added once every entry is in
+	for _, e := range r.sections {
+		kind := Section(e.kind)
+		if slices.Contains(coreSections, kind) || slices.ContainsFunc(w.extra, func(x extraSection) bool { return x.kind == kind }) {
+			continue
+		}
+		data, _, err := r.section(kind)
+		if err != nil {
+			return nil, err
+		}
+		w.addSection(kind, slices.Clone(data))
+	}
+	w.Finalize()
+	return w, nil
+}
@@ -0,0 +1,194 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readBack writes tb out and reads it in again
+func readBack(t testing.TB, tb *Write) *Read {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	return r
+}
+
+// checkEntries checks r holds the entries buildTable(t, n) sets
+func checkEntries(t *testing.T, r *Read, n int) {
+	t.Helper()
+	assert.Equal(t, n, r.Len())
+	for i := 0; i < n; i++ {
+		v, ok := r.GetPtr(fmt.Sprintf("key%d", n-i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tb := buildTable(t, 500, WithFingerprints(), WithVersion("v7"), WithBuildTime(created))
+	tb.Finalize()
+	r := readBack(t, tb)
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		check func(t *testing.T, r *Read)
+	}{
+		{
+			name: "same options",
+			opts: r.Options(),
+			check: func(t *testing.T, m *Read) {
+				assert.Equal(t, r.flags, m.flags)
+				assert.Equal(t, r.NumSlots(), m.NumSlots())
+			},
+		},
+		{
+			name: "seeded",
+			opts: append(r.Options(), WithSeed(42)),
+			check: func(t *testing.T, m *Read) {
+				assert.NotZero(t, m.flags&flagSeeded)
+				assert.NotZero(t, m.flags&flagFingerprints)
+				assert.EqualValues(t, 42, m.seed)
+			},
+		},
+		{
+			name: "reset",
+			check: func(t *testing.T, m *Read) {
+				assert.Zero(t, m.flags&flagFingerprints)
+			},
+		},
+		{
+			name: "interleaved inline",
+			opts: []Option{WithInlineValues()},
+			check: func(t *testing.T, m *Read) {
+				assert.NotZero(t, m.flags&flagInterleaved)
+				assert.NotZero(t, m.flags&flagInlineValues)
+			},
+		},
+		{
+			name: "cuckoo",
+			opts: []Option{WithCuckoo()},
+			check: func(t *testing.T, m *Read) {
+				assert.Equal(t, StrategyCuckoo, m.Info().Strategy)
+			},
+		},
+		{
+			name: "hopscotch",
+			opts: []Option{WithHopscotch(), WithAutoGrow()},
+			check: func(t *testing.T, m *Read) {
+				assert.Equal(t, StrategyHopscotch, m.Info().Strategy)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, err := Migrate(r, r.NumSlots(), test.opts...)
+			assert.NoError(t, err)
+			m := readBack(t, w)
+			checkEntries(t, m, 500)
+			info := m.Info()
+			assert.Equal(t, "v7", info.Version)
+			assert.True(t, created.Equal(info.Created))
+			test.check(t, m)
+		})
+	}
+}
+
+func TestMigrateCapacity(t *testing.T) {
+	tb := buildTable(t, 100)
+	tb.Finalize()
+	r := readBack(t, tb)
+
+	// Capacity sets the load factor of the new table, but never below what the entries need
+	w, err := Migrate(r, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, w.NumSlots())
+	checkEntries(t, readBack(t, w), 100)
+
+	w, err = Migrate(r, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, r.NumSlots(), w.NumSlots())
+	checkEntries(t, readBack(t, w), 100)
+}
+
+func TestMigrateOldFormat(t *testing.T) {
+	tb := buildTable(t, 100, WithInsertionOrder())
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(toV1(t, buf.Bytes()))
+	assert.NoError(t, err)
+
+	w, err := Migrate(r, r.NumSlots(), r.Options()...)
+	assert.NoError(t, err)
+	m := readBack(t, w)
+	h, err := readHeader(m.data)
+	assert.NoError(t, err)
+	assert.EqualValues(t, currentFormat, h.format)
+	checkEntries(t, m, 100)
+
+	// Insertion order is kept
+	var keys []string
+	for it := m.Iterate(); it.Next(); {
+		keys = append(keys, it.Key())
+	}
+	assert.Equal(t, "key100", keys[0])
+	assert.Equal(t, "key1", keys[99])
+}
+
+func TestMigrateSections(t *testing.T) {
+	schema, err := SchemaOf[typedValue]()
+	assert.NoError(t, err)
+	tb := New(10, int64(unsafe.Sizeof(typedValue{})), 100, WithSchema(schema), WithValueType[typedValue]())
+	for i := range 10 {
+		v := typedValue{A: int32(i), B: float64(i) / 2}
+		assert.NoError(t, tb.Set(fmt.Sprint(i), unsafe.Pointer(&v)))
+	}
+	tb.Finalize()
+	r := readBack(t, tb)
+
+	w, err := Migrate(r, 10, WithSeed(1))
+	assert.NoError(t, err)
+	m := readBack(t, w)
+	got, ok, err := m.Schema()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, schema, got)
+	_, err = NewTyped[typedValue](m)
+	assert.NoError(t, err)
+	v, ok := m.GetPtr("3")
+	assert.True(t, ok)
+	assert.Equal(t, typedValue{A: 3, B: 1.5}, *(*typedValue)(v))
+}
+
+func TestMigrateStrings(t *testing.T) {
+	st := NewStringTable(100, 2000)
+	for i := range 100 {
+		assert.NoError(t, st.Set(fmt.Sprintf("key%d", i), fmt.Sprintf("value %d", i*i)))
+	}
+	st.Finalize()
+	r := readBack(t, st.w)
+
+	w, err := Migrate(r, 200, WithSeed(3))
+	assert.NoError(t, err)
+	m, err := stringTableFrom(readBack(t, w))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, m.Len())
+	for i := range 100 {
+		v, ok := m.Get(fmt.Sprintf("key%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("value %d", i*i), v)
+	}
+}
@@ -0,0 +1,139 @@
+package statichash
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+// lockPriority is the order WithMemoryLockLimit locks sections in. Every lookup reads the index sections, and
+// most read the key data, so these matter most.
+var lockPriority = []Section{SectionHashes, SectionFingerprints, SectionKeys, SectionKeyData, SectionValues, SectionOrder, SectionSorted}
+
+// Lock locks the given sections of the table into memory, so that lookups never wait for them to be read from
+// the file. If no sections are given the whole table is locked. Tables are locked when they are opened unless
+// they are opened WithoutMemoryLock. Open them that way and Lock just the sections lookups touch most, such as
+// SectionHashes and SectionKeys, to keep within RLIMIT_MEMLOCK and leave the rest of the table pageable.
+//
+// Lock does nothing for tables created with NewFromBytes. The values and key data of a table opened
+// WithWindowedMapping are only mapped in windows, so they can't be locked.
+func (r *Read) Lock(sections ...Section) error {
+	if !r.mapped {
+		return nil
+	}
+	if len(sections) == 0 {
+		sections = coreSections
+	}
+	for _, s := range sections {
+		if _, err := r.lockSection(s, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlock unlocks the whole table, so that its pages can be dropped when memory is short and read back in from
+// the file as lookups need them
+func (r *Read) Unlock() error {
+	if !r.mapped {
+		return nil
+	}
+	if err := unlockMemory(r.data); err != nil {
+		return err
+	}
+	r.locked = nil
+	return nil
+}
+
+// Locked reports the parts of the table that are locked into memory, one entry for each section that is
+// wholly or partly locked. Locking applies to whole pages, so an entry may start a little before its section.
+func (r *Read) Locked() []SectionStats {
+	return slices.Clone(r.locked)
+}
+
+// lockWithin locks sections in lockPriority order until limit bytes are locked or locking fails. It returns
+// the number of bytes locked.
+func (r *Read) lockWithin(limit int64) (locked int64) {
+	for _, s := range lockPriority {
+		if limit-locked <= 0 {
+			break
+		}
+		n, err := r.lockSection(s, limit-locked)
+		if err != nil {
+			break
+		}
+		locked += n
+	}
+	return locked
+}
+
+// lockSection locks the pages of section s, or as many whole pages from its start as fit in limit bytes if
+// limit isn't negative. It returns the number of bytes locked.
+func (r *Read) lockSection(s Section, limit int64) (int64, error) {
+	if r.win != nil && (s == SectionValues || s == SectionKeyData) {
+		return 0, nil
+	}
+	start, data := r.sectionPages(s)
+	if limit >= 0 && int64(len(data)) > limit {
+		data = data[:limit&^(int64(os.Getpagesize())-1)]
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	if err := lockMemory(data); err != nil {
+		return 0, fmt.Errorf("locking %s section: %w", s, err)
+	}
+	r.setLocked(SectionStats{Section: s, Offset: start, Length: int64(len(data))})
+	return int64(len(data)), nil
+}
+
+// noteLocked records that every section in the mapping is locked
+func (r *Read) noteLocked() {
+	for _, s := range coreSections {
+		if start, data := r.sectionPages(s); len(data) > 0 {
+			r.setLocked(SectionStats{Section: s, Offset: start, Length: int64(len(data))})
+		}
+	}
+}
+
+// setLocked records the locked part of a section, replacing any earlier record for the section
+func (r *Read) setLocked(locked SectionStats) {
+	r.clearLocked(locked.Section)
+	r.locked = append(r.locked, locked)
+}
+
+// clearLocked forgets any record of section s being locked
+func (r *Read) clearLocked(s Section) {
+	r.locked = slices.DeleteFunc(r.locked, func(l SectionStats) bool { return l.Section == s })
+}
+
+// sectionPages returns the offset and mapped memory of the whole pages holding section s. The memory is empty
+// if the section is, or if it isn't mapped. Locking and advice apply to whole pages, so the first page may be
+// shared with the end of the section before.
+func (r *Read) sectionPages(s Section) (int64, []byte) {
+	start, end := r.layout.section(s)
+	if start >= end {
+		return start, nil
+	}
+	start &^= int64(os.Getpagesize()) - 1
+	end = min(end, int64(len(r.data)))
+	if start >= end {
+		return start, nil
+	}
+	return start, r.data[start:end]
+}
+
+// mapMemory maps the first size bytes of the file, locking them into memory unless the table is being opened
+// WithoutMemoryLock
+func (o *readOptions) mapMemory(fd uintptr, size int) ([]byte, error) {
+	return o.mapMemoryAt(fd, 0, size)
+}
+
+// mapMemoryAt is like mapMemory, but maps size bytes starting at offset, which must be a multiple of the page
+// size
+func (o *readOptions) mapMemoryAt(fd uintptr, offset int64, size int) ([]byte, error) {
+	if o.noMemLock {
+		return mapFile(fd, offset, size)
+	}
+	return mapMemoryAt(fd, offset, size)
+}
@@ -0,0 +1,218 @@
+package statichash
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	name := writeTempTable(t, buildTable(t, 10000))
+	before := lockedKiB(t)
+
+	tr, err := NewFrom(name, WithoutMemoryLock())
+	assert.NoError(t, err)
+	defer tr.Close()
+	if before >= 0 {
+		assert.Equal(t, before, lockedKiB(t))
+	}
+
+	check := func() {
+		for i := 0; i < 10000; i += 100 {
+			v, ok := tr.GetPtr(fmt.Sprintf("key%d", 10000-i))
+			if assert.True(t, ok) {
+				assert.Equal(t, i, *(*int)(v))
+			}
+		}
+	}
+	check()
+
+	assert.Empty(t, tr.Locked())
+	assert.NoError(t, tr.Lock(SectionHashes, SectionKeys))
+	check()
+	assert.Equal(t, []Section{SectionHashes, SectionKeys}, lockedSections(tr))
+	var index int64
+	if before >= 0 {
+		index = lockedKiB(t) - before
+		assert.Greater(t, index, int64(0))
+	}
+
+	assert.NoError(t, tr.Lock())
+	check()
+	if before >= 0 {
+		assert.Greater(t, lockedKiB(t)-before, index)
+	}
+
+	assert.NoError(t, tr.Unlock())
+	check()
+	assert.Empty(t, tr.Locked())
+	if before >= 0 {
+		assert.Equal(t, before, lockedKiB(t))
+	}
+
+	// Tables that aren't mapped have nothing to lock
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	tr2, err := NewFromBytes(data)
+	assert.NoError(t, err)
+	assert.NoError(t, tr2.Lock())
+	assert.NoError(t, tr2.Unlock())
+}
+
+func TestMemoryLockLimit(t *testing.T) {
+	name := writeTempTable(t, buildTable(t, 10000, WithFingerprints()))
+
+	tr, err := NewFrom(name)
+	assert.NoError(t, err)
+	assert.Equal(t, []Section{SectionHashes, SectionFingerprints, SectionKeys, SectionValues, SectionKeyData}, lockedSections(tr))
+
+	// The hashes and fingerprints fit but only the first page of the key offsets does
+	pageSize := int64(os.Getpagesize())
+	_, hashes := tr.sectionPages(SectionHashes)
+	_, fingerprints := tr.sectionPages(SectionFingerprints)
+	limit := int64(len(hashes)+len(fingerprints)) + pageSize
+	assert.NoError(t, tr.Close())
+
+	tr, err = NewFrom(name, WithMemoryLockLimit(limit))
+	assert.NoError(t, err)
+	defer tr.Close()
+	locked := tr.Locked()
+	assert.Equal(t, []Section{SectionHashes, SectionFingerprints, SectionKeys}, lockedSections(tr))
+	var total int64
+	for _, l := range locked {
+		total += l.Length
+	}
+	assert.LessOrEqual(t, total, limit)
+	if len(locked) == 3 {
+		keysStart, keysEnd := tr.layout.section(SectionKeys)
+		assert.Less(t, locked[2].Offset+locked[2].Length, keysEnd)
+		assert.Greater(t, locked[2].Offset+locked[2].Length, keysStart)
+	}
+
+	for i := 0; i < 10000; i += 100 {
+		v, ok := tr.GetPtr(fmt.Sprintf("key%d", 10000-i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+}
+
+func TestWithoutMemoryLockOpeners(t *testing.T) {
+	tb := buildTable(t, 10000)
+	tb.Finalize()
+	var table bytes.Buffer
+	_, err := tb.WriteTo(&table)
+	assert.NoError(t, err)
+	dir := t.TempDir()
+
+	// A zip archive whose only member is stored so that it can be mapped
+	zipName := filepath.Join(dir, "tables.zip")
+	f, err := os.Create(zipName)
+	assert.NoError(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "ab", Method: zip.Store})
+	assert.NoError(t, err)
+	_, err = w.Write(table.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	assert.NoError(t, f.Close())
+
+	containerName := filepath.Join(dir, "container")
+	assert.NoError(t, os.WriteFile(containerName, append(make([]byte, 5000), table.Bytes()...), 0o644))
+	container, err := os.Open(containerName)
+	assert.NoError(t, err)
+	defer container.Close()
+
+	segmentedName := filepath.Join(dir, "segmented")
+	assert.NoError(t, AppendSegment(segmentedName, tb))
+
+	partitionedName := filepath.Join(dir, "partitioned")
+	pt := NewPartitioned(10000, 8, 100000, 2)
+	for i := range 10000 {
+		v := int64(i)
+		assert.NoError(t, pt.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&v)))
+	}
+	pt.Finalize()
+	assert.NoError(t, pt.WriteFile(partitionedName))
+
+	tests := []struct {
+		name string
+		open func(opts ...ReadOption) (r *Read, close func() error)
+	}{
+		{name: "zip", open: func(opts ...ReadOption) (*Read, func() error) {
+			r, err := NewFromZip(zipName, "ab", opts...)
+			assert.NoError(t, err)
+			assert.NotNil(t, r.mapping)
+			return r, r.Close
+		}},
+		{name: "section reader", open: func(opts ...ReadOption) (*Read, func() error) {
+			r, err := NewFromSectionReader(io.NewSectionReader(container, 5000, int64(table.Len())), opts...)
+			assert.NoError(t, err)
+			assert.NotNil(t, r.mapping)
+			return r, r.Close
+		}},
+		{name: "segmented", open: func(opts ...ReadOption) (*Read, func() error) {
+			s, err := OpenSegmented(segmentedName, opts...)
+			assert.NoError(t, err)
+			return s.segments[0], s.Close
+		}},
+		{name: "partitioned", open: func(opts ...ReadOption) (*Read, func() error) {
+			p, err := OpenPartitioned(partitionedName, opts...)
+			assert.NoError(t, err)
+			r, err := p.Partition(0)
+			assert.NoError(t, err)
+			return r, p.Close
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := lockedKiB(t)
+			r, close := test.open(WithoutMemoryLock())
+			assert.Empty(t, r.Locked())
+			if before >= 0 {
+				assert.Equal(t, before, lockedKiB(t))
+			}
+			assert.NoError(t, close())
+
+			r, close = test.open(WithMemoryLockLimit(int64(os.Getpagesize())))
+			assert.Equal(t, []Section{SectionHashes}, lockedSections(r))
+			assert.NoError(t, close())
+		})
+	}
+}
+
+// lockedSections lists the sections Locked reports
+func lockedSections(tr *Read) []Section {
+	var sections []Section
+	for _, l := range tr.Locked() {
+		sections = append(sections, l.Section)
+	}
+	return sections
+}
+
+// lockedKiB returns the amount of memory the process has locked, or -1 if we can't tell on this platform
+func lockedKiB(t *testing.T) int64 {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+	for s := bufio.NewScanner(f); s.Scan(); {
+		if rest, ok := strings.CutPrefix(s.Text(), "VmLck:"); ok {
+			var kib int64
+			_, err := fmt.Sscanf(strings.TrimSpace(rest), "%d kB", &kib)
+			assert.NoError(t, err)
+			return kib
+		}
+	}
+	return -1
+}
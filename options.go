@@ -0,0 +1,438 @@
+package statichash
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Option configures a table created with New
+type Option func(o *options)
+
+type options struct {
+	flags   int64
+	seed    uint64
+	created int64
+	version [32]byte
+	grow    bool
+	columns []int
+	// onDuplicate is called when Set overwrites a key
+	onDuplicate func(key string)
+	// progress is called as the table is built and saved
+	progress func(Progress)
+	// schema is saved in the file if set
+	schema *Schema
+	// valueType is the type of the values given to WithValueType
+	valueType reflect.Type
+	// inlineStrings is the length of the longest string stored in a value WithInlineStrings
+	inlineStrings int
+}
+
+// WithInsertionOrder records the order in which keys are first Set in an extra section of the file. Iterating
+// the table then reproduces the original input order rather than the order of the hash slots. It costs 8 bytes
+// per slot.
+func WithInsertionOrder() Option {
+	return func(o *options) {
+		o.flags |= flagInsertionOrder
+	}
+}
+
+// WithSeed makes the table hash keys with a fixed seed rather than with aeshash, which is seeded per process.
+// The seed is stored in the file, and building from the same input in the same order always produces an
+// identical file.
+func WithSeed(seed uint64) Option {
+	return func(o *options) {
+		o.flags |= flagSeeded
+		o.seed = seed
+	}
+}
+
+// WithFingerprints stores an extra byte of each key's hash in a separate section. Lookups compare it before
+// looking at the key itself, so most hash collisions are resolved without touching the key data. It costs a
+// byte per slot.
+func WithFingerprints() Option {
+	return func(o *options) {
+		o.flags |= flagFingerprints
+	}
+}
+
+// WithSortedIndex stores the slots of the entries in key order in an extra section of the file, so that
+// SortedIterate doesn't need to sort the keys when the table is loaded. It costs 8 bytes per slot.
+func WithSortedIndex() Option {
+	return func(o *options) {
+		o.flags |= flagSortedIndex
+	}
+}
+
+// WithVersion records a version string in the file, which is returned by Info when the table is read. Use it
+// to identify the data or the program that built it. Only the first 32 bytes are kept.
+func WithVersion(version string) Option {
+	return func(o *options) {
+		o.version = [32]byte{}
+		copy(o.version[:], version)
+	}
+}
+
+// WithBuildTime sets the build time recorded in the file. By default the time the table is written is used,
+// unless the table is built WithSeed, when no time is recorded so that the output is reproducible.
+func WithBuildTime(t time.Time) Option {
+	return func(o *options) {
+		o.created = t.UnixNano()
+	}
+}
+
+// WithAutoGrow lets a Write grow when it runs out of slots or key space, rather than Set returning an error.
+// The table is rehashed into a new arena with twice the space, so growing is expensive and briefly needs
+// memory for both the old and new tables. Use it when the number of items is only approximately known in
+// advance.
+func WithAutoGrow() Option {
+	return func(o *options) {
+		o.grow = true
+	}
+}
+
+// WithColumns splits each value into columns of the given widths in bytes, and stores each column in its own
+// array (a struct-of-arrays layout). Lookups that need only one column then touch only that column's memory.
+// The widths must add up to the value size, and there can be at most 16 columns. Each column should be a
+// multiple of its type's alignment, so for example a struct with an int32 and a float64 could have columns of
+// 4 and 8 bytes but not be split into bytes.
+//
+// GetPtr on a columnar table returns a pointer to a copy of the value, assembled from the columns.
+func WithColumns(widths ...int) Option {
+	return func(o *options) {
+		o.flags |= flagColumnar
+		o.columns = widths
+	}
+}
+
+// WithPageAlignedSections starts each section of the file on a 64 KiB boundary rather than packing them
+// together. Each section then occupies whole pages, so Evict releases exactly the sections it is given, and
+// sections can be mapped or locked independently. It costs up to 64 KiB of padding per section, so it is only
+// worthwhile for large tables.
+func WithPageAlignedSections() Option {
+	return func(o *options) {
+		o.flags |= flagPageAligned
+	}
+}
+
+// WithCuckoo builds the table with cuckoo hashing, so a lookup examines at most two slots: the key's home slot
+// and a second slot chosen by another hash. Set moves entries between their two slots to make room, and
+// rehashes the table if that fails. Use it when each probe is expensive, such as when the table is a cold
+// memory-mapped file and each slot examined may be a page fault. The table can only be filled to 45% of its
+// slots, so New allocates more than twice as many slots as entries. It can't be combined with
+// WithInsertionOrder.
+func WithCuckoo() Option {
+	return func(o *options) {
+		o.flags |= flagCuckoo
+	}
+}
+
+// WithHopscotch builds the table with hopscotch hashing, which keeps every key within 32 slots of its home slot
+// by moving other entries along to make room for it. A lookup then examines at most 32 consecutive slots, which
+// is only a couple of cache lines, even when the table is 90% full or more. Set returns ErrTableFull if a key
+// can't be brought close enough to its home slot, unless the table is built WithAutoGrow. It can't be combined
+// with WithCuckoo or WithInsertionOrder.
+func WithHopscotch() Option {
+	return func(o *options) {
+		o.flags |= flagHopscotch
+	}
+}
+
+// WithInterleavedSlots stores the hash, fingerprint and key offset of each slot together in one 16-byte record,
+// rather than in three parallel arrays. A probe then touches one cache line instead of up to three, which makes
+// lookups in large tables that don't fit in the CPU caches noticeably faster. The records take 16 bytes per slot
+// where the arrays take 12, or 13 WithFingerprints, and every slot gets a fingerprint. The file can only be read
+// by versions of this package that know about interleaved slots, and Hashes and KeyOffsets return nil for it.
+func WithInterleavedSlots() Option {
+	return func(o *options) {
+		o.flags |= flagInterleaved
+	}
+}
+
+// WithInlineValues stores each value in its slot, straight after the hash, fingerprint and key offset, so a
+// lookup that finds its key has the value in the same cache line rather than having to fetch it from the values
+// section. It implies WithInterleavedSlots. Values can be at most 16 bytes, and are padded to a multiple of 8 in
+// the slot, so it suits tables of counters or IDs, such as strings mapped to an int64. It can't be combined
+// with WithColumns.
+func WithInlineValues() Option {
+	return func(o *options) {
+		o.flags |= flagInterleaved | flagInlineValues
+	}
+}
+
+// WithInlineStrings stores string and blob values of up to n bytes in the values themselves rather than with
+// the keys, and longer ones with the keys as usual. It suits tables whose values are mostly short but sometimes
+// long, as only the long ones pay for an offset and a trip to the key data, and the values needn't all be padded
+// to the longest. It implies WithBlobValues. n can be at most 247, and the value size is n+1 rounded up to a
+// multiple of 8, and at least 16, which NewStringTable works out. Pass that value size if you create the table
+// with New, or New panics. Long strings must still be included in totalKeyLength.
+func WithInlineStrings(n int) Option {
+	return func(o *options) {
+		o.flags |= flagStringValues | flagInlineStrings
+		o.inlineStrings = n
+	}
+}
+
+// WithHashOnly stores the 64-bit hash of each key in place of the key itself, and no key data at all. Lookups
+// compare the hashes, so two keys whose hashes are the same are treated as one key. Use it when keys are known
+// not to collide, or when a rare false positive is acceptable, to save most of the space long keys such as URLs
+// would take. totalKeyLength is ignored. Hash is only 32 bits on 32-bit platforms, so build the table WithSeed
+// if it might be used on one.
+//
+// Iterating the table gives empty keys, though Iterator.Hash tells the entries apart, and anything that needs
+// the keys themselves, such as Diff, WritePatch, Rekey or Migrate, returns an error wrapping ErrNoKeys. It
+// can't be combined with WithCuckoo, WithSortedIndex or string values.
+func WithHashOnly() Option {
+	return func(o *options) {
+		o.flags |= flagHashOnly
+	}
+}
+
+// WithDuplicateHandler calls fn whenever Set is called with a key that is already in the table. The new value
+// still replaces the old one. Use it to find duplicates in input that should have none. Duplicates counts
+// them whether or not a handler is set.
+func WithDuplicateHandler(fn func(key string)) Option {
+	return func(o *options) {
+		o.onDuplicate = fn
+	}
+}
+
+// WithProgress calls fn periodically as entries are Set, and as the table is saved with WriteTo, so that long
+// builds can report progress. fn is called from the goroutine doing the work, and should be quick.
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithBlobValues marks the table as holding variable-length blob values, set with SetBytes and read with
+// GetBytes. The value size passed to New must be 8, as each value is the offset of its blob in the key data.
+// Blobs are stored in the same way as strings, so the table can also be opened as a StringTable.
+func WithBlobValues() Option {
+	return withStringValues()
+}
+
+// WithSchema saves s in the file, so that readers can check the values are laid out as they expect with
+// WithExpectedSchema, and tools can find the fields with Read.Schema. New panics if any of the fields don't fit
+// in the value.
+func WithSchema(s Schema) Option {
+	return func(o *options) {
+		o.schema = &s
+	}
+}
+
+// withStringValues marks the table as holding string values
+func withStringValues() Option {
+	return func(o *options) {
+		o.flags |= flagStringValues
+	}
+}
+
+// withoutColumns undoes WithColumns
+func withoutColumns() Option {
+	return func(o *options) {
+		o.flags &^= flagColumnar
+		o.columns = nil
+	}
+}
+
+// options returns the Options needed to create a new table configured the same way as t
+func (t *table) options() []Option {
+	var opts []Option
+	if t.flags&flagInsertionOrder != 0 {
+		opts = append(opts, WithInsertionOrder())
+	}
+	if t.flags&flagSeeded != 0 {
+		opts = append(opts, WithSeed(t.seed))
+	}
+	if t.flags&flagFingerprints != 0 {
+		opts = append(opts, WithFingerprints())
+	}
+	if t.flags&flagSortedIndex != 0 {
+		opts = append(opts, WithSortedIndex())
+	}
+	if t.flags&flagStringValues != 0 {
+		opts = append(opts, withStringValues())
+	}
+	if t.flags&flagColumnar != 0 {
+		opts = append(opts, WithColumns(t.columns...))
+	}
+	if t.flags&flagPageAligned != 0 {
+		opts = append(opts, WithPageAlignedSections())
+	}
+	if t.flags&flagCuckoo != 0 {
+		opts = append(opts, WithCuckoo())
+	}
+	if t.flags&flagHopscotch != 0 {
+		opts = append(opts, WithHopscotch())
+	}
+	if t.flags&flagInterleaved != 0 {
+		opts = append(opts, WithInterleavedSlots())
+	}
+	if t.flags&flagInlineValues != 0 {
+		opts = append(opts, WithInlineValues())
+	}
+	if t.flags&flagInlineStrings != 0 {
+		opts = append(opts, WithInlineStrings(t.valueSize-1))
+	}
+	if t.flags&flagHashOnly != 0 {
+		opts = append(opts, WithHashOnly())
+	}
+	return opts
+}
+
+// ReadOption configures a table opened with NewFrom
+type ReadOption func(o *readOptions)
+
+type readOptions struct {
+	windowSize   int64
+	maxWindows   int
+	trusted      bool
+	encoder      ValueEncoder
+	valueSize    int
+	lock         bool
+	keepOpen     bool
+	noMemLock    bool
+	memLockLimit int64
+	trace        func(Trace)
+	logger       *slog.Logger
+	progress     func(Progress)
+	schema       *Schema
+}
+
+// newReadOptions applies opts to the default read options
+func newReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// logOpen logs the result of opening the table file filename, if there is a logger
+func (o *readOptions) logOpen(filename string, r *Read, err error) {
+	if o.logger == nil {
+		return
+	}
+	if err != nil {
+		o.logger.Error("opening table failed", "file", filename, "error", err)
+		return
+	}
+	o.logger.Info("opened table", "file", filename, "entries", r.Len(), "slots", r.NumSlots(), "windowed", r.win != nil)
+}
+
+// WithWindowedMapping is for tables too large to map into memory in one go. Only the header, hashes and key
+// offsets are mapped (and locked) for the life of the table. The values and key data are mapped on demand in
+// windows of windowSize bytes, with at most maxWindows mapped at once. windowSize is rounded up to a multiple
+// of the page size.
+//
+// Lookups are slower in this mode, and GetPtr returns a pointer to a copy of the value rather than into the
+// mapping. If a window can't be mapped the key is reported as not found, and the error is returned by Err.
+func WithWindowedMapping(windowSize int64, maxWindows int) ReadOption {
+	return func(o *readOptions) {
+		o.windowSize = roundUp(windowSize, uintptr(os.Getpagesize()))
+		o.maxWindows = maxWindows
+		if o.maxWindows < 1 {
+			o.maxWindows = 1
+		}
+	}
+}
+
+// WithTrustedHashes makes lookups match on the hash alone, without comparing the key. This avoids touching the
+// key data at all, but a lookup of a key that is not in the table will return another key's value if their
+// hashes collide. Only use it if every key you look up is known to be in the table, or if occasional false
+// positives are acceptable.
+func WithTrustedHashes() ReadOption {
+	return func(o *readOptions) {
+		o.trusted = true
+	}
+}
+
+// WithSharedLock makes NewFrom take a shared advisory lock on the file, which is held until the table is
+// closed. Opening fails with ErrLocked if the file is being written by Write.WriteFile, and WriteFile fails
+// with ErrLocked while the table is open. Only readers that ask for the lock are protected.
+func WithSharedLock() ReadOption {
+	return func(o *readOptions) {
+		o.lock = true
+	}
+}
+
+// WithoutMemoryLock maps the table without locking it into memory. Its pages are read in from the file as
+// lookups need them, and may be dropped again when memory is short. Use Read.Lock to lock just the sections
+// that matter most. Without this option the whole table is locked, which must fit within RLIMIT_MEMLOCK.
+func WithoutMemoryLock() ReadOption {
+	return func(o *readOptions) {
+		o.noMemLock = true
+		o.memLockLimit = 0
+	}
+}
+
+// WithMemoryLockLimit locks at most limit bytes of the table into memory rather than all of it. The hashes,
+// fingerprints and key offsets are locked first as every lookup reads them, then the key data, the values and
+// the order and sorted sections, and the last section to fit is locked in part. If locking fails, say because
+// limit is more than RLIMIT_MEMLOCK allows, the table is still opened with what could be locked. Read.Locked
+// reports what was.
+func WithMemoryLockLimit(limit int64) ReadOption {
+	return func(o *readOptions) {
+		o.noMemLock = true
+		o.memLockLimit = limit
+	}
+}
+
+// WithKeepOpen keeps the table's file open until the table is closed, rather than closing it as soon as it is
+// mapped. Use it on platforms where a mapping needs its file descriptor to stay open, or to get at the file with
+// Read.File.
+func WithKeepOpen() ReadOption {
+	return func(o *readOptions) {
+		o.keepOpen = true
+	}
+}
+
+// WithTrace calls fn after every lookup in the table with a description of it, to diagnose why a key is missing
+// or slow to find. fn is called on the goroutine making the lookup, so it must be safe for concurrent use and
+// should be quick. Tables opened without WithTrace only pay for a nil check.
+func WithTrace(fn func(Trace)) ReadOption {
+	return func(o *readOptions) {
+		o.trace = fn
+	}
+}
+
+// WithLogger logs when the table is opened and closed to l, along with the errors if either fails, and when a
+// Reloadable swaps in a new table. The error says so if opening failed because the table couldn't be locked
+// into memory.
+func WithLogger(l *slog.Logger) ReadOption {
+	return func(o *readOptions) {
+		o.logger = l
+	}
+}
+
+// WithWarmProgress calls fn periodically as Warm or WarmRate pulls the table into memory
+func WithWarmProgress(fn func(Progress)) ReadOption {
+	return func(o *readOptions) {
+		o.progress = fn
+	}
+}
+
+// WithValueSize makes opening the table fail with ErrValueSizeMismatch unless its values are size bytes
+func WithValueSize(size int) ReadOption {
+	return func(o *readOptions) {
+		o.valueSize = size
+	}
+}
+
+// WithExpectedSchema makes opening the table fail with ErrSchemaMismatch unless it was built WithSchema and every
+// field of s is in the table's schema with the same type, offset and size. The table's schema may have other
+// fields. If s describes the keys, the table's schema must describe them in the same way.
+func WithExpectedSchema(s Schema) ReadOption {
+	return func(o *readOptions) {
+		o.schema = &s
+	}
+}
+
+// WithValueEncoder sets how MarshalJSON encodes values
+func WithValueEncoder(enc ValueEncoder) ReadOption {
+	return func(o *readOptions) {
+		o.encoder = enc
+	}
+}
@@ -0,0 +1,45 @@
+package statichash
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Overlay presents an ordered list of tables as a single table. Lookups consult the tables in order and return
+// the first match, so a small table of fresh changes can be layered over a large base table without rebuilding
+// the base.
+type Overlay struct {
+	tables []*Read
+}
+
+// NewOverlay creates an Overlay over tables. Put the freshest table first. All the tables must have the same
+// value size.
+func NewOverlay(tables ...*Read) (*Overlay, error) {
+	for _, t := range tables[1:] {
+		if t.valueSize != tables[0].valueSize {
+			return nil, fmt.Errorf("%w: overlay tables have value sizes %d and %d", ErrValueSizeMismatch, tables[0].valueSize, t.valueSize)
+		}
+	}
+	return &Overlay{tables: tables}, nil
+}
+
+// GetPtr gets the value associated with key from the first table that contains it. See table.GetPtr.
+func (o *Overlay) GetPtr(key string) (val unsafe.Pointer, ok bool) {
+	for _, t := range o.tables {
+		if val, ok = t.GetPtr(key); ok {
+			return val, ok
+		}
+	}
+	return nil, false
+}
+
+// Close closes all the tables in the overlay
+func (o *Overlay) Close() error {
+	var firstErr error
+	for _, t := range o.tables {
+		if err := t.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
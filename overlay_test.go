@@ -0,0 +1,26 @@
+package statichash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlay(t *testing.T) {
+	base := readFromMap(t, map[string]int{"a": 1, "b": 2, "c": 3})
+	delta := readFromMap(t, map[string]int{"b": 20, "d": 40})
+
+	o, err := NewOverlay(delta, base)
+	assert.NoError(t, err)
+	defer o.Close()
+
+	for key, exp := range map[string]int{"a": 1, "b": 20, "c": 3, "d": 40} {
+		v, ok := o.GetPtr(key)
+		if assert.True(t, ok, key) {
+			assert.Equal(t, exp, *(*int)(v), key)
+		}
+	}
+
+	_, ok := o.GetPtr("e")
+	assert.False(t, ok)
+}
@@ -0,0 +1,426 @@
+package statichash
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*
+A partitioned file holds a very large table as a number of complete tables, its partitions, in one file. The
+top bits of a key's hash choose its partition, so each partition holds a fraction of the keys and its slot
+indices stay well within the range of an int even if the whole table has billions of entries.
+
+The file is
+
+Partition header - the magic, the number of partitions in 4 bytes, 4 bytes of padding and the value size in 8
+Partition directory - the offset, length and entry count of each partition, in 8 bytes each
+Partitions - each a complete table starting on a sectionAlignment boundary, so it can be mapped on its own
+
+*/
+
+// partitionMagic marks the start of a partitioned file
+var partitionMagic = [8]byte{'S', 'T', 'A', 'T', 'P', 'A', 'R', 'T'}
+
+// partitionHeader is the header of a partitioned file
+type partitionHeader struct {
+	magic [8]byte
+	// partitions is the number of partitions, which is a power of 2
+	partitions uint32
+	valueSize  int64
+}
+
+// partitionEntry is the directory entry for a partition
+type partitionEntry struct {
+	offset int64
+	length int64
+	count  int64
+}
+
+const (
+	// partitionHeaderSize is the size of the header of a partitioned file
+	partitionHeaderSize = 24
+	// partitionEntrySize is the size of a partition's directory entry in the file
+	partitionEntrySize = 24
+)
+
+// encode returns the encoding of the header and directory of a partitioned file
+func (h *partitionHeader) encode(dir []partitionEntry) []byte {
+	b := make([]byte, partitionHeaderSize+len(dir)*partitionEntrySize)
+	copy(b, h.magic[:])
+	fileByteOrder.PutUint32(b[8:], h.partitions)
+	fileByteOrder.PutUint64(b[16:], uint64(h.valueSize))
+	for i, e := range dir {
+		p := b[partitionHeaderSize+i*partitionEntrySize:]
+		fileByteOrder.PutUint64(p, uint64(e.offset))
+		fileByteOrder.PutUint64(p[8:], uint64(e.length))
+		fileByteOrder.PutUint64(p[16:], uint64(e.count))
+	}
+	return b
+}
+
+// decodePartitionHeader decodes the header of a partitioned file from b, which must be partitionHeaderSize bytes
+func decodePartitionHeader(b []byte) partitionHeader {
+	return partitionHeader{
+		magic:      [8]byte(b),
+		partitions: fileByteOrder.Uint32(b[8:]),
+		valueSize:  int64(fileByteOrder.Uint64(b[16:])),
+	}
+}
+
+// decodePartitionDirectory decodes the partition directory entries in b
+func decodePartitionDirectory(b []byte) []partitionEntry {
+	dir := make([]partitionEntry, len(b)/partitionEntrySize)
+	for i := range dir {
+		p := b[i*partitionEntrySize:]
+		dir[i] = partitionEntry{
+			offset: int64(fileByteOrder.Uint64(p)),
+			length: int64(fileByteOrder.Uint64(p[8:])),
+			count:  int64(fileByteOrder.Uint64(p[16:])),
+		}
+	}
+	return dir
+}
+
+// partitionFor returns which of the 1<<bits partitions holds key
+func partitionFor(key string, bits int) int {
+	if bits == 0 {
+		return 0
+	}
+	return int(seededHash(shardSeed, key) >> (64 - bits))
+}
+
+// PartitionedWrite builds a partitioned table. Create one with NewPartitioned, Set every entry, then call
+// Finalize and save it with WriteTo or WriteFile. Open the file with OpenPartitioned.
+type PartitionedWrite struct {
+	parts []*Write
+	bits  int
+}
+
+// NewPartitioned creates a table for numItems entries split into the given number of partitions, which must be a
+// power of 2. Each partition is a Write created with opts, sized for its share of the entries and key data with
+// some allowance for uneven hashing. Partitions with a few million entries each are a reasonable choice.
+func NewPartitioned(numItems int, valueSize, totalKeyLength int64, partitions int, opts ...Option) *PartitionedWrite {
+	if partitions <= 0 || partitions&(partitions-1) != 0 || uint64(partitions) > math.MaxUint32 {
+		panic(fmt.Sprintf("statichash: %d partitions is not a power of 2", partitions))
+	}
+
+	// The number of keys in each partition is binomially distributed, so allow several standard deviations
+	// more than the mean.
+	mean := float64(numItems) / float64(partitions)
+	perPart := int(mean+4*math.Sqrt(mean)) + 16
+	keyLength := int64(float64(totalKeyLength)*float64(perPart)/max(float64(numItems), 1)) + 64
+
+	t := PartitionedWrite{bits: bits.TrailingZeros(uint(partitions))}
+	for range partitions {
+		t.parts = append(t.parts, New(perPart, valueSize, keyLength, opts...))
+	}
+	return &t
+}
+
+// Set sets the value for key in the partition that holds it. See Write.Set.
+func (t *PartitionedWrite) Set(key string, val unsafe.Pointer) error {
+	return t.parts[partitionFor(key, t.bits)].Set(key, val)
+}
+
+// GetPtr gets the value associated with key from the partition that holds it. See table.GetPtr.
+func (t *PartitionedWrite) GetPtr(key string) (unsafe.Pointer, bool) {
+	return t.parts[partitionFor(key, t.bits)].GetPtr(key)
+}
+
+// Len returns the number of entries in all the partitions
+func (t *PartitionedWrite) Len() int {
+	var n int
+	for _, p := range t.parts {
+		n += p.Len()
+	}
+	return n
+}
+
+// Partition returns partition i. Use it to see how each partition was built, or to set options such as the
+// version on each one.
+func (t *PartitionedWrite) Partition(i int) *Write {
+	return t.parts[i]
+}
+
+// Partitions returns the number of partitions
+func (t *PartitionedWrite) Partitions() int {
+	return len(t.parts)
+}
+
+// Finalize finalizes every partition, returning a report for each
+func (t *PartitionedWrite) Finalize() []BuildReport {
+	reports := make([]BuildReport, len(t.parts))
+	for i, p := range t.parts {
+		reports[i] = p.Finalize()
+	}
+	return reports
+}
+
+// directory returns the directory of the partitions when saved
+func (t *PartitionedWrite) directory() []partitionEntry {
+	dir := make([]partitionEntry, len(t.parts))
+	offset := int64(partitionHeaderSize + len(dir)*partitionEntrySize)
+	for i, p := range t.parts {
+		offset = roundUp(offset, sectionAlignment)
+		dir[i] = partitionEntry{offset: offset, length: p.FileLen(), count: int64(p.count)}
+		offset += dir[i].length
+	}
+	return dir
+}
+
+// WriteTo writes the partitioned table to w. Every partition must have been finalized, otherwise WriteTo returns
+// ErrNotFinalized.
+func (t *PartitionedWrite) WriteTo(w io.Writer) (int64, error) {
+	for _, p := range t.parts {
+		if !p.finalized {
+			return 0, ErrNotFinalized
+		}
+	}
+
+	h := partitionHeader{
+		magic:      partitionMagic,
+		partitions: uint32(len(t.parts)),
+		valueSize:  int64(t.parts[0].valueSize),
+	}
+	dir := t.directory()
+
+	n, err := w.Write(h.encode(dir))
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	zeros := make([]byte, sectionAlignment)
+	for i, p := range t.parts {
+		n, err := w.Write(zeros[:dir[i].offset-written])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+		m, err := p.WriteTo(w)
+		written += m
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// WriteFile saves the partitioned table to the named file, taking an exclusive lock on it while writing as
+// Write.WriteFile does
+func (t *PartitionedWrite) WriteFile(filename string) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f, true); err != nil {
+		return fmt.Errorf("locking %s: %w", filename, err)
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if _, err := t.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Partitioned is a partitioned table opened with OpenPartitioned. Only the header and directory are read when
+// it is opened. Each partition is mapped into memory the first time it is needed, so a process that only looks
+// up some keys only maps the partitions that hold them.
+type Partitioned struct {
+	f         *os.File
+	opts      readOptions
+	bits      int
+	valueSize int
+	dir       []partitionEntry
+
+	// parts holds each partition once it is mapped. mu is held while mapping one.
+	parts []atomic.Pointer[Read]
+	mu    sync.Mutex
+	// err is the first error mapping a partition in a lookup
+	err error
+}
+
+// OpenPartitioned opens a partitioned table written by PartitionedWrite. opts are applied to each partition as
+// it is mapped. WithWindowedMapping and WithSharedLock have no effect.
+func OpenPartitioned(filename string, opts ...ReadOption) (*Partitioned, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	t, err := partitionedFrom(f, opts)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	return t, nil
+}
+
+// partitionedFrom reads the header and directory of the partitioned table in f
+func partitionedFrom(f *os.File, opts []ReadOption) (*Partitioned, error) {
+	fileLength, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, partitionHeaderSize)
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: file is only %d bytes long", ErrTruncated, fileLength)
+		}
+		return nil, err
+	}
+	h := decodePartitionHeader(buf)
+	if h.magic != partitionMagic {
+		return nil, ErrBadMagic
+	}
+	if h.partitions == 0 || h.partitions&(h.partitions-1) != 0 {
+		return nil, fmt.Errorf("%w: %d partitions is not a power of 2", ErrCorrupt, h.partitions)
+	}
+
+	buf = make([]byte, int(h.partitions)*partitionEntrySize)
+	if _, err := f.ReadAt(buf, partitionHeaderSize); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: file is too short for the directory of %d partitions", ErrTruncated, h.partitions)
+		}
+		return nil, err
+	}
+	dir := decodePartitionDirectory(buf)
+	for i, e := range dir {
+		if e.offset%sectionAlignment != 0 || e.length <= 0 || e.offset+e.length > fileLength || e.count < 0 {
+			return nil, fmt.Errorf("%w: partition %d at %d for %d bytes is not within the file", ErrCorrupt, i, e.offset, e.length)
+		}
+	}
+
+	return &Partitioned{
+		f:         f,
+		opts:      newReadOptions(opts),
+		bits:      bits.TrailingZeros32(h.partitions),
+		valueSize: int(h.valueSize),
+		dir:       dir,
+		parts:     make([]atomic.Pointer[Read], len(dir)),
+	}, nil
+}
+
+// Partition returns partition i, mapping it into memory if it isn't already
+func (t *Partitioned) Partition(i int) (*Read, error) {
+	if r := t.parts[i].Load(); r != nil {
+		return r, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r := t.parts[i].Load(); r != nil {
+		return r, nil
+	}
+	if t.f == nil {
+		return nil, fmt.Errorf("partitioned table is closed")
+	}
+
+	e := t.dir[i]
+	if err := checkMappable(e.length); err != nil {
+		return nil, fmt.Errorf("partition %d: %w", i, err)
+	}
+	data, err := t.opts.mapMemoryAt(t.f.Fd(), e.offset, int(e.length))
+	if err != nil {
+		return nil, fmt.Errorf("mapping partition %d: %w", i, err)
+	}
+	r, err := newFromData(data)
+	if err == nil && r.valueSize != t.valueSize {
+		err = fmt.Errorf("%w: partition has values of %d bytes, not %d", ErrValueSizeMismatch, r.valueSize, t.valueSize)
+	}
+	if err != nil {
+		unmap(data)
+		return nil, fmt.Errorf("partition %d: %w", i, err)
+	}
+	r.mapped = true
+	if err := r.apply(&t.opts); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("partition %d: %w", i, err)
+	}
+	t.parts[i].Store(r)
+	return r, nil
+}
+
+// Partitions returns the number of partitions
+func (t *Partitioned) Partitions() int {
+	return len(t.dir)
+}
+
+// PartitionFor returns the partition that holds key
+func (t *Partitioned) PartitionFor(key string) int {
+	return partitionFor(key, t.bits)
+}
+
+// GetPtr gets the value associated with key from the partition that holds it, mapping the partition if
+// necessary. See table.GetPtr. If the partition can't be mapped the key is reported as not found, and the
+// error is returned by Err.
+func (t *Partitioned) GetPtr(key string) (unsafe.Pointer, bool) {
+	r, err := t.Partition(partitionFor(key, t.bits))
+	if err != nil {
+		t.mu.Lock()
+		if t.err == nil {
+			t.err = err
+		}
+		t.mu.Unlock()
+		return nil, false
+	}
+	return r.GetPtr(key)
+}
+
+// Err returns the first error mapping a partition during a GetPtr
+func (t *Partitioned) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Len returns the number of entries in all the partitions. It doesn't need to map them.
+func (t *Partitioned) Len() int {
+	var n int
+	for _, e := range t.dir {
+		n += int(e.count)
+	}
+	return n
+}
+
+// ValueSize returns the size of each value in bytes
+func (t *Partitioned) ValueSize() int {
+	return t.valueSize
+}
+
+// Close unmaps every partition that has been mapped and closes the file
+func (t *Partitioned) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var firstErr error
+	for i := range t.parts {
+		if r := t.parts[i].Swap(nil); r != nil {
+			if err := r.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if t.f != nil {
+		if err := t.f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		t.f = nil
+	}
+	return firstErr
+}
@@ -0,0 +1,77 @@
+package statichash
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitioned(t *testing.T) {
+	const n = 10000
+	tb := NewPartitioned(n, 8, n*10, 8, WithSeed(1))
+	for i := 0; i < n; i++ {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&i)))
+	}
+	assert.Equal(t, n, tb.Len())
+	var total int
+	for i, report := range tb.Finalize() {
+		// The keys are spread roughly evenly
+		assert.InDelta(t, n/8, report.Entries, n/32, i)
+		total += report.Entries
+	}
+	assert.Equal(t, n, total)
+
+	name := filepath.Join(t.TempDir(), "table")
+	assert.NoError(t, tb.WriteFile(name))
+
+	r, err := OpenPartitioned(name)
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, 8, r.Partitions())
+	assert.Equal(t, n, r.Len())
+	assert.Equal(t, 8, r.ValueSize())
+
+	// Looking up a key maps only its partition
+	v, ok := r.GetPtr("key42")
+	if assert.True(t, ok) {
+		assert.Equal(t, 42, *(*int)(v))
+	}
+	for i := range r.parts {
+		assert.Equal(t, i == r.PartitionFor("key42"), r.parts[i].Load() != nil, i)
+	}
+	// The partition is opened as a mapped table, so the options apply to it as they would to one opened with NewFrom
+	p, err := r.Partition(r.PartitionFor("key42"))
+	assert.NoError(t, err)
+	assert.True(t, p.mapped)
+	assert.NotEmpty(t, p.Locked())
+
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key%d", i)
+		v, ok := r.GetPtr(key)
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+		p, err := r.Partition(r.PartitionFor(key))
+		assert.NoError(t, err)
+		_, ok = p.GetPtr(key)
+		assert.True(t, ok)
+	}
+	_, ok = r.GetPtr("missing")
+	assert.False(t, ok)
+	assert.NoError(t, r.Err())
+}
+
+func TestPartitionedErrors(t *testing.T) {
+	assert.Panics(t, func() { NewPartitioned(10, 8, 100, 3) })
+
+	tb := NewPartitioned(10, 8, 100, 2)
+	_, err := tb.WriteTo(nil)
+	assert.ErrorIs(t, err, ErrNotFinalized)
+
+	// An ordinary table isn't a partitioned one
+	_, err = OpenPartitioned(writeTempTable(t, buildTable(t, 10)))
+	assert.ErrorIs(t, err, ErrBadMagic)
+}
@@ -0,0 +1,219 @@
+package statichash
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+/*
+A patch is
+
+Magic - "SHPATCH1"
+Header - uvarints: number of slots, value size, total key length, flags and seed of the new table
+Records - an op byte followed by a uvarint key length and the key. Set records are followed by the value
+End - the op byte patchEnd
+
+*/
+
+var patchMagic = [8]byte{'S', 'H', 'P', 'A', 'T', 'C', 'H', '1'}
+
+const (
+	patchSet    = 's'
+	patchDelete = 'd'
+	patchEnd    = 'e'
+	// maxPatchSlotsPerEntry limits the number of slots ApplyPatch gives the new table for each entry it could
+	// have. Tables are rarely more than half empty, so this only affects patches that are corrupt.
+	maxPatchSlotsPerEntry = 8
+)
+
+// WritePatch writes a patch to w that ApplyPatch can use to turn table a into table b. The patch contains only
+// the keys that differ between the tables, so it is much smaller than b when few entries have changed.
+func WritePatch(w io.Writer, a, b *Read) error {
+	if a.valueSize != b.valueSize {
+		return fmt.Errorf("%w: cannot patch between tables with value sizes %d and %d", ErrValueSizeMismatch, a.valueSize, b.valueSize)
+	}
+	if (a.flags|b.flags)&flagStringValues != 0 {
+		return errors.New("patches between string tables are not supported")
+	}
+	for _, t := range []*Read{a, b} {
+		if err := t.needKeys("patch"); err != nil {
+			return err
+		}
+	}
+	if !slices.Equal(a.columns, b.columns) {
+		// The patch takes the columns from the table it is applied to
+		return fmt.Errorf("cannot patch between tables with columns %v and %v", a.columns, b.columns)
+	}
+
+	var keyLength int
+	it := b.Iterate()
+	for it.Next() {
+		keyLength += len(it.Key())
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(patchMagic[:])
+	writeUvarint(bw, uint64(b.numItems))
+	writeUvarint(bw, uint64(b.valueSize))
+	writeUvarint(bw, uint64(keyLength))
+	writeUvarint(bw, uint64(b.flags))
+	writeUvarint(bw, b.seed)
+
+	if err := Diff(a, b, func(kind DiffKind, key string) bool {
+		if kind == Removed {
+			bw.WriteByte(patchDelete)
+		} else {
+			bw.WriteByte(patchSet)
+		}
+		writeUvarint(bw, uint64(len(key)))
+		bw.WriteString(key)
+		if kind != Removed {
+			index, _ := b.find(key, b.hashKey(key))
+			bw.Write(b.value(index))
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+
+	bw.WriteByte(patchEnd)
+	return bw.Flush()
+}
+
+// ApplyPatch applies a patch written by WritePatch to base, returning the new table ready to be saved with
+// WriteTo. The result has the same keys and values as the table the patch was made from, but entries may not
+// be in the same slots, so the file is not necessarily byte-for-byte identical.
+func ApplyPatch(base *Read, patch io.Reader) (*Write, error) {
+	if err := base.needKeys("patch"); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(patch)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, fmt.Errorf("reading patch magic: %w", err)
+	}
+	if magic != patchMagic {
+		return nil, fmt.Errorf("%w: not a statichash patch", ErrBadMagic)
+	}
+
+	var hdr [5]uint64
+	for i := range hdr {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading patch header: %w", err)
+		}
+		hdr[i] = v
+	}
+	if hdr[1] != uint64(base.valueSize) {
+		return nil, fmt.Errorf("%w: patch value size %d does not match table value size %d", ErrValueSizeMismatch, hdr[1], base.valueSize)
+	}
+	valueSize, flags, seed := base.valueSize, int64(hdr[3]), hdr[4]
+	if err := checkPatchFlags(base, hdr[3]); err != nil {
+		return nil, err
+	}
+	if base.flags&flagStringValues != 0 {
+		return nil, errors.New("patches to string tables are not supported")
+	}
+
+	// The patch is expected to be small, so we read all of it before touching the base table
+	type op struct {
+		key   string
+		value []byte
+	}
+	var sets []op
+	var keyBytes int64
+	changed := make(map[string]struct{})
+	for {
+		code, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("reading patch record: %w", err)
+		}
+		if code == patchEnd {
+			break
+		}
+		if code != patchSet && code != patchDelete {
+			return nil, fmt.Errorf("%w: unexpected patch record type %q", ErrCorrupt, code)
+		}
+
+		l, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading patch record: %w", err)
+		}
+		// The length may be corrupt, so we let the data we actually read set how much we allocate
+		key, err := io.ReadAll(io.LimitReader(r, int64(min(l, math.MaxInt64))))
+		if err != nil {
+			return nil, fmt.Errorf("reading patch record: %w", err)
+		}
+		if uint64(len(key)) != l {
+			return nil, fmt.Errorf("reading patch record: %w", io.ErrUnexpectedEOF)
+		}
+		keyBytes += int64(len(key))
+		changed[string(key)] = struct{}{}
+		if code == patchSet {
+			value := make([]byte, valueSize)
+			if _, err := io.ReadFull(r, value); err != nil {
+				return nil, fmt.Errorf("reading patch record: %w", err)
+			}
+			sets = append(sets, op{key: string(key), value: value})
+		}
+	}
+
+	// The sizes in the header are hints, and a corrupt patch could ask for a huge table, so we don't make the
+	// table larger than its entries could need
+	numItems := int(max(min(hdr[0], uint64(maxPatchSlotsPerEntry*(base.count+len(sets)+1))), 1))
+	keyLength := int64(min(hdr[2], uint64(base.usedKeyData())+uint64(keyBytes)))
+	if err := checkSize(int64(numItems), int64(valueSize)); err != nil {
+		return nil, err
+	}
+
+	t := New(numItems, int64(valueSize), keyLength, (&table{flags: flags, seed: seed, columns: base.columns}).options()...)
+	it := base.Iterate()
+	for it.Next() {
+		key := it.Key()
+		if _, ok := changed[key]; ok {
+			continue
+		}
+		if err := t.Set(key, it.Value()); err != nil {
+			return nil, err
+		}
+	}
+	for _, op := range sets {
+		if err := t.Set(op.key, bytesPointer(op.value)); err != nil {
+			return nil, err
+		}
+	}
+	t.Finalize()
+
+	return t, nil
+}
+
+func writeUvarint(w *bufio.Writer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+// checkPatchFlags returns an error if flags from a patch header describe a table that can't be built from base
+func checkPatchFlags(base *Read, flags uint64) error {
+	switch {
+	case flags&^knownFlags != 0:
+		return fmt.Errorf("%w: patch has unknown flags %#x", ErrCorrupt, flags&^knownFlags)
+	case flags&flagCuckoo != 0 && flags&flagInsertionOrder != 0,
+		flags&flagHopscotch != 0 && flags&(flagCuckoo|flagInsertionOrder) != 0,
+		flags&flagHashOnly != 0 && flags&(flagCuckoo|flagSortedIndex) != 0:
+		return fmt.Errorf("%w: patch has an impossible combination of flags %#x", ErrCorrupt, flags)
+	case flags&flagInlineValues != 0 && (flags&(flagInterleaved|flagColumnar) != flagInterleaved || base.valueSize > maxInlineValueSize):
+		return fmt.Errorf("%w: patch asks for inline values of %d bytes with flags %#x", ErrCorrupt, base.valueSize, flags)
+	case flags&(flagStringValues|flagInlineStrings) != 0:
+		return errors.New("patches to string tables are not supported")
+	case (flags&flagColumnar != 0) != (base.columns != nil):
+		// The new table takes its columns from base
+		return fmt.Errorf("%w: patch and table disagree about whether values are in columns", ErrCorrupt)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package statichash
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatch(t *testing.T) {
+	a := readFromMap(t, map[string]int{"a": 1, "b": 2, "c": 3, "d": 4})
+	b := readFromMap(t, map[string]int{"a": 1, "b": 20, "d": 4, "e": 5, "f": 6})
+
+	var patch bytes.Buffer
+	assert.NoError(t, WritePatch(&patch, a, b))
+
+	w, err := ApplyPatch(a, &patch)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, w.Len())
+
+	var out bytes.Buffer
+	_, err = w.WriteTo(&out)
+	assert.NoError(t, err)
+	c, err := NewFromBytes(out.Bytes())
+	assert.NoError(t, err)
+
+	assert.NoError(t, Diff(b, c, func(kind DiffKind, key string) bool {
+		t.Errorf("unexpected difference %s %s", kind, key)
+		return true
+	}))
+}
+
+func TestPatchBadMagic(t *testing.T) {
+	a := readFromMap(t, map[string]int{"a": 1})
+	_, err := ApplyPatch(a, bytes.NewReader([]byte("NOTAPATCH")))
+	assert.ErrorIs(t, err, ErrBadMagic)
+}
+
+func TestPatchCorrupt(t *testing.T) {
+	a := readFromMap(t, map[string]int{"a": 1})
+	valueSize := uint64(a.valueSize)
+
+	_, err := ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{16, valueSize, 10, 1 << 40, 0}, patchEnd)))
+	assert.ErrorIs(t, err, ErrCorrupt)
+	_, err = ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{16, valueSize, 10, flagCuckoo | flagInsertionOrder, 0}, patchEnd)))
+	assert.ErrorIs(t, err, ErrCorrupt)
+	_, err = ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{16, valueSize, 10, flagColumnar, 0}, patchEnd)))
+	assert.ErrorIs(t, err, ErrCorrupt)
+	_, err = ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{16, valueSize, 10, flagInlineStrings, 0}, patchEnd)))
+	assert.EqualError(t, err, "patches to string tables are not supported")
+	_, err = ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{16, valueSize, 10, flagInlineValues, 0}, patchEnd)))
+	assert.ErrorIs(t, err, ErrCorrupt)
+	_, err = ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{16, valueSize, 10, flagHashOnly | flagSortedIndex, 0}, patchEnd)))
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	// Values this wide don't fit in the slot records
+	wide := wideTable(t)
+	_, err = ApplyPatch(wide, bytes.NewReader(craftPatch([5]uint64{16, uint64(wide.valueSize), 10, flagInterleaved | flagInlineValues, 0}, patchEnd)))
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	// A huge key length is only a problem if the key is really there
+	_, err = ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{16, valueSize, 10, 0, 0}, patchDelete, 0xff, 0xff, 0xff, 0xff, 0x0f, 'a')))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// Sizes larger than the entries could need are ignored
+	w, err := ApplyPatch(a, bytes.NewReader(craftPatch([5]uint64{1 << 50, valueSize, 1 << 50, 0, 0}, patchDelete, 1, 'a', patchEnd)))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, w.Len())
+	assert.LessOrEqual(t, w.numItems, 16)
+}
+
+func FuzzApplyPatch(f *testing.F) {
+	a := readFromMap(f, map[string]int{"a": 1, "b": 2, "c": 3, "d": 4})
+	b := readFromMap(f, map[string]int{"a": 1, "b": 20, "d": 4, "e": 5, "f": 6})
+	var patch bytes.Buffer
+	if err := WritePatch(&patch, a, b); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(patch.Bytes())
+	f.Add(craftPatch([5]uint64{16, uint64(a.valueSize), 10, flagInlineStrings, 0}, patchEnd))
+	f.Add(craftPatch([5]uint64{16, uint64(a.valueSize), 10, flagHashOnly | flagCuckoo, 0}, patchEnd))
+	wide := wideTable(f)
+	f.Add(craftPatch([5]uint64{16, uint64(wide.valueSize), 10, flagInterleaved | flagInlineValues, 0}, patchEnd))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ApplyPatch(a, bytes.NewReader(data))
+		ApplyPatch(wide, bytes.NewReader(data))
+	})
+}
+
+// craftPatch returns a patch with the given header and records, which needn't make sense
+func craftPatch(hdr [5]uint64, records ...byte) []byte {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	bw.Write(patchMagic[:])
+	for _, v := range hdr {
+		writeUvarint(bw, v)
+	}
+	bw.Write(records)
+	bw.Flush()
+	return buf.Bytes()
+}
+
+// wideTable returns a table whose values are too big to be stored inline
+func wideTable(t testing.TB) *Read {
+	tb, err := FromMapT(map[string][3]int64{"a": {1, 2, 3}, "b": {4, 5, 6}})
+	assert.NoError(t, err)
+	return readBack(t, tb)
+}
@@ -0,0 +1,113 @@
+// Package plenctable stores values that contain pointers, such as strings, slices, maps and nested structs, in
+// statichash tables. The values are encoded with plenc (github.com/philpearl/plenc) and stored alongside the
+// keys, as the values of a StringTable are, so opening a table still only maps the file and lookups still only
+// touch the index and the entries they find. Each Get decodes the value it returns.
+//
+// The fields of T need plenc tags, as plenc.Marshal requires.
+package plenctable
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/philpearl/plenc"
+	"github.com/philpearl/statichash"
+)
+
+// Table is a table whose values are of type T, encoded with plenc. Create one to write with New, or open one
+// written earlier with Open or FromBytes.
+type Table[T any] struct {
+	s *statichash.StringTable
+	// buf is reused to encode values
+	buf []byte
+}
+
+// New creates a Table for writing. totalLength is the total length of all the keys and encoded values.
+func New[T any](numItems int, totalLength int64, opts ...statichash.Option) *Table[T] {
+	return &Table[T]{s: statichash.NewStringTable(numItems, totalLength, opts...)}
+}
+
+// Open opens a Table saved to filename
+func Open[T any](filename string, opts ...statichash.ReadOption) (*Table[T], error) {
+	s, err := statichash.OpenStringTable(filename, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Table[T]{s: s}, nil
+}
+
+// FromBytes is like Open, but reads the table from data, as statichash.NewFromBytes does
+func FromBytes[T any](data []byte, opts ...statichash.ReadOption) (*Table[T], error) {
+	s, err := statichash.StringTableFromBytes(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Table[T]{s: s}, nil
+}
+
+// Set encodes value and sets it as the value for key. It returns statichash.ErrReadOnly if the table was read
+// from a file.
+func (t *Table[T]) Set(key string, value T) error {
+	buf, err := plenc.Marshal(t.buf[:0], &value)
+	if err != nil {
+		return fmt.Errorf("encoding value for key %q: %w", key, err)
+	}
+	t.buf = buf
+	return t.s.Set(key, unsafe.String(unsafe.SliceData(buf), len(buf)))
+}
+
+// Get decodes the value for key. ok is false if the key isn't in the table. Get must not be called after the
+// table is closed.
+func (t *Table[T]) Get(key string) (value T, ok bool, err error) {
+	s, ok := t.s.Get(key)
+	if !ok {
+		return value, false, nil
+	}
+	if err := decode(key, s, &value); err != nil {
+		return value, true, err
+	}
+	return value, true, nil
+}
+
+// Len returns the number of entries in the table
+func (t *Table[T]) Len() int {
+	return t.s.Len()
+}
+
+// Range decodes each entry in the table and calls f with it, in the order Iterate visits them, until f returns
+// false. It stops and returns the error if a value can't be decoded.
+func (t *Table[T]) Range(f func(key string, value T) bool) error {
+	var err error
+	t.s.Range(func(key, s string) bool {
+		var value T
+		if err = decode(key, s, &value); err != nil {
+			return false
+		}
+		return f(key, value)
+	})
+	return err
+}
+
+// decode decodes the encoded value s of key into value
+func decode[T any](key, s string, value *T) error {
+	if err := plenc.Unmarshal(unsafe.Slice(unsafe.StringData(s), len(s)), value); err != nil {
+		return fmt.Errorf("decoding value for key %q: %w", key, err)
+	}
+	return nil
+}
+
+// Finalize seals a table created with New so it can be saved
+func (t *Table[T]) Finalize() statichash.BuildReport {
+	return t.s.Finalize()
+}
+
+// WriteTo saves a table created with New once it has been finalized
+func (t *Table[T]) WriteTo(w io.Writer) (int64, error) {
+	return t.s.WriteTo(w)
+}
+
+// Close releases the resources of a table that was read from a file
+func (t *Table[T]) Close() error {
+	return t.s.Close()
+}
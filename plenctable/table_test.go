@@ -0,0 +1,82 @@
+package plenctable
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/philpearl/statichash"
+	"github.com/stretchr/testify/assert"
+)
+
+type value struct {
+	Name  string            `plenc:"1"`
+	Tags  []string          `plenc:"2"`
+	Attrs map[string]string `plenc:"3"`
+	Next  *value            `plenc:"4"`
+}
+
+func TestTable(t *testing.T) {
+	tb := New[value](100, 20000, statichash.WithSeed(1))
+	for i := 0; i < 100; i++ {
+		v := value{
+			Name:  fmt.Sprintf("name %d", i),
+			Tags:  []string{"a", fmt.Sprint(i)},
+			Attrs: map[string]string{"i": fmt.Sprint(i)},
+			Next:  &value{Name: "next"},
+		}
+		assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), v))
+	}
+	assert.Equal(t, 100, tb.Len())
+	tb.Finalize()
+
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "table")
+	assert.NoError(t, os.WriteFile(filename, buf.Bytes(), 0o644))
+
+	r, err := Open[value](filename)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	for i := 0; i < 100; i++ {
+		v, ok, err := r.Get(fmt.Sprintf("key%d", i))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("name %d", i), v.Name)
+		assert.Equal(t, []string{"a", fmt.Sprint(i)}, v.Tags)
+		assert.Equal(t, fmt.Sprint(i), v.Attrs["i"])
+		if assert.NotNil(t, v.Next) {
+			assert.Equal(t, "next", v.Next.Name)
+		}
+	}
+	_, ok, err := r.Get("missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	b, err := FromBytes[value](buf.Bytes())
+	assert.NoError(t, err)
+	var count int
+	assert.NoError(t, b.Range(func(key string, v value) bool {
+		count++
+		assert.Equal(t, "name "+key[3:], v.Name)
+		return true
+	}))
+	assert.Equal(t, 100, count)
+
+	assert.ErrorIs(t, r.Set("key", value{}), statichash.ErrReadOnly)
+}
+
+func TestNotStringTable(t *testing.T) {
+	w := statichash.New(10, 8, 100)
+	w.Finalize()
+	var buf bytes.Buffer
+	_, err := w.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	_, err = FromBytes[value](buf.Bytes())
+	assert.ErrorIs(t, err, statichash.ErrNotStringTable)
+}
@@ -0,0 +1,46 @@
+package statichash
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// AssertPointerFree returns an error wrapping ErrHasPointers if values of type T contain pointers, maps, slices,
+// strings, funcs or anything else that holds a pointer, and so can't be saved in a table. The error gives the
+// path to the first offending field, such as "main.value.Inner.Names[]". Call it from init or a test for each
+// type you store, so that adding a string to a value type is caught before any tables are written.
+func AssertPointerFree[T any]() error {
+	return checkPointerFree(reflect.TypeFor[T]())
+}
+
+// checkPointerFree returns an error wrapping ErrHasPointers if values of type typ contain any pointers, and
+// so can't be copied into a table. The error names the path to the first offending field.
+func checkPointerFree(typ reflect.Type) error {
+	if path, bad := findPointer(typ, typ.String()); bad != nil {
+		return fmt.Errorf("%w: %s is a %s", ErrHasPointers, path, bad)
+	}
+	return nil
+}
+
+// findPointer walks typ looking for anything that contains a pointer. It returns the path to it and its type,
+// or a nil type if there's nothing
+func findPointer(typ reflect.Type, path string) (string, reflect.Type) {
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return "", nil
+	case reflect.Array:
+		return findPointer(typ.Elem(), path+"[]")
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if p, bad := findPointer(f.Type, path+"."+f.Name); bad != nil {
+				return p, bad
+			}
+		}
+		return "", nil
+	}
+	// Pointers, strings, slices, maps, channels, funcs, interfaces & unsafe.Pointer
+	return path, typ
+}
@@ -0,0 +1,68 @@
+package statichash
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetValue(t *testing.T) {
+	type value struct {
+		A int32
+		B [4]byte
+	}
+	tb := New(10, int64(unsafe.Sizeof(value{})), 100)
+
+	assert.NoError(t, tb.SetValue("a", value{A: 1, B: [4]byte{1}}))
+	v := value{A: 2}
+	assert.NoError(t, tb.SetValue("b", &v))
+
+	p, ok := tb.GetPtr("a")
+	assert.True(t, ok)
+	assert.Equal(t, value{A: 1, B: [4]byte{1}}, *(*value)(p))
+	p, ok = tb.GetPtr("b")
+	assert.True(t, ok)
+	assert.Equal(t, value{A: 2}, *(*value)(p))
+
+	assert.ErrorIs(t, tb.SetValue("c", struct {
+		A int32
+		S string
+	}{}), ErrHasPointers)
+	assert.ErrorIs(t, tb.SetValue("c", int32(1)), ErrValueSizeMismatch)
+	assert.ErrorIs(t, tb.SetValue("c", nil), ErrHasPointers)
+	assert.ErrorIs(t, tb.SetValue("c", (*value)(nil)), ErrHasPointers)
+	assert.Equal(t, 2, tb.Len())
+}
+
+func TestAssertPointerFree(t *testing.T) {
+	type inner struct {
+		X     int
+		Names [2]string
+	}
+	type value struct {
+		A     float64
+		Inner inner
+	}
+	type ok struct {
+		A [3]complex64
+		B struct{ C uint8 }
+	}
+
+	assert.NoError(t, AssertPointerFree[ok]())
+	assert.NoError(t, AssertPointerFree[int]())
+
+	err := AssertPointerFree[value]()
+	assert.ErrorIs(t, err, ErrHasPointers)
+	assert.EqualError(t, err, "statichash: value type contains pointers: statichash.value.Inner.Names[] is a string")
+
+	for _, err := range []error{
+		AssertPointerFree[*int](),
+		AssertPointerFree[map[string]int](),
+		AssertPointerFree[[]byte](),
+		AssertPointerFree[func()](),
+		AssertPointerFree[struct{ E any }](),
+	} {
+		assert.ErrorIs(t, err, ErrHasPointers)
+	}
+}
@@ -0,0 +1,24 @@
+package statichash
+
+import "sync"
+
+// Pool keeps Write tables for reuse, so that building many tables doesn't allocate a new arena each time.
+// The zero Pool is ready to use, and a Pool is safe for concurrent use.
+type Pool struct {
+	pool sync.Pool
+}
+
+// Get returns an empty table as if created by New, reusing a table from the pool if there is one
+func (p *Pool) Get(numItems int, valueSize, totalKeyLength int64, opts ...Option) *Write {
+	t, _ := p.pool.Get().(*Write)
+	if t == nil {
+		return New(numItems, valueSize, totalKeyLength, opts...)
+	}
+	t.Reset(numItems, valueSize, totalKeyLength, opts...)
+	return t
+}
+
+// Put returns a table to the pool once you have finished with it. Don't use the table after calling Put.
+func (p *Pool) Put(t *Write) {
+	p.pool.Put(t)
+}
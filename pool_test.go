@@ -0,0 +1,58 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReset(t *testing.T) {
+	tb := buildTable(t, 100)
+	arena := unsafe.SliceData(tb.arena)
+
+	tb.Reset(10, 8, 100, WithInsertionOrder())
+	assert.Equal(t, arena, unsafe.SliceData(tb.arena))
+	assert.Equal(t, 0, tb.Len())
+	assert.Equal(t, 16, tb.NumSlots())
+	_, ok := tb.GetPtr("key1")
+	assert.False(t, ok)
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, tb.Set(fmt.Sprintf("new%d", i), unsafe.Pointer(&i)))
+	}
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	var fresh bytes.Buffer
+	nt := New(10, 8, 100, WithInsertionOrder())
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, nt.Set(fmt.Sprintf("new%d", i), unsafe.Pointer(&i)))
+	}
+	nt.Finalize()
+	_, err = nt.WriteTo(&fresh)
+	assert.NoError(t, err)
+	// Everything but the creation time in the header should match
+	assert.Equal(t, fresh.Bytes()[headerSize:], buf.Bytes()[headerSize:])
+
+	tb.Reset(1000, 8, 10000)
+	assert.NotEqual(t, arena, unsafe.SliceData(tb.arena))
+	assert.Equal(t, 1024, tb.NumSlots())
+}
+
+func TestPool(t *testing.T) {
+	var p Pool
+	tb := p.Get(10, 8, 100)
+	assert.Equal(t, 16, tb.NumSlots())
+	tb.Set("a", unsafe.Pointer(&tb))
+	p.Put(tb)
+
+	tb = p.Get(4, 8, 100, WithFingerprints())
+	assert.Equal(t, 0, tb.Len())
+	assert.Equal(t, 4, tb.NumSlots())
+	assert.NotNil(t, tb.fingerprints)
+}
@@ -0,0 +1,66 @@
+package statichash
+
+import (
+	"os"
+	"unsafe"
+)
+
+// Prefetch asks the kernel to start reading in the pages a lookup of key will need, without waiting for them.
+// If the caller knows which keys it will look up a little before it needs their values, calling Prefetch first
+// hides some or all of the page fault latency. It covers the hash, fingerprint, key offset and value of the
+// key's home slot, which is where a lookup starts. The key data can't be covered, as finding it means reading
+// the slot, and that could block. Prefetch does nothing for tables that aren't mapped from a file in one piece.
+func (r *Read) Prefetch(key string) {
+	if !r.mapped || r.win != nil || r.numItems == 0 {
+		return
+	}
+	h := r.hashKey(key)
+	if r.flags&flagCuckoo != 0 {
+		first, second := r.cuckooSlots(key, slotHash(h))
+		r.prefetchSlot(first)
+		r.prefetchSlot(second)
+		return
+	}
+	r.prefetchSlot(int(slotHash(h)) & (r.numItems - 1))
+}
+
+// prefetchSlot advises the kernel we'll need the pages holding slot index
+func (r *Read) prefetchSlot(index int) {
+	if r.slots != nil {
+		// This covers the value too if it is inline
+		r.prefetch(r.layout.hashes+int64(index*r.slotSize), int64(r.slotSize))
+		if r.flags&flagInlineValues != 0 {
+			return
+		}
+	} else {
+		r.prefetchSlotArrays(index)
+	}
+	if r.columns != nil {
+		for c, w := range r.columns {
+			r.prefetch(r.layout.values+int64(r.columnOffset(c, index)), int64(w))
+		}
+		return
+	}
+	r.prefetch(r.layout.values+int64(index*r.valueSize), int64(r.valueSize))
+}
+
+// prefetchSlotArrays advises the kernel we'll need the hash, fingerprint and key offset of slot index, which are
+// in separate sections unless the slots are interleaved
+func (r *Read) prefetchSlotArrays(index int) {
+	r.prefetch(r.layout.hashes+int64(index)*int64(unsafe.Sizeof(hash(0))), int64(unsafe.Sizeof(hash(0))))
+	if r.fingerprints != nil {
+		r.prefetch(r.layout.fingerprints+int64(index), 1)
+	}
+	r.prefetch(r.layout.keys+int64(index)*int64(unsafe.Sizeof(keyOffset(0))), int64(unsafe.Sizeof(keyOffset(0))))
+}
+
+// prefetch advises the kernel we'll need the pages holding the n bytes at offset in the file. The mapping
+// starts on a page boundary, so rounding the offset down to a page boundary gives an address madvise accepts.
+func (r *Read) prefetch(offset, n int64) {
+	if n == 0 {
+		return
+	}
+	start := offset &^ int64(os.Getpagesize()-1)
+	// This is only a hint, so we don't mind if it fails
+	adviseWillNeed(r.data[start:min(offset+n, int64(len(r.data)))])
+}
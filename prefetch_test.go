@@ -0,0 +1,38 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefetch(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithFingerprints()}, {WithCuckoo()}, {WithColumns(4, 4)}, {WithPageAlignedSections()}, {WithInterleavedSlots()}} {
+		tb := buildTable(t, 1000, opts...)
+		tb.Finalize()
+		var buf bytes.Buffer
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		name := filepath.Join(t.TempDir(), "table")
+		assert.NoError(t, os.WriteFile(name, buf.Bytes(), 0o644))
+
+		r, err := NewFrom(name)
+		assert.NoError(t, err)
+		for i := 0; i < 1100; i++ {
+			key := fmt.Sprintf("key%d", i)
+			r.Prefetch(key)
+			_, ok := r.GetPtr(key)
+			assert.Equal(t, i >= 1 && i <= 1000, ok, key)
+		}
+		assert.NoError(t, r.Close())
+
+		// Tables that aren't mapped are already in memory
+		r, err = NewFromBytes(buf.Bytes())
+		assert.NoError(t, err)
+		r.Prefetch("key1")
+	}
+}
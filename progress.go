@@ -0,0 +1,42 @@
+package statichash
+
+import "fmt"
+
+// Stage identifies the operation a Progress report is for
+type Stage int
+
+const (
+	// StageIngest is adding entries to a table with Set. Progress is counted in entries.
+	StageIngest Stage = iota + 1
+	// StageWrite is saving a table with WriteTo. Progress is counted in bytes.
+	StageWrite
+	// StageWarm is pulling a table into memory with Warm. Progress is counted in bytes.
+	StageWarm
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageIngest:
+		return "ingest"
+	case StageWrite:
+		return "write"
+	case StageWarm:
+		return "warm"
+	}
+	return fmt.Sprintf("Stage(%d)", int(s))
+}
+
+// Progress reports how far a long operation has got
+type Progress struct {
+	Stage Stage
+	// Done is the number of entries or bytes processed so far, out of Total. For StageIngest Total is the
+	// number of items the table was created for, so Done can exceed it.
+	Done, Total int64
+}
+
+const (
+	// progressEntries is how many new entries Set adds between progress reports
+	progressEntries = 1 << 16
+	// progressBytes is how many bytes WriteTo writes between progress reports
+	progressBytes = 1 << 20
+)
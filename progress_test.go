@@ -0,0 +1,50 @@
+package statichash
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	var reports []Progress
+	record := func(p Progress) { reports = append(reports, p) }
+
+	const n = progressEntries + 10
+	tb := New(n, 8, 10*n, WithProgress(record))
+	for i := 0; i < n; i++ {
+		assert.NoError(t, tb.Set(strconv.Itoa(i), unsafe.Pointer(&i)))
+	}
+	// Overwriting doesn't add entries
+	assert.NoError(t, tb.Set("0", unsafe.Pointer(&tb)))
+	assert.Equal(t, []Progress{{Stage: StageIngest, Done: progressEntries, Total: n}}, reports)
+	tb.Finalize()
+
+	reports = nil
+	var buf bytes.Buffer
+	written, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Greater(t, len(reports), 1)
+	for i, p := range reports {
+		assert.Equal(t, StageWrite, p.Stage)
+		assert.Equal(t, written, p.Total)
+		if i > 0 {
+			assert.Greater(t, p.Done, reports[i-1].Done)
+		}
+	}
+	assert.Equal(t, written, reports[len(reports)-1].Done)
+
+	reports = nil
+	r, err := NewFromBytes(buf.Bytes(), WithWarmProgress(record))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Warm(context.Background()))
+	if assert.NotEmpty(t, reports) {
+		last := reports[len(reports)-1]
+		assert.Equal(t, Progress{Stage: StageWarm, Done: written, Total: written}, last)
+	}
+	assert.Equal(t, "warm", StageWarm.String())
+}
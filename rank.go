@@ -0,0 +1,37 @@
+package statichash
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// Rank returns the position of key among the table's keys in sorted order, counting from 0, and whether the
+// key is in the table. If it isn't, the position is where it would go. Together with Select this lets the table
+// be used as a static ordered map, or joined by position against arrays kept in key order.
+//
+// Rank is a binary search of the keys in order. For a table built WithSortedIndex that order is read from the
+// file. Otherwise it is worked out the first time it is needed, which takes a while for a large table, and
+// kept once the table is finalized. A table built WithHashOnly has no keys to order, so Rank panics.
+func (t *table) Rank(key string) (int, bool) {
+	slots := t.keyOrder("rank")
+	i := sort.Search(len(slots), func(i int) bool {
+		return t.getKey(t.keyAt(int(slots[i]))) >= key
+	})
+	return i, i < len(slots) && t.getKey(t.keyAt(int(slots[i]))) == key
+}
+
+// Select returns the key and a pointer to the value at position i among the table's keys in sorted order, so
+// Select(Rank(key)) returns key. It panics if i is not less than Len, or if the table was built WithHashOnly.
+func (t *table) Select(i int) (key string, value unsafe.Pointer) {
+	slot := int(t.keyOrder("select from")[i])
+	return t.getKey(t.keyAt(slot)), t.valuePtr(slot)
+}
+
+// keyOrder returns the occupied slots in key order for op, panicking if the table has no keys
+func (t *table) keyOrder(op string) []slotIndex {
+	if err := t.needKeys(op); err != nil {
+		// The error already starts with the package name
+		panic(err.Error())
+	}
+	return t.sortedSlots()
+}
@@ -0,0 +1,69 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankSelect(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithSortedIndex()}} {
+		tb := buildTable(t, 100, opts...)
+		tb.Finalize()
+		var buf bytes.Buffer
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		r, err := NewFromBytes(buf.Bytes())
+		assert.NoError(t, err)
+
+		var keys []string
+		for i := 1; i <= 100; i++ {
+			keys = append(keys, fmt.Sprintf("key%d", i))
+		}
+		sort.Strings(keys)
+
+		for i, key := range keys {
+			rank, ok := r.Rank(key)
+			assert.True(t, ok)
+			assert.Equal(t, i, rank)
+
+			k, v := r.Select(i)
+			assert.Equal(t, key, k)
+			var n int
+			fmt.Sscanf(key, "key%d", &n)
+			assert.Equal(t, 100-n, *(*int)(v))
+		}
+
+		// Missing keys rank where they would go
+		rank, ok := r.Rank("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, rank)
+		rank, ok = r.Rank("key10a")
+		assert.False(t, ok)
+		assert.Equal(t, 3, rank)
+		rank, ok = r.Rank("z")
+		assert.False(t, ok)
+		assert.Equal(t, 100, rank)
+
+		assert.Panics(t, func() { r.Select(100) })
+	}
+}
+
+func TestRankFinalized(t *testing.T) {
+	tb := buildTable(t, 100)
+	tb.Finalize()
+	rank, ok := tb.Rank("key1")
+	assert.True(t, ok)
+	assert.Equal(t, 0, rank)
+	// The order is sorted once and kept
+	first := tb.sortedSlots()
+	assert.Same(t, &first[0], &tb.sortedSlots()[0])
+
+	hashOnly := buildTable(t, 100, WithHashOnly())
+	hashOnly.Finalize()
+	assert.PanicsWithValue(t, "statichash: table has no keys: can't rank a table built WithHashOnly", func() { hashOnly.Rank("key1") })
+	assert.Panics(t, func() { hashOnly.Select(0) })
+}
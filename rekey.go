@@ -0,0 +1,62 @@
+package statichash
+
+import "fmt"
+
+// Rekey builds a new table holding the entries of r under new keys. fn returns the new key for each old key,
+// for example strings.ToLower. Entries are added in the order Iterate visits them, and the new table has the
+// same options as r plus any in opts.
+//
+// If fn maps two old keys to the same new key, onCollision is called with the new key, the old key whose value
+// is currently kept and the old key just visited. It returns true to keep the value of the second, false to keep
+// the first, or an error to stop. If onCollision is nil any collision is an error wrapping ErrKeyCollision.
+func Rekey(r *Read, fn func(key string) string, onCollision func(newKey, first, second string) (bool, error), opts ...Option) (*Write, error) {
+	if err := r.needKeys("rekey"); err != nil {
+		return nil, err
+	}
+	type entry struct {
+		oldKey, newKey string
+		index          int
+	}
+	entries := make([]entry, 0, r.count)
+	var keyLength int64
+	for it := r.Iterate(); it.Next(); {
+		e := entry{oldKey: it.Key(), index: it.index}
+		e.newKey = fn(e.oldKey)
+		entries = append(entries, e)
+		keyLength += int64(len(e.newKey))
+		if r.flags&flagStringValues != 0 {
+			keyLength += int64(len(r.stringValue(it.index)))
+		}
+	}
+
+	w := New(len(entries), int64(r.valueSize), keyLength, append(r.options(), opts...)...)
+	// kept records the old key whose value is stored under each new key
+	kept := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if prev, ok := kept[e.newKey]; ok {
+			if onCollision == nil {
+				return nil, fmt.Errorf("%w: %q and %q both become %q", ErrKeyCollision, prev, e.oldKey, e.newKey)
+			}
+			keep, err := onCollision(e.newKey, prev, e.oldKey)
+			if err != nil {
+				return nil, err
+			}
+			if !keep {
+				continue
+			}
+		}
+		kept[e.newKey] = e.oldKey
+
+		var err error
+		if r.flags&flagStringValues != 0 {
+			err = w.SetString(e.newKey, r.stringValue(e.index))
+		} else {
+			err = w.Set(e.newKey, r.valuePtr(e.index))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.Finalize()
+	return w, nil
+}
@@ -0,0 +1,81 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRekey(t *testing.T) {
+	tb := buildTable(t, 10, WithInsertionOrder())
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	w, err := Rekey(r, strings.ToUpper, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, w.Len())
+	for i := 0; i < 10; i++ {
+		v, ok := w.GetPtr(fmt.Sprintf("KEY%d", 10-i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+	it := w.Iterate()
+	assert.True(t, it.Next())
+	assert.Equal(t, "KEY10", it.Key())
+
+	// Every key collides
+	prefix := func(key string) string { return key[:3] }
+	_, err = Rekey(r, prefix, nil)
+	assert.ErrorIs(t, err, ErrKeyCollision)
+
+	for _, keepSecond := range []bool{true, false} {
+		var collisions []string
+		w, err = Rekey(r, prefix, func(newKey, first, second string) (bool, error) {
+			collisions = append(collisions, newKey+":"+first+","+second)
+			return keepSecond, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, w.Len())
+		assert.Len(t, collisions, 9)
+		v, _ := w.GetPtr("key")
+		if keepSecond {
+			assert.Equal(t, "key:key10,key9", collisions[0])
+			assert.Equal(t, "key:key2,key1", collisions[8])
+			assert.Equal(t, 9, *(*int)(v))
+		} else {
+			assert.Equal(t, "key:key10,key1", collisions[8])
+			assert.Equal(t, 0, *(*int)(v))
+		}
+	}
+
+	_, err = Rekey(r, prefix, func(newKey, first, second string) (bool, error) {
+		return false, fmt.Errorf("no")
+	})
+	assert.EqualError(t, err, "no")
+}
+
+func TestRekeyStrings(t *testing.T) {
+	st := NewStringTable(3, 100)
+	assert.NoError(t, st.Set("a:1", "one"))
+	assert.NoError(t, st.Set("a:2", "two"))
+	st.Finalize()
+	var buf bytes.Buffer
+	_, err := st.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	w, err := Rekey(r, func(key string) string { return strings.TrimPrefix(key, "a:") }, nil)
+	assert.NoError(t, err)
+	v, ok := w.GetString("2")
+	assert.True(t, ok)
+	assert.Equal(t, "two", v)
+}
@@ -0,0 +1,115 @@
+package statichash
+
+import (
+	"sync/atomic"
+)
+
+// Reloadable holds a table that can be replaced by a new version of the file while it is in use, for refreshing
+// data without downtime. Lookups are made through View, which holds on to the current generation of the table
+// until it returns. Reload maps the new file and swaps it in, and the old generation is closed once the last
+// View using it returns. A Reloadable is safe for concurrent use.
+type Reloadable struct {
+	current atomic.Pointer[generation]
+	opts    []ReadOption
+	o       readOptions
+}
+
+// generation is one version of the table held by a Reloadable
+type generation struct {
+	r *Read
+	// refs counts the Views using the table, plus one for the Reloadable while it is current. The table is closed
+	// when it drops to zero.
+	refs atomic.Int64
+}
+
+// OpenReloadable opens the table file filename with NewFrom. opts are also used to open the files given to
+// Reload.
+func OpenReloadable(filename string, opts ...ReadOption) (*Reloadable, error) {
+	r, err := NewFrom(filename, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rl := Reloadable{opts: opts, o: newReadOptions(opts)}
+	rl.current.Store(newGeneration(r))
+	return &rl, nil
+}
+
+func newGeneration(r *Read) *generation {
+	g := generation{r: r}
+	g.refs.Store(1)
+	return &g
+}
+
+// Reload opens the table file filename and swaps it in for the current table. Views that start after Reload
+// returns see the new table. The old table is closed once the Views using it have returned. If filename can't be
+// opened the current table is kept.
+func (rl *Reloadable) Reload(filename string) error {
+	r, err := NewFrom(filename, rl.opts...)
+	if err != nil {
+		return err
+	}
+	old := rl.current.Swap(newGeneration(r))
+	if rl.o.logger != nil {
+		rl.o.logger.Info("reloaded table", "file", filename, "entries", r.Len())
+	}
+	if old != nil {
+		old.release()
+	}
+	return nil
+}
+
+// View calls fn with the current table. The table stays open until fn returns, even if Reload is called, but
+// nothing read from it may be used after that.
+func (rl *Reloadable) View(fn func(r *Read)) {
+	g := rl.acquire()
+	defer g.release()
+	fn(g.r)
+}
+
+// GetValue returns a copy of the value for key in the current table, and whether the key was found
+func (rl *Reloadable) GetValue(key string) (value []byte, ok bool) {
+	rl.View(func(r *Read) {
+		var v []byte
+		if v, ok = r.GetValue(key); ok {
+			value = append([]byte(nil), v...)
+		}
+	})
+	return value, ok
+}
+
+// Len returns the number of entries in the current table
+func (rl *Reloadable) Len() (n int) {
+	rl.View(func(r *Read) { n = r.Len() })
+	return n
+}
+
+// Close releases the current table. It is closed straight away if no Views are using it, and otherwise once they
+// have returned. The Reloadable can't be used after Close.
+func (rl *Reloadable) Close() error {
+	if g := rl.current.Swap(nil); g != nil {
+		return g.release()
+	}
+	return nil
+}
+
+// acquire takes a reference to the current generation
+func (rl *Reloadable) acquire() *generation {
+	for {
+		g := rl.current.Load()
+		if g == nil {
+			panic("statichash: Reloadable used after Close")
+		}
+		// A generation whose count has reached zero has been replaced and closed, so we load the new one
+		if n := g.refs.Load(); n > 0 && g.refs.CompareAndSwap(n, n+1) {
+			return g
+		}
+	}
+}
+
+// release drops a reference to the generation, closing its table if it was the last
+func (g *generation) release() error {
+	if g.refs.Add(-1) == 0 {
+		return g.r.Close()
+	}
+	return nil
+}
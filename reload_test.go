@@ -0,0 +1,105 @@
+package statichash
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadable(t *testing.T) {
+	dir := t.TempDir()
+	// write saves a table of n keys, each with the value gen
+	write := func(name string, n, gen int) string {
+		filename := filepath.Join(dir, name)
+		tb := New(n, int64(unsafe.Sizeof(gen)), int64(n*10))
+		for i := 0; i < n; i++ {
+			assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&gen)))
+		}
+		tb.Finalize()
+		assert.NoError(t, tb.WriteFile(filename))
+		return filename
+	}
+	first := write("first", 10, 1)
+	second := write("second", 20, 2)
+
+	rl, err := OpenReloadable(first)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, rl.Len())
+
+	var old *Read
+	rl.View(func(r *Read) {
+		old = r
+		// The table being viewed stays open while we reload
+		assert.NoError(t, rl.Reload(second))
+		v, ok := r.GetPtr("key3")
+		assert.True(t, ok)
+		assert.Equal(t, 1, *(*int)(v))
+		assert.NotNil(t, old.data)
+	})
+	// Now nothing is using it, the old table is closed
+	assert.Nil(t, old.data)
+
+	assert.Equal(t, 20, rl.Len())
+	v, ok := rl.GetValue("key15")
+	assert.True(t, ok)
+	assert.Equal(t, 2, *(*int)(unsafe.Pointer(&v[0])))
+
+	// A failed reload keeps the current table
+	assert.Error(t, rl.Reload(filepath.Join(dir, "missing")))
+	assert.Equal(t, 20, rl.Len())
+
+	assert.NoError(t, rl.Close())
+	assert.Panics(t, func() { rl.Len() })
+}
+
+func TestReloadableConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for gen := range 4 {
+		filename := filepath.Join(dir, fmt.Sprint(gen))
+		tb := New(100, int64(unsafe.Sizeof(gen)), 1000)
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&gen)))
+		}
+		tb.Finalize()
+		assert.NoError(t, tb.WriteFile(filename))
+		files = append(files, filename)
+	}
+
+	rl, err := OpenReloadable(files[0])
+	assert.NoError(t, err)
+	defer rl.Close()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				rl.View(func(r *Read) {
+					// Every key in a generation has the same value
+					a, _ := r.GetPtr("key1")
+					b, _ := r.GetPtr("key99")
+					if *(*int)(a) != *(*int)(b) {
+						t.Error("values from different generations")
+					}
+				})
+			}
+		}()
+	}
+	for i := range 100 {
+		assert.NoError(t, rl.Reload(files[i%len(files)]))
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,431 @@
+// Package safe reads statichash table files without using package unsafe or making any system calls of its own.
+// It is for environments where unsafe code has to be audited or is forbidden. The file is read into an ordinary
+// heap buffer rather than mapped, every field is decoded with encoding/binary, and values are copied out of the
+// table. It is much slower to open and somewhat slower to query than statichash itself, and it can only read
+// tables.
+//
+// Tables built without WithSeed are hashed with aeshash, which can't be computed without unsafe, so only
+// seeded tables can be read.
+package safe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+)
+
+var (
+	// ErrBadMagic means the data is not a table file
+	ErrBadMagic = errors.New("safe: bad magic number")
+	// ErrUnsupportedFormat means the data is a table file this package can't read
+	ErrUnsupportedFormat = errors.New("safe: unsupported file format")
+	// ErrCorrupt means the table file is inconsistent or truncated
+	ErrCorrupt = errors.New("safe: data corrupt")
+	// ErrNotSeeded means the table was built without WithSeed, so its hash can't be computed safely
+	ErrNotSeeded = errors.New("safe: table is not seeded")
+)
+
+// These mirror the header flags in statichash's file.go
+const (
+	flagInsertionOrder = 1 << iota
+	flagSeeded
+	flagFingerprints
+	flagSortedIndex
+	flagStringValues
+	flagColumnar
+	flagPageAligned
+	flagCuckoo
+	flagHopscotch
+	flagInterleaved
+	flagInlineValues
+	flagInlineStrings
+	flagHashOnly
+
+	// knownFlags has every flag this package understands
+	knownFlags = flagHashOnly<<1 - 1
+)
+
+const (
+	// currentFormat is the newest file format this package reads
+	currentFormat = 3
+	// minHeaderSize is the size of the smallest header, that of format 1. Format 0 files have no magic number
+	// and are never seeded, so this package doesn't read them.
+	minHeaderSize = 128
+	// maxColumns is the number of column widths in the header
+	maxColumns = 16
+	// sectionAlignment is where sections start in a table built WithPageAlignedSections
+	sectionAlignment = 64 << 10
+	// maxSlots is the most slots a table can have
+	maxSlots = 1 << 32
+)
+
+var fileMagic = []byte("STATHASH")
+
+// Table is a read-only table loaded from a file written by statichash
+type Table struct {
+	data []byte
+
+	numItems   int
+	valueSize  int
+	count      int
+	flags      int64
+	seed       uint64
+	cuckooSeed uint64
+	maxProbe   int
+	columns    []int
+
+	// These are the offsets of the sections within data. If the slots are interleaved, hashes, fingerprints
+	// and keys are the offsets of the fields of the first slot record.
+	hashes       int
+	fingerprints int
+	keys         int
+	values       int
+	keyData      int
+	// hashStride, fingerprintStride, keyStride and valueStride are the distances between the fields of
+	// consecutive slots
+	hashStride        int
+	fingerprintStride int
+	keyStride         int
+	valueStride       int
+}
+
+// Open reads the table file filename into memory
+func Open(filename string) (*Table, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	t, err := New(data)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	return t, nil
+}
+
+// New reads the table in data, which must be the contents of a table file. The table refers to data, which
+// must not be changed while it is in use.
+func New(data []byte) (*Table, error) {
+	t := Table{data: data}
+	if err := t.readHeader(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// field reads the 8 byte field at offset in the header
+func (t *Table) field(offset int) int64 {
+	return int64(binary.LittleEndian.Uint64(t.data[offset:]))
+}
+
+// readHeader decodes the header and works out where the sections are
+func (t *Table) readHeader() error {
+	if len(t.data) < minHeaderSize {
+		return fmt.Errorf("%w: data is only %d bytes long", ErrCorrupt, len(t.data))
+	}
+	if !bytes.Equal(t.data[:8], fileMagic) {
+		return ErrBadMagic
+	}
+	if format := binary.LittleEndian.Uint32(t.data[8:]); format > currentFormat {
+		return fmt.Errorf("%w: file format version %d is newer than this package supports (%d)", ErrUnsupportedFormat, format, currentFormat)
+	}
+
+	// base is the offset of numItems
+	base, headerSize := 16, int(binary.LittleEndian.Uint32(t.data[12:]))
+	if headerSize < minHeaderSize || headerSize%8 != 0 || headerSize > len(t.data) {
+		return fmt.Errorf("%w: header size %d", ErrCorrupt, headerSize)
+	}
+	if headerSize >= 144 {
+		t.maxProbe = int(binary.LittleEndian.Uint32(t.data[140:]))
+	}
+	if headerSize >= 152 {
+		t.cuckooSeed = uint64(t.field(144))
+	}
+
+	numItems := t.field(base)
+	valueSize := t.field(base + 8)
+	count := t.field(base + 16)
+	t.flags = t.field(base + 24)
+	t.seed = uint64(t.field(base + 32))
+
+	if t.flags&^knownFlags != 0 {
+		return fmt.Errorf("%w: unknown flags %#x", ErrUnsupportedFormat, t.flags&^knownFlags)
+	}
+	if t.flags&flagSeeded == 0 {
+		return ErrNotSeeded
+	}
+	// Each slot takes at least 4 bytes for its hash
+	if numItems <= 0 || numItems&(numItems-1) != 0 || numItems > maxSlots || numItems > int64(len(t.data))/4 {
+		return fmt.Errorf("%w: %d slots", ErrCorrupt, numItems)
+	}
+	if hi, lo := bits.Mul64(uint64(numItems), uint64(valueSize)); valueSize < 0 || hi != 0 || lo > uint64(len(t.data)) {
+		return fmt.Errorf("%w: %d values of %d bytes", ErrCorrupt, numItems, valueSize)
+	}
+	if count < 0 || count > numItems {
+		return fmt.Errorf("%w: %d entries in %d slots", ErrCorrupt, count, numItems)
+	}
+	t.numItems, t.valueSize, t.count = int(numItems), int(valueSize), int(count)
+
+	if t.flags&flagColumnar != 0 {
+		var width int
+		for c := range maxColumns {
+			if w := int(binary.LittleEndian.Uint16(t.data[base+80+2*c:])); w != 0 {
+				t.columns = append(t.columns, w)
+				width += w
+			}
+		}
+		if width != t.valueSize {
+			return fmt.Errorf("%w: columns are %d bytes wide, but the value size is %d", ErrCorrupt, width, t.valueSize)
+		}
+	}
+
+	t.layout(int64(headerSize))
+	if t.keyData > len(t.data) {
+		return fmt.Errorf("%w: file is %d bytes but the sections need at least %d", ErrCorrupt, len(t.data), t.keyData)
+	}
+	return nil
+}
+
+// layout works out where each section starts, as statichash's offsetsFrom does
+func (t *Table) layout(headerSize int64) {
+	n := int64(t.numItems)
+	start := func(offset int64) int64 {
+		if t.flags&flagPageAligned != 0 {
+			return roundUp(offset, sectionAlignment)
+		}
+		return offset
+	}
+
+	hashes := start(headerSize)
+	var fingerprints, keys, order int64
+	if t.flags&flagInterleaved != 0 {
+		// Each slot is a 16-byte record of its hash, fingerprint, padding and key offset, followed by the value
+		// padded to 8 bytes if the values are inline
+		stride := int64(16)
+		if t.flags&flagInlineValues != 0 {
+			stride += roundUp(int64(t.valueSize), 8)
+		}
+		fingerprints, keys, order = hashes+4, hashes+8, start(hashes+stride*n)
+		t.hashStride, t.fingerprintStride, t.keyStride = int(stride), int(stride), int(stride)
+	} else {
+		fingerprints = start(hashes + 4*n)
+		keys = fingerprints
+		if t.flags&flagFingerprints != 0 {
+			keys += n
+		}
+		keys = start(roundUp(keys, 8))
+		order = start(keys + 8*n)
+		t.hashStride, t.fingerprintStride, t.keyStride = 4, 1, 8
+	}
+	sorted := order
+	if t.flags&flagInsertionOrder != 0 {
+		sorted += 8 * n
+	}
+	sorted = start(sorted)
+	values := sorted
+	if t.flags&flagSortedIndex != 0 {
+		values += 8 * n
+	}
+	values = start(values)
+	keyData := start(values + int64(t.valueSize)*n)
+	t.valueStride = t.valueSize
+	if t.flags&flagInlineValues != 0 {
+		// The values section is empty, and each value follows the record of its slot
+		keyData = values
+		values = hashes + 16
+		t.valueStride = t.hashStride
+	}
+
+	t.hashes, t.fingerprints, t.keys, t.values, t.keyData = int(hashes), int(fingerprints), int(keys), int(values), int(keyData)
+}
+
+func roundUp(offset, align int64) int64 {
+	return (offset + align - 1) &^ (align - 1)
+}
+
+// Len returns the number of entries in the table
+func (t *Table) Len() int {
+	return t.count
+}
+
+// ValueSize returns the size of each value in bytes
+func (t *Table) ValueSize() int {
+	return t.valueSize
+}
+
+// Get returns a copy of the value for key, and whether the key was found
+func (t *Table) Get(key string) ([]byte, bool) {
+	index, ok := t.find(key)
+	if !ok {
+		return nil, false
+	}
+	return t.value(index), true
+}
+
+// GetString returns the string value for key from a table built with SetString or SetBytes, and whether the key
+// was found
+func (t *Table) GetString(key string) (string, bool) {
+	if t.valueSize != 8 && t.flags&flagInlineStrings == 0 {
+		return "", false
+	}
+	index, ok := t.find(key)
+	if !ok {
+		return "", false
+	}
+	value := t.value(index)
+	if t.flags&flagInlineStrings != 0 {
+		// Short strings are stored in the value, with their length in its last byte
+		if n := int(value[len(value)-1]); n != 0xff {
+			if n >= len(value) {
+				return "", false
+			}
+			return string(value[:n]), true
+		}
+	}
+	s, ok := t.key(int64(binary.LittleEndian.Uint64(value)))
+	return s, ok
+}
+
+// Range calls fn with each key and a copy of its value in slot order, until fn returns false. The keys are
+// empty if the table was built WithHashOnly.
+func (t *Table) Range(fn func(key string, value []byte) bool) {
+	for i := range t.numItems {
+		if t.slotHash(i) == 0 {
+			continue
+		}
+		var key string
+		if t.flags&flagHashOnly == 0 {
+			var ok bool
+			if key, ok = t.key(t.keyOffset(i)); !ok {
+				continue
+			}
+		}
+		if !fn(key, t.value(i)) {
+			return
+		}
+	}
+}
+
+// find returns the slot holding key
+func (t *Table) find(key string) (int, bool) {
+	h := seededHash(t.seed, key)
+	hashVal := uint32(h)
+	if hashVal == 0 {
+		hashVal = 1
+	}
+	fp := uint8(h >> 32)
+	mask := t.numItems - 1
+
+	if t.flags&flagCuckoo != 0 {
+		if first := int(hashVal) & mask; t.matches(first, key, h, hashVal, fp) {
+			return first, true
+		}
+		if second := int(seededHash(t.cuckooSeed, key)) & mask; t.matches(second, key, h, hashVal, fp) {
+			return second, true
+		}
+		return -1, false
+	}
+
+	cursor := int(hashVal) & mask
+	for probes := 1; t.slotHash(cursor) != 0; probes++ {
+		if t.matches(cursor, key, h, hashVal, fp) {
+			return cursor, true
+		}
+		if probes == t.maxProbe || probes == t.numItems {
+			break
+		}
+		cursor = (cursor + 1) & mask
+	}
+	return -1, false
+}
+
+// matches returns true if slot i holds key, whose full hash is h, slot hash hashVal and fingerprint fp
+func (t *Table) matches(i int, key string, h uint64, hashVal uint32, fp uint8) bool {
+	if t.slotHash(i) != hashVal {
+		return false
+	}
+	if t.flags&(flagFingerprints|flagInterleaved) != 0 && t.data[t.fingerprints+t.fingerprintStride*i] != fp {
+		return false
+	}
+	if t.flags&flagHashOnly != 0 {
+		// The slot holds the full hash rather than the offset of the key
+		return uint64(t.keyOffset(i)) == h
+	}
+	k, ok := t.keyBytes(t.keyOffset(i))
+	return ok && string(k) == key
+}
+
+func (t *Table) slotHash(i int) uint32 {
+	return binary.LittleEndian.Uint32(t.data[t.hashes+t.hashStride*i:])
+}
+
+func (t *Table) keyOffset(i int) int64 {
+	return int64(binary.LittleEndian.Uint64(t.data[t.keys+t.keyStride*i:]))
+}
+
+// keyBytes returns the bytes of the key or string at offset in the key data. It returns false if they aren't
+// within the data.
+func (t *Table) keyBytes(offset int64) ([]byte, bool) {
+	keyData := t.data[t.keyData:]
+	if offset < 0 || offset >= int64(len(keyData)) {
+		return nil, false
+	}
+	l, n := binary.Varint(keyData[offset:])
+	if n <= 0 || l < 0 || l > int64(len(keyData))-offset-int64(n) {
+		return nil, false
+	}
+	start := offset + int64(n)
+	return keyData[start : start+l], true
+}
+
+// key is keyBytes as a string
+func (t *Table) key(offset int64) (string, bool) {
+	b, ok := t.keyBytes(offset)
+	return string(b), ok
+}
+
+// value returns a copy of the value in slot i
+func (t *Table) value(i int) []byte {
+	v := make([]byte, t.valueSize)
+	if t.columns == nil {
+		copy(v, t.data[t.values+i*t.valueStride:])
+		return v
+	}
+	// Each column is stored in its own array
+	var start int
+	for _, w := range t.columns {
+		copy(v[start:start+w], t.data[t.values+t.numItems*start+i*w:])
+		start += w
+	}
+	return v
+}
+
+// seededHash is statichash's hash for tables built WithSeed
+func seededHash(seed uint64, key string) uint64 {
+	const (
+		prime1 = 0xa0761d6478bd642f
+		prime2 = 0xe7037ed1a0b428db
+		prime3 = 0x8ebc6af09c88c6e3
+		prime4 = 0x589965cc75374cc3
+	)
+	h := seed ^ prime1 ^ (uint64(len(key)) * prime2)
+	for len(key) >= 8 {
+		h = mix(h^binary.LittleEndian.Uint64([]byte(key[:8])), prime3)
+		key = key[8:]
+	}
+
+	var tail uint64
+	for i := 0; i < len(key); i++ {
+		tail |= uint64(key[i]) << (8 * uint(i))
+	}
+	h = mix(h^tail, prime4)
+
+	return mix(h, prime1)
+}
+
+func mix(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
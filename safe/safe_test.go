@@ -0,0 +1,147 @@
+package safe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/philpearl/statichash"
+	"github.com/stretchr/testify/assert"
+)
+
+// build returns the contents of a file holding a table of n keys built with opts. The value of each key is its
+// index.
+func build(t *testing.T, n int, opts ...statichash.Option) []byte {
+	tb := statichash.New(n, 8, int64(n*10), opts...)
+	for i := 0; i < n; i++ {
+		assert.NoError(t, tb.SetValue(fmt.Sprintf("key%d", i), int64(i)))
+	}
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestSafe(t *testing.T) {
+	const n = 1000
+	tests := []struct {
+		name string
+		opts []statichash.Option
+	}{
+		{name: "plain"},
+		{name: "fingerprints", opts: []statichash.Option{statichash.WithFingerprints()}},
+		{name: "ordered", opts: []statichash.Option{statichash.WithInsertionOrder(), statichash.WithSortedIndex()}},
+		{name: "columns", opts: []statichash.Option{statichash.WithColumns(2, 4, 2)}},
+		{name: "page aligned", opts: []statichash.Option{statichash.WithPageAlignedSections()}},
+		{name: "cuckoo", opts: []statichash.Option{statichash.WithCuckoo()}},
+		{name: "hopscotch", opts: []statichash.Option{statichash.WithHopscotch()}},
+		{name: "interleaved", opts: []statichash.Option{statichash.WithInterleavedSlots()}},
+		{name: "interleaved page aligned", opts: []statichash.Option{statichash.WithInterleavedSlots(), statichash.WithPageAlignedSections()}},
+		{name: "inline values", opts: []statichash.Option{statichash.WithInlineValues(), statichash.WithInsertionOrder()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb, err := New(build(t, n, append(test.opts, statichash.WithSeed(7))...))
+			assert.NoError(t, err)
+			assert.Equal(t, n, tb.Len())
+			assert.Equal(t, 8, tb.ValueSize())
+
+			for i := 0; i < n; i++ {
+				v, ok := tb.Get(fmt.Sprintf("key%d", i))
+				if assert.True(t, ok) {
+					assert.Equal(t, uint64(i), binary.NativeEndian.Uint64(v))
+				}
+			}
+			_, ok := tb.Get("missing")
+			assert.False(t, ok)
+
+			seen := make(map[string]uint64)
+			tb.Range(func(key string, value []byte) bool {
+				seen[key] = binary.NativeEndian.Uint64(value)
+				return true
+			})
+			assert.Len(t, seen, n)
+			assert.Equal(t, uint64(17), seen["key17"])
+		})
+	}
+}
+
+func TestSafeHashOnly(t *testing.T) {
+	const n = 1000
+	tb, err := New(build(t, n, statichash.WithSeed(7), statichash.WithHashOnly()))
+	assert.NoError(t, err)
+	for i := 0; i < n; i++ {
+		v, ok := tb.Get(fmt.Sprintf("key%d", i))
+		if assert.True(t, ok) {
+			assert.Equal(t, uint64(i), binary.NativeEndian.Uint64(v))
+		}
+	}
+	_, ok := tb.Get("missing")
+	assert.False(t, ok)
+
+	var count int
+	tb.Range(func(key string, value []byte) bool {
+		assert.Empty(t, key)
+		count++
+		return true
+	})
+	assert.Equal(t, n, count)
+}
+
+func TestSafeStrings(t *testing.T) {
+	value := func(i int) string {
+		if i%2 == 0 {
+			return fmt.Sprintf("value %d", i)
+		}
+		return fmt.Sprintf("a value too long to be stored inline %d", i)
+	}
+	for _, test := range []struct {
+		name string
+		tb   *statichash.Write
+	}{
+		{name: "offsets", tb: statichash.New(10, 8, 500, statichash.WithSeed(1))},
+		{name: "inline", tb: statichash.New(10, 16, 500, statichash.WithSeed(1), statichash.WithInlineStrings(15))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tb := test.tb
+			for i := 0; i < 10; i++ {
+				assert.NoError(t, tb.SetString(fmt.Sprintf("key%d", i), value(i)))
+			}
+			tb.Finalize()
+			filename := filepath.Join(t.TempDir(), "strings")
+			assert.NoError(t, tb.WriteFile(filename))
+
+			r, err := Open(filename)
+			assert.NoError(t, err)
+			for i := 0; i < 10; i++ {
+				v, ok := r.GetString(fmt.Sprintf("key%d", i))
+				assert.True(t, ok)
+				assert.Equal(t, value(i), v)
+			}
+			_, ok := r.GetString("missing")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestSafeErrors(t *testing.T) {
+	_, err := New(build(t, 10))
+	assert.ErrorIs(t, err, ErrNotSeeded)
+
+	data := build(t, 10, statichash.WithSeed(1))
+	_, err = New(data[:100])
+	assert.ErrorIs(t, err, ErrCorrupt)
+	_, err = New(data[:len(data)/2])
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	bad := append([]byte("NOTAHASH"), data[8:]...)
+	_, err = New(bad)
+	assert.ErrorIs(t, err, ErrBadMagic)
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,162 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// Salvage recovers what it can from the bytes of a truncated or partly corrupt table file. Every entry whose
+// slot, key and value are all present, and whose stored hash matches its key, is copied into a new finalized
+// table with the same options. lost is the number of entries the header says the file held that could not be
+// recovered, or zero for the oldest files, whose header doesn't record it. The entries of a table built
+// WithInsertionOrder are recovered in slot order.
+//
+// Salvage returns an error if the header itself is unusable, or if the table was built WithHashOnly, as then
+// there are no keys to check the hashes against.
+func Salvage(data []byte) (t *Write, lost int, err error) {
+	h, err := readHeader(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Check everything but the length by pretending the file is complete
+	if err := h.validate(h.offsets().keyData); err != nil {
+		return nil, 0, err
+	}
+	if h.flags&flagHashOnly != 0 {
+		return nil, 0, fmt.Errorf("%w: can't salvage a table built WithHashOnly", ErrNoKeys)
+	}
+
+	// We use a table for its hashing and column arithmetic, but its sections are not set as they may be
+	// beyond the end of the data
+	src := readTable(&h, int64(len(data)))
+	l := src.layout
+	length := int64(len(data))
+
+	type entry struct {
+		key   string
+		value []byte
+	}
+	var entries []entry
+	var keyLength int64
+	for i := 0; i < src.numItems; i++ {
+		hashAt, keyAt := src.slotFields(i)
+		if hashAt+int64(unsafe.Sizeof(hash(0))) > length || keyAt+int64(unsafe.Sizeof(keyOffset(0))) > length {
+			break
+		}
+		hv := hash(fileByteOrder.Uint32(data[hashAt:]))
+		if hv == 0 {
+			continue
+		}
+
+		key, ok := salvageString(data, l.keyData+int64(fileByteOrder.Uint64(data[keyAt:])))
+		if !ok || slotHash(src.hashKey(key)) != hv {
+			continue
+		}
+
+		value, ok := salvageValue(&src, data, i)
+		if !ok {
+			continue
+		}
+		if src.flags&flagStringValues != 0 {
+			s, ok := salvageStringValue(&src, data, value)
+			if !ok {
+				continue
+			}
+			value = []byte(s)
+		}
+
+		entries = append(entries, entry{key: key, value: value})
+		keyLength += int64(len(key))
+		if src.flags&flagStringValues != 0 {
+			keyLength += int64(len(value))
+		}
+	}
+
+	opts := append(src.options(), WithBuildTime(time.Unix(0, src.created)))
+	numItems := src.numItems
+	if src.flags&flagCuckoo != 0 {
+		// New sizes cuckoo tables by the entries they hold rather than their slots
+		numItems = cuckooCapacity(numItems)
+	}
+	t = New(numItems, int64(src.valueSize), keyLength, opts...)
+	t.version = src.version
+	for _, e := range entries {
+		if src.flags&flagStringValues != 0 {
+			err = t.SetString(e.key, string(e.value))
+		} else {
+			err = t.Set(e.key, bytesPointer(e.value))
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	t.Finalize()
+
+	if h.format == formatV0 {
+		// The header doesn't say how many entries there were
+		return t, 0, nil
+	}
+	return t, src.count - len(entries), nil
+}
+
+// salvageString returns the string stored at offset in data, if it's all there
+func salvageString(data []byte, offset int64) (string, bool) {
+	if offset < 0 || offset >= int64(len(data)) {
+		return "", false
+	}
+	l, lenLen := binary.Varint(data[offset:])
+	if lenLen <= 0 || l < 0 || offset+int64(lenLen)+l > int64(len(data)) {
+		return "", false
+	}
+	start := offset + int64(lenLen)
+	return string(data[start : start+l]), true
+}
+
+// salvageStringValue returns the string whose value is value, if it is intact
+func salvageStringValue(t *table, data, value []byte) (string, bool) {
+	if t.flags&flagInlineStrings != 0 {
+		if n := value[len(value)-1]; n != overflowString {
+			if int(n) >= len(value) {
+				return "", false
+			}
+			return string(value[:n]), true
+		}
+	}
+	return salvageString(data, t.layout.keyData+int64(fileByteOrder.Uint64(value)))
+}
+
+// slotFields returns the offsets in the file of the hash and key offset of slot i
+func (t *table) slotFields(i int) (hashAt, keyAt int64) {
+	if t.flags&flagInterleaved != 0 {
+		record := t.layout.hashes + slotSize(t.flags, int64(t.valueSize))*int64(i)
+		return record + int64(unsafe.Offsetof(slotRecord{}.hash)), record + int64(unsafe.Offsetof(slotRecord{}.key))
+	}
+	return t.layout.hashes + int64(unsafe.Sizeof(hash(0)))*int64(i), t.layout.keys + int64(unsafe.Sizeof(keyOffset(0)))*int64(i)
+}
+
+// salvageValue returns a copy of the value in slot index of t, if it's all within data
+func salvageValue(t *table, data []byte, index int) ([]byte, bool) {
+	value := make([]byte, t.valueSize)
+	if t.columns == nil {
+		start := t.layout.values + int64(index*t.valueSize)
+		if t.flags&flagInlineValues != 0 {
+			hashAt, _ := t.slotFields(index)
+			start = hashAt + slotRecordSize
+		}
+		if start+int64(t.valueSize) > int64(len(data)) {
+			return nil, false
+		}
+		copy(value, data[start:])
+		return value, true
+	}
+	for c, w := range t.columns {
+		start := t.layout.values + int64(t.columnOffset(c, index))
+		if start+int64(w) > int64(len(data)) {
+			return nil, false
+		}
+		copy(value[t.columnStart[c]:], data[start:start+int64(w)])
+	}
+	return value, true
+}
@@ -0,0 +1,84 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSalvage(t *testing.T) {
+	for _, opts := range [][]Option{{WithFingerprints()}, {WithInterleavedSlots()}} {
+		testSalvage(t, opts...)
+	}
+}
+
+func testSalvage(t *testing.T, opts ...Option) {
+	tb := buildTable(t, 100, append(opts, WithVersion("v2"))...)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+
+	// Intact files are recovered completely
+	w, lost, err := Salvage(data)
+	assert.NoError(t, err)
+	assert.Zero(t, lost)
+	assert.Equal(t, 100, w.Len())
+	assert.Equal(t, tb.flags, w.flags)
+	assert.Equal(t, tb.version, w.version)
+
+	// Lose the end of the key data
+	w, lost, err = Salvage(data[:tb.layout.keyData+300])
+	assert.NoError(t, err)
+	assert.Greater(t, lost, 0)
+	assert.Equal(t, 100, w.Len()+lost)
+
+	var out bytes.Buffer
+	_, err = w.WriteTo(&out)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(out.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, r.Validate())
+	for it := r.Iterate(); it.Next(); {
+		var i int
+		fmt.Sscanf(it.Key(), "key%d", &i)
+		assert.Equal(t, 100-i, *(*int)(it.Value()))
+	}
+
+	// Lose all the values
+	w, lost, err = Salvage(data[:tb.layout.values])
+	assert.NoError(t, err)
+	assert.Equal(t, 100, lost)
+	assert.Zero(t, w.Len())
+
+	_, _, err = Salvage(data[:10])
+	assert.ErrorIs(t, err, ErrTruncated)
+	bad := append([]byte(nil), data...)
+	bad[0] = 'x'
+	_, _, err = Salvage(bad)
+	assert.ErrorIs(t, err, ErrBadMagic)
+}
+
+func TestSalvageStrings(t *testing.T) {
+	st := NewStringTable(10, 100)
+	for i := 0; i < 10; i++ {
+		st.Set(fmt.Sprintf("k%d", i), fmt.Sprintf("value %d", i))
+	}
+	st.Finalize()
+	var buf bytes.Buffer
+	_, err := st.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	w, lost, err := Salvage(buf.Bytes()[:st.w.layout.keyData+int64(st.w.keyOffset)-10])
+	assert.NoError(t, err)
+	assert.Equal(t, 10, w.Len()+lost)
+	assert.Less(t, lost, 10)
+	for it := w.Iterate(); it.Next(); {
+		v, ok := w.GetString(it.Key())
+		assert.True(t, ok)
+		assert.Equal(t, "value "+it.Key()[1:], v)
+	}
+}
@@ -0,0 +1,179 @@
+package statichash
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+// scalarTable is a table whose values are a single scalar of type T. It holds the methods shared by
+// Uint64Table, Int64Table and Float64Table.
+type scalarTable[T uint64 | int64 | float64] struct {
+	t *table
+	// w is set if the table is for writing
+	w *Write
+	// r is set if the table was read from a file
+	r *Read
+}
+
+func newScalarTable[T uint64 | int64 | float64](numItems int, totalKeyLength int64, opts []Option) scalarTable[T] {
+	var zero T
+	w := New(numItems, int64(unsafe.Sizeof(zero)), totalKeyLength, opts...)
+	return scalarTable[T]{t: &w.table, w: w}
+}
+
+func readScalarTable[T uint64 | int64 | float64](r *Read, err error) (scalarTable[T], error) {
+	if err != nil {
+		return scalarTable[T]{}, err
+	}
+	if r.flags&flagStringValues != 0 {
+		r.Close()
+		return scalarTable[T]{}, fmt.Errorf("%w: table has string values", ErrValueSizeMismatch)
+	}
+	return scalarTable[T]{t: &r.table, r: r}, nil
+}
+
+// scalarOpts makes opening a table fail unless the values are the size of a T
+func scalarOpts[T uint64 | int64 | float64](opts []ReadOption) []ReadOption {
+	var zero T
+	return append(opts, WithValueSize(int(unsafe.Sizeof(zero))))
+}
+
+// Set sets the value for key. It returns ErrReadOnly if the table was read from a file.
+func (s *scalarTable[T]) Set(key string, value T) error {
+	if s.w == nil {
+		return ErrReadOnly
+	}
+	return s.w.Set(key, unsafe.Pointer(&value))
+}
+
+// Get returns the value for key
+func (s *scalarTable[T]) Get(key string) (value T, ok bool) {
+	p, ok := s.t.GetPtr(key)
+	if ok {
+		value = *(*T)(p)
+	}
+	return value, ok
+}
+
+// Len returns the number of entries in the table
+func (s *scalarTable[T]) Len() int {
+	return s.t.Len()
+}
+
+// Range calls f for each entry in the table, in the order Iterate visits them, until f returns false
+func (s *scalarTable[T]) Range(f func(key string, value T) bool) {
+	for it := s.t.Iterate(); it.Next(); {
+		if !f(it.Key(), *(*T)(it.Value())) {
+			return
+		}
+	}
+}
+
+// Finalize seals a table created for writing so it can be saved, as Write.Finalize does
+func (s *scalarTable[T]) Finalize() BuildReport {
+	if s.w == nil {
+		return BuildReport{}
+	}
+	return s.w.Finalize()
+}
+
+// WriteTo saves a table created for writing once it has been finalized
+func (s *scalarTable[T]) WriteTo(w io.Writer) (int64, error) {
+	if s.w == nil {
+		return 0, ErrReadOnly
+	}
+	return s.w.WriteTo(w)
+}
+
+// Close releases the resources of a table that was read from a file
+func (s *scalarTable[T]) Close() error {
+	if s.r == nil {
+		return nil
+	}
+	return s.r.Close()
+}
+
+// Uint64Table is a table with uint64 values
+type Uint64Table struct {
+	scalarTable[uint64]
+}
+
+// NewUint64Table creates a Uint64Table for writing
+func NewUint64Table(numItems int, totalKeyLength int64, opts ...Option) *Uint64Table {
+	return &Uint64Table{newScalarTable[uint64](numItems, totalKeyLength, opts)}
+}
+
+// OpenUint64Table opens a Uint64Table saved to filename
+func OpenUint64Table(filename string, opts ...ReadOption) (*Uint64Table, error) {
+	s, err := readScalarTable[uint64](NewFrom(filename, scalarOpts[uint64](opts)...))
+	if err != nil {
+		return nil, err
+	}
+	return &Uint64Table{s}, nil
+}
+
+// Uint64TableFromBytes is like OpenUint64Table, but reads the table from data, as NewFromBytes does
+func Uint64TableFromBytes(data []byte, opts ...ReadOption) (*Uint64Table, error) {
+	s, err := readScalarTable[uint64](NewFromBytes(data, scalarOpts[uint64](opts)...))
+	if err != nil {
+		return nil, err
+	}
+	return &Uint64Table{s}, nil
+}
+
+// Int64Table is a table with int64 values
+type Int64Table struct {
+	scalarTable[int64]
+}
+
+// NewInt64Table creates an Int64Table for writing
+func NewInt64Table(numItems int, totalKeyLength int64, opts ...Option) *Int64Table {
+	return &Int64Table{newScalarTable[int64](numItems, totalKeyLength, opts)}
+}
+
+// OpenInt64Table opens an Int64Table saved to filename
+func OpenInt64Table(filename string, opts ...ReadOption) (*Int64Table, error) {
+	s, err := readScalarTable[int64](NewFrom(filename, scalarOpts[int64](opts)...))
+	if err != nil {
+		return nil, err
+	}
+	return &Int64Table{s}, nil
+}
+
+// Int64TableFromBytes is like OpenInt64Table, but reads the table from data, as NewFromBytes does
+func Int64TableFromBytes(data []byte, opts ...ReadOption) (*Int64Table, error) {
+	s, err := readScalarTable[int64](NewFromBytes(data, scalarOpts[int64](opts)...))
+	if err != nil {
+		return nil, err
+	}
+	return &Int64Table{s}, nil
+}
+
+// Float64Table is a table with float64 values
+type Float64Table struct {
+	scalarTable[float64]
+}
+
+// NewFloat64Table creates a Float64Table for writing
+func NewFloat64Table(numItems int, totalKeyLength int64, opts ...Option) *Float64Table {
+	return &Float64Table{newScalarTable[float64](numItems, totalKeyLength, opts)}
+}
+
+// OpenFloat64Table opens a Float64Table saved to filename
+func OpenFloat64Table(filename string, opts ...ReadOption) (*Float64Table, error) {
+	s, err := readScalarTable[float64](NewFrom(filename, scalarOpts[float64](opts)...))
+	if err != nil {
+		return nil, err
+	}
+	return &Float64Table{s}, nil
+}
+
+// Float64TableFromBytes is like OpenFloat64Table, but reads the table from data, as NewFromBytes does
+func Float64TableFromBytes(data []byte, opts ...ReadOption) (*Float64Table, error) {
+	s, err := readScalarTable[float64](NewFromBytes(data, scalarOpts[float64](opts)...))
+	if err != nil {
+		return nil, err
+	}
+	return &Float64Table{s}, nil
+}
@@ -0,0 +1,91 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUint64Table(t *testing.T) {
+	ut := NewUint64Table(10, 100)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, ut.Set(fmt.Sprintf("k%d", i), math.MaxUint64-uint64(i)))
+	}
+	var buf bytes.Buffer
+	ut.Finalize()
+	_, err := ut.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	rt, err := Uint64TableFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	v, ok := rt.Get("k3")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(math.MaxUint64-3), v)
+	_, ok = rt.Get("k10")
+	assert.False(t, ok)
+	assert.Equal(t, 10, rt.Len())
+	assert.ErrorIs(t, rt.Set("a", 1), ErrReadOnly)
+
+	var sum uint64
+	rt.Range(func(key string, value uint64) bool {
+		sum += math.MaxUint64 - value
+		return true
+	})
+	assert.Equal(t, uint64(45), sum)
+
+	rt, err = OpenUint64Table(writeTempTable(t, ut.w))
+	assert.NoError(t, err)
+	v, _ = rt.Get("k0")
+	assert.Equal(t, uint64(math.MaxUint64), v)
+	assert.NoError(t, rt.Close())
+}
+
+func TestInt64Table(t *testing.T) {
+	it := NewInt64Table(2, 10)
+	assert.NoError(t, it.Set("a", -1))
+	assert.NoError(t, it.Set("b", math.MinInt64))
+	var buf bytes.Buffer
+	it.Finalize()
+	_, err := it.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	rt, err := Int64TableFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	v, ok := rt.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, int64(math.MinInt64), v)
+}
+
+func TestFloat64Table(t *testing.T) {
+	ft := NewFloat64Table(2, 10)
+	assert.NoError(t, ft.Set("pi", math.Pi))
+	v, ok := ft.Get("pi")
+	assert.True(t, ok)
+	assert.Equal(t, math.Pi, v)
+
+	var buf bytes.Buffer
+	ft.Finalize()
+	_, err := ft.WriteTo(&buf)
+	assert.NoError(t, err)
+	_, err = Float64TableFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	buf.Reset()
+	tb := New(2, 4, 10)
+	tb.Finalize()
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	_, err = Float64TableFromBytes(buf.Bytes())
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+
+	buf.Reset()
+	st := NewStringTable(2, 10)
+	st.Finalize()
+	_, err = st.WriteTo(&buf)
+	assert.NoError(t, err)
+	_, err = Float64TableFromBytes(buf.Bytes())
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+}
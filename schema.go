@@ -0,0 +1,283 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// FieldType is the type of a field within a value
+type FieldType int
+
+const (
+	// FieldBytes is a fixed-length run of bytes, such as a [16]byte
+	FieldBytes FieldType = iota
+	FieldBool
+	FieldInt8
+	FieldInt16
+	FieldInt32
+	FieldInt64
+	FieldUint8
+	FieldUint16
+	FieldUint32
+	FieldUint64
+	FieldFloat32
+	FieldFloat64
+)
+
+var fieldTypeNames = [...]string{
+	FieldBytes:   "bytes",
+	FieldBool:    "bool",
+	FieldInt8:    "int8",
+	FieldInt16:   "int16",
+	FieldInt32:   "int32",
+	FieldInt64:   "int64",
+	FieldUint8:   "uint8",
+	FieldUint16:  "uint16",
+	FieldUint32:  "uint32",
+	FieldUint64:  "uint64",
+	FieldFloat32: "float32",
+	FieldFloat64: "float64",
+}
+
+func (f FieldType) String() string {
+	if f >= 0 && int(f) < len(fieldTypeNames) {
+		return fieldTypeNames[f]
+	}
+	return fmt.Sprintf("FieldType(%d)", int(f))
+}
+
+// MarshalText encodes the type as its name, so schemas can be saved as JSON
+func (f FieldType) MarshalText() ([]byte, error) {
+	if f < 0 || int(f) >= len(fieldTypeNames) {
+		return nil, fmt.Errorf("unknown field type %d", int(f))
+	}
+	return []byte(fieldTypeNames[f]), nil
+}
+
+// UnmarshalText decodes a type name written by MarshalText
+func (f *FieldType) UnmarshalText(text []byte) error {
+	for t, name := range fieldTypeNames {
+		if name == string(text) {
+			*f = FieldType(t)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown field type %q", text)
+}
+
+// size returns the size of a field of this type, or 0 if the size is variable
+func (f FieldType) size() int {
+	switch f {
+	case FieldBool, FieldInt8, FieldUint8:
+		return 1
+	case FieldInt16, FieldUint16:
+		return 2
+	case FieldInt32, FieldUint32, FieldFloat32:
+		return 4
+	case FieldInt64, FieldUint64, FieldFloat64:
+		return 8
+	}
+	return 0
+}
+
+// Field describes a field within a value
+type Field struct {
+	Name   string    `json:"name"`
+	Type   FieldType `json:"type"`
+	Offset int       `json:"offset"`
+	// Size is the length of a FieldBytes field. It is ignored for other types.
+	Size int `json:"size,omitempty"`
+}
+
+// size returns the number of bytes the field occupies
+func (f *Field) size() int {
+	if f.Type == FieldBytes {
+		return f.Size
+	}
+	return f.Type.size()
+}
+
+// Decode returns the field from value, which must be a whole value from the table. Signed integers are
+// returned as int64, unsigned integers as uint64, floats as float64, and FieldBytes as a []byte that refers to
+// value.
+func (f *Field) Decode(value []byte) any {
+	b := value[f.Offset:]
+	switch f.Type {
+	case FieldBool:
+		return b[0] != 0
+	case FieldInt8:
+		return int64(int8(b[0]))
+	case FieldInt16:
+		return int64(int16(binary.NativeEndian.Uint16(b)))
+	case FieldInt32:
+		return int64(int32(binary.NativeEndian.Uint32(b)))
+	case FieldInt64:
+		return int64(binary.NativeEndian.Uint64(b))
+	case FieldUint8:
+		return uint64(b[0])
+	case FieldUint16:
+		return uint64(binary.NativeEndian.Uint16(b))
+	case FieldUint32:
+		return uint64(binary.NativeEndian.Uint32(b))
+	case FieldUint64:
+		return binary.NativeEndian.Uint64(b)
+	case FieldFloat32:
+		return float64(math.Float32frombits(binary.NativeEndian.Uint32(b)))
+	case FieldFloat64:
+		return math.Float64frombits(binary.NativeEndian.Uint64(b))
+	}
+	return b[:f.Size:f.Size]
+}
+
+// Schema describes the fields within the values of a table. Use it with tools that need to show values
+// without access to the Go type.
+type Schema struct {
+	// Key optionally describes what the keys are, for example "ISO 3166 country code"
+	Key    string  `json:"key,omitempty"`
+	Fields []Field `json:"fields"`
+}
+
+// SchemaOf returns the Schema of the pointer-free type T. The fields of nested structs are included with
+// dotted names, and arrays are treated as FieldBytes.
+func SchemaOf[T any]() (Schema, error) {
+	typ := reflect.TypeFor[T]()
+	if err := checkPointerFree(typ); err != nil {
+		return Schema{}, err
+	}
+	var s Schema
+	if typ.Kind() != reflect.Struct {
+		s.addField("value", typ, 0)
+		return s, nil
+	}
+	s.addStruct("", typ, 0)
+	return s, nil
+}
+
+func (s *Schema) addStruct(prefix string, typ reflect.Type, offset int) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Name == "_" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			s.addStruct(prefix+f.Name+".", f.Type, offset+int(f.Offset))
+			continue
+		}
+		s.addField(prefix+f.Name, f.Type, offset+int(f.Offset))
+	}
+}
+
+func (s *Schema) addField(name string, typ reflect.Type, offset int) {
+	f := Field{Name: name, Offset: offset}
+	switch typ.Kind() {
+	case reflect.Bool:
+		f.Type = FieldBool
+	case reflect.Int8:
+		f.Type = FieldInt8
+	case reflect.Int16:
+		f.Type = FieldInt16
+	case reflect.Int32:
+		f.Type = FieldInt32
+	case reflect.Int64:
+		f.Type = FieldInt64
+	case reflect.Int:
+		f.Type = intFieldType(typ, FieldInt32, FieldInt64)
+	case reflect.Uint8:
+		f.Type = FieldUint8
+	case reflect.Uint16:
+		f.Type = FieldUint16
+	case reflect.Uint32:
+		f.Type = FieldUint32
+	case reflect.Uint64:
+		f.Type = FieldUint64
+	case reflect.Uint, reflect.Uintptr:
+		f.Type = intFieldType(typ, FieldUint32, FieldUint64)
+	case reflect.Float32:
+		f.Type = FieldFloat32
+	case reflect.Float64:
+		f.Type = FieldFloat64
+	default:
+		// Arrays and complex numbers
+		f.Type = FieldBytes
+		f.Size = int(typ.Size())
+	}
+	s.Fields = append(s.Fields, f)
+}
+
+func intFieldType(typ reflect.Type, small, large FieldType) FieldType {
+	if typ.Size() == 4 {
+		return small
+	}
+	return large
+}
+
+// Field returns the field called name, or nil if there isn't one
+func (s *Schema) Field(name string) *Field {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Check returns an error if any of the fields don't fit in a value of valueSize bytes
+func (s *Schema) Check(valueSize int) error {
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if f.Offset < 0 || f.size() < 0 || f.Offset+f.size() > valueSize {
+			return fmt.Errorf("%w: field %s (%s at offset %d) does not fit in a %d byte value", ErrValueSizeMismatch, f.Name, f.Type, f.Offset, valueSize)
+		}
+	}
+	return nil
+}
+
+// Schema returns the schema saved in the file by WithSchema, or false if the table was built without one
+func (r *Read) Schema() (Schema, bool, error) {
+	data, ok, err := r.section(SectionSchema)
+	if !ok || err != nil {
+		return Schema{}, false, err
+	}
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Schema{}, true, fmt.Errorf("%w: decoding schema: %v", ErrCorrupt, err)
+	}
+	return s, true, nil
+}
+
+// checkSchema returns an error unless the table's schema includes every field of expected, as described by
+// WithExpectedSchema
+func (r *Read) checkSchema(expected *Schema) error {
+	s, ok, err := r.Schema()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: table has no schema", ErrSchemaMismatch)
+	}
+	if expected.Key != "" && s.Key != expected.Key {
+		return fmt.Errorf("%w: table keys are %q, expected %q", ErrSchemaMismatch, s.Key, expected.Key)
+	}
+	for i := range expected.Fields {
+		want := &expected.Fields[i]
+		got := s.Field(want.Name)
+		if got == nil {
+			return fmt.Errorf("%w: table has no field %s", ErrSchemaMismatch, want.Name)
+		}
+		if got.Type != want.Type || got.Offset != want.Offset || got.size() != want.size() {
+			return fmt.Errorf("%w: field %s is %s, expected %s", ErrSchemaMismatch, want.Name, got.describe(), want.describe())
+		}
+	}
+	return nil
+}
+
+// describe returns the type, size and offset of the field for error messages
+func (f *Field) describe() string {
+	if f.Type == FieldBytes {
+		return fmt.Sprintf("%d bytes at offset %d", f.Size, f.Offset)
+	}
+	return fmt.Sprintf("%s at offset %d", f.Type, f.Offset)
+}
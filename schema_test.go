@@ -0,0 +1,125 @@
+package statichash
+
+import (
+	"encoding/json"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	type inner struct {
+		X uint16
+		Y [3]byte
+	}
+	type value struct {
+		A int32
+		B float64
+		C inner
+		D bool
+		E float32
+		F int8
+	}
+
+	s, err := SchemaOf[value]()
+	assert.NoError(t, err)
+	assert.Equal(t, []Field{
+		{Name: "A", Type: FieldInt32, Offset: 0},
+		{Name: "B", Type: FieldFloat64, Offset: 8},
+		{Name: "C.X", Type: FieldUint16, Offset: 16},
+		{Name: "C.Y", Type: FieldBytes, Offset: 18, Size: 3},
+		{Name: "D", Type: FieldBool, Offset: 22},
+		{Name: "E", Type: FieldFloat32, Offset: 24},
+		{Name: "F", Type: FieldInt8, Offset: 28},
+	}, s.Fields)
+	assert.NoError(t, s.Check(int(unsafe.Sizeof(value{}))))
+	assert.ErrorIs(t, s.Check(24), ErrValueSizeMismatch)
+
+	v := value{A: -7, B: 1.5, C: inner{X: 300, Y: [3]byte{1, 2, 3}}, D: true, E: 2.25, F: -2}
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&v)), unsafe.Sizeof(v))
+	var decoded []any
+	for _, f := range s.Fields {
+		decoded = append(decoded, f.Decode(data))
+	}
+	assert.Equal(t, []any{int64(-7), 1.5, uint64(300), []byte{1, 2, 3}, true, 2.25, int64(-2)}, decoded)
+
+	assert.Equal(t, "C.X", s.Field("C.X").Name)
+	assert.Nil(t, s.Field("Z"))
+	assert.Equal(t, "float32", FieldFloat32.String())
+
+	s, err = SchemaOf[uint32]()
+	assert.NoError(t, err)
+	assert.Equal(t, []Field{{Name: "value", Type: FieldUint32}}, s.Fields)
+
+	_, err = SchemaOf[struct{ S string }]()
+	assert.ErrorIs(t, err, ErrHasPointers)
+}
+
+func TestSchemaJSON(t *testing.T) {
+	s := Schema{Fields: []Field{
+		{Name: "A", Type: FieldInt32},
+		{Name: "B", Type: FieldBytes, Offset: 4, Size: 12},
+	}}
+	data, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"fields":[{"name":"A","type":"int32","offset":0},{"name":"B","type":"bytes","offset":4,"size":12}]}`, string(data))
+
+	var out Schema
+	assert.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, s, out)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"fields":[{"name":"A","type":"int128"}]}`), &out))
+}
+
+func TestSchemaInFile(t *testing.T) {
+	type value struct {
+		Count int64
+		Score float32
+		Code  [4]byte
+	}
+	s, err := SchemaOf[value]()
+	assert.NoError(t, err)
+	s.Key = "customer id"
+
+	tb := New(10, int64(unsafe.Sizeof(value{})), 100, WithSchema(s))
+	v := value{Count: 3}
+	assert.NoError(t, tb.Set("a", unsafe.Pointer(&v)))
+	tb.Finalize()
+	name := writeTempTable(t, tb)
+
+	r, err := NewFrom(name)
+	assert.NoError(t, err)
+	got, ok, err := r.Schema()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, s, got)
+	assert.NoError(t, r.Close())
+
+	// A subset of the fields is fine
+	r, err = NewFrom(name, WithExpectedSchema(Schema{Fields: s.Fields[1:]}))
+	if assert.NoError(t, err) {
+		assert.NoError(t, r.Close())
+	}
+	r, err = NewFrom(name, WithWindowedMapping(4096, 1), WithExpectedSchema(s))
+	if assert.NoError(t, err) {
+		assert.NoError(t, r.Close())
+	}
+
+	for _, bad := range []Schema{
+		{Key: "order id", Fields: s.Fields},
+		{Fields: []Field{{Name: "Count", Type: FieldUint64}}},
+		{Fields: []Field{{Name: "Code", Type: FieldBytes, Offset: 12, Size: 8}}},
+		{Fields: []Field{{Name: "Missing", Type: FieldBool}}},
+	} {
+		_, err = NewFrom(name, WithExpectedSchema(bad))
+		assert.ErrorIs(t, err, ErrSchemaMismatch)
+	}
+
+	plain := buildTable(t, 10)
+	plain.Finalize()
+	_, err = NewFrom(writeTempTable(t, plain), WithExpectedSchema(s))
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+	assert.Panics(t, func() { New(10, 4, 100, WithSchema(s)) })
+}
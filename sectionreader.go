@@ -0,0 +1,89 @@
+package statichash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// NewFromSectionReader opens the table held in sr, such as a table embedded in a larger container file. If sr
+// reads from an *os.File and the table starts on an 8-byte boundary within it, the table is mapped straight
+// from the file, as NewFromZip does for archives. Otherwise the table is read into memory and checked as if it
+// were opened with NewFromBytes, so sr can read from anything, such as a network stream. Evict does nothing
+// for tables opened this way, and WithWindowedMapping and WithSharedLock have no effect.
+func NewFromSectionReader(sr *io.SectionReader, opts ...ReadOption) (*Read, error) {
+	o := newReadOptions(opts)
+
+	var r *Read
+	var err error
+	if f, offset, size := sectionFile(sr); f != nil && offset%int64(unsafe.Alignof(int64(0))) == 0 {
+		r, err = mapSection(f, offset, size, &o)
+	} else {
+		r, err = readSection(sr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.apply(&o); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// sectionFile returns the file sr reads from and where within it, or nil if sr doesn't read from a file
+func sectionFile(sr *io.SectionReader) (f *os.File, offset, size int64) {
+	outer, offset, size := sr.Outer()
+	f, ok := outer.(*os.File)
+	if !ok {
+		return nil, 0, 0
+	}
+	return f, offset, size
+}
+
+// mapSection maps the table of size bytes at offset in f, locking it into memory as o says
+func mapSection(f *os.File, offset, size int64, o *readOptions) (*Read, error) {
+	if size < int64(minHeaderSize) {
+		return nil, fmt.Errorf("%w: table is only %d bytes long", ErrTruncated, size)
+	}
+	// Mapping past the end of the file succeeds, but reading there crashes the process
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if offset+size > fi.Size() {
+		return nil, fmt.Errorf("%w: table of %d bytes at offset %d runs past the end of %s, which is %d bytes long", ErrTruncated, size, offset, f.Name(), fi.Size())
+	}
+	r, err := mapTableAt(f, offset, size, o)
+	if err != nil {
+		return nil, fmt.Errorf("opening table at offset %d in %s: %w", offset, f.Name(), err)
+	}
+	return r, nil
+}
+
+// readSection reads the table in sr into memory
+func readSection(sr *io.SectionReader) (*Read, error) {
+	size := sr.Size()
+	if err := checkMappable(size); err != nil {
+		return nil, err
+	}
+	// We read into []int64 so the table's sections are aligned
+	buf := make([]int64, (size+7)/8)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(buf))), size)
+	n, err := sr.ReadAt(data, 0)
+	if n < len(data) {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: expected %d bytes but could only read %d", ErrTruncated, size, n)
+		}
+		return nil, fmt.Errorf("reading table: %w", err)
+	}
+	r, err := newFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkBounds(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,66 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromSectionReader(t *testing.T) {
+	tb := buildTable(t, 1000)
+	tb.Finalize()
+	var table bytes.Buffer
+	_, err := tb.WriteTo(&table)
+	assert.NoError(t, err)
+	size := int64(table.Len())
+
+	// A container holding the table twice, once 8-byte aligned and once not
+	container := append(make([]byte, 5000), table.Bytes()...)
+	container = append(container, make([]byte, 3)...)
+	container = append(container, table.Bytes()...)
+	name := filepath.Join(t.TempDir(), "container")
+	assert.NoError(t, os.WriteFile(name, container, 0o644))
+	f, err := os.Open(name)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	check := func(r *Read) {
+		assert.Equal(t, 1000, r.Len())
+		for i := 0; i < 1000; i += 10 {
+			v, ok := r.GetPtr(fmt.Sprintf("key%d", 1000-i))
+			if assert.True(t, ok) {
+				assert.Equal(t, i, *(*int)(v))
+			}
+		}
+	}
+
+	r, err := NewFromSectionReader(io.NewSectionReader(f, 5000, size))
+	assert.NoError(t, err)
+	assert.NotNil(t, r.mapping)
+	check(r)
+	assert.NoError(t, r.Close())
+
+	r, err = NewFromSectionReader(io.NewSectionReader(f, 5000+size+3, size))
+	assert.NoError(t, err)
+	assert.Nil(t, r.mapping)
+	check(r)
+	assert.NoError(t, r.Close())
+
+	r, err = NewFromSectionReader(io.NewSectionReader(bytes.NewReader(container), 5000, size), WithValueSize(8))
+	assert.NoError(t, err)
+	check(r)
+
+	_, err = NewFromSectionReader(io.NewSectionReader(f, 5000, 100))
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, err = NewFromSectionReader(io.NewSectionReader(f, 5000+size+3, size+100))
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, err = NewFromSectionReader(io.NewSectionReader(f, 5000+size+3+8, size+100))
+	assert.Error(t, err)
+	_, err = NewFromSectionReader(io.NewSectionReader(f, 5000, size+int64(len(container))))
+	assert.ErrorIs(t, err, ErrTruncated)
+}
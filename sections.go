@@ -0,0 +1,201 @@
+package statichash
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"unsafe"
+)
+
+/*
+The directory is an array of directoryEntry at the offset given in the header, at the end of the file. It lists
+every section in the file. The core sections are always where offsets puts them, and the directory must agree.
+Any other sections follow the key data. Readers look these up by type and skip any type they don't know, so
+new optional sections can be added to the format without breaking older readers.
+*/
+
+// directoryEntry describes one section of the file. It is encoded in the file as layout.go describes.
+type directoryEntry struct {
+	kind   uint32
+	offset int64
+	length int64
+}
+
+// coreSections are the sections every table has. The optional ones are empty if the table doesn't use them.
+var coreSections = []Section{SectionHashes, SectionFingerprints, SectionKeys, SectionOrder, SectionSorted, SectionValues, SectionKeyData}
+
+// maxSections is the most directory entries we'll read. It stops a corrupt header making us allocate a huge
+// directory.
+const maxSections = 1024
+
+// extraSection is an optional section to be written after the key data
+type extraSection struct {
+	kind Section
+	data []byte
+}
+
+// addSection adds an optional section to the file written by WriteTo, replacing any section of the same kind.
+// The data is not copied.
+func (t *Write) addSection(kind Section, data []byte) {
+	if slices.Contains(coreSections, kind) {
+		panic(fmt.Sprintf("statichash: %s is a core section", kind))
+	}
+	for i := range t.extra {
+		if t.extra[i].kind == kind {
+			t.extra[i].data = data
+			return
+		}
+	}
+	t.extra = append(t.extra, extraSection{kind: kind, data: data})
+}
+
+// directory returns the directory WriteTo writes after the table, the offset it is written at and the length
+// of the whole file
+func (t *Write) directory() (dir []directoryEntry, offset, length int64) {
+	for _, s := range coreSections {
+		start, end := t.layout.section(s)
+		dir = append(dir, directoryEntry{kind: uint32(s), offset: start, length: end - start})
+	}
+	offset = t.length
+	for _, e := range t.extra {
+		offset = roundUp(offset, unsafe.Alignof(int64(0)))
+		dir = append(dir, directoryEntry{kind: uint32(e.kind), offset: offset, length: int64(len(e.data))})
+		offset += int64(len(e.data))
+	}
+	offset = roundUp(offset, unsafe.Alignof(int64(0)))
+	return dir, offset, offset + int64(len(dir))*directoryEntrySize
+}
+
+// FileLen returns the length of the file WriteTo writes
+func (t *Write) FileLen() int64 {
+	_, _, length := t.directory()
+	return length
+}
+
+// writeSections writes the optional sections and the directory. n is the number of bytes already written.
+func (t *Write) writeSections(w io.Writer, n int64, dir []directoryEntry) (int64, error) {
+	var zeros [8]byte
+	pad := func(to int64) error {
+		if to == n {
+			return nil
+		}
+		m, err := w.Write(zeros[:to-n])
+		n += int64(m)
+		return err
+	}
+
+	for i, e := range t.extra {
+		if err := pad(dir[len(coreSections)+i].offset); err != nil {
+			return n, err
+		}
+		m, err := w.Write(e.data)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	if err := pad(roundUp(n, unsafe.Alignof(int64(0)))); err != nil {
+		return n, err
+	}
+
+	m, err := w.Write(encodeDirectory(dir))
+	return n + int64(m), err
+}
+
+// readDirectory reads the section directory of the file described by h, checking the core sections are where
+// the table expects them. It sets the end of the key data and records the optional sections. Files written
+// before formatV2 have no directory, and the key data runs to the end of the file.
+func (t *table) readDirectory(h *header, r io.ReaderAt, fileLength int64) error {
+	if h.format < formatV2 {
+		return nil
+	}
+	if h.sections == 0 || h.sections > maxSections {
+		return fmt.Errorf("%w: %d sections in directory", ErrCorrupt, h.sections)
+	}
+	size := int64(h.sections) * directoryEntrySize
+	if h.directory < t.layout.keyData || size > fileLength-h.directory {
+		return fmt.Errorf("%w: directory of %d bytes at %d is outside the file", ErrTruncated, size, h.directory)
+	}
+
+	buf := make([]byte, size)
+	if _, err := r.ReadAt(buf, h.directory); err != nil {
+		return fmt.Errorf("reading section directory: %w", err)
+	}
+	dir := decodeDirectory(buf)
+
+	var haveKeyData bool
+	for _, e := range dir {
+		kind := Section(e.kind)
+		if e.offset < 0 || e.length < 0 || e.length > h.directory-e.offset {
+			return fmt.Errorf("%w: %s section of %d bytes at %d is outside the file", ErrCorrupt, kind, e.length, e.offset)
+		}
+		switch {
+		case kind == SectionKeyData:
+			if e.offset != t.layout.keyData {
+				return fmt.Errorf("%w: key data is at %d, expected %d", ErrCorrupt, e.offset, t.layout.keyData)
+			}
+			t.layout.length = e.offset + e.length
+			haveKeyData = true
+		case slices.Contains(coreSections, kind):
+			if start, end := t.layout.section(kind); e.offset != start || e.length != end-start {
+				return fmt.Errorf("%w: %s section is %d bytes at %d, expected %d bytes at %d", ErrCorrupt, kind, e.length, e.offset, end-start, start)
+			}
+		default:
+			// Sections we don't know about are recorded but otherwise ignored
+			t.sections = append(t.sections, e)
+		}
+	}
+	if !haveKeyData {
+		return fmt.Errorf("%w: directory has no key data section", ErrCorrupt)
+	}
+	return nil
+}
+
+// section returns the contents of an optional section, or false if the file doesn't have one. The contents are
+// only copied if the section isn't mapped into memory.
+func (r *Read) section(kind Section) ([]byte, bool, error) {
+	for _, e := range r.sections {
+		if Section(e.kind) != kind {
+			continue
+		}
+		if end := e.offset + e.length; end <= int64(len(r.data)) {
+			return r.data[e.offset:end:end], true, nil
+		}
+		data := make([]byte, e.length)
+		if _, err := r.file.ReadAt(data, e.offset); err != nil {
+			return nil, true, fmt.Errorf("reading %s section: %w", kind, err)
+		}
+		return data, true, nil
+	}
+	return nil, false, nil
+}
+
+// Hashes returns the slot hashes, for tools that analyse or repair tables. A zero hash marks an empty slot. The
+// slice refers directly to the table's memory, so it must not be modified and is not valid after the table is
+// closed. The same goes for the other raw section accessors. It is nil if the table was built
+// WithInterleavedSlots, as the hashes are then stored with the rest of each slot.
+func (r *Read) Hashes() []uint32 {
+	return unsafe.Slice((*uint32)(unsafe.SliceData(r.hashes)), len(r.hashes))
+}
+
+// KeyOffsets returns the offset within the key data of the key in each slot. Like Hashes, it is nil if the
+// table was built WithInterleavedSlots.
+func (r *Read) KeyOffsets() []int64 {
+	return unsafe.Slice((*int64)(unsafe.SliceData(r.keys)), len(r.keys))
+}
+
+// RawValues returns the values section. Value i is at i*ValueSize unless the table was built WithColumns, in
+// which case the section holds the array of each column in turn. It is nil if the table is mapped in windows
+// or was built WithInlineValues.
+func (r *Read) RawValues() []byte {
+	if r.flags&flagInlineValues != 0 {
+		return nil
+	}
+	return r.values
+}
+
+// RawKeyData returns the key data section. Each key is stored at its offset as a varint length followed by the
+// key. It is nil if the table is mapped in windows.
+func (r *Read) RawKeyData() []byte {
+	return r.keyData
+}
@@ -0,0 +1,113 @@
+package statichash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSections(t *testing.T) {
+	tb := buildTable(t, 10)
+	tb.addSection(100, []byte("hello"))
+	tb.addSection(101, []byte("replaced"))
+	tb.addSection(101, []byte("a longer section"))
+	assert.Panics(t, func() { tb.addSection(SectionValues, nil) })
+	tb.Finalize()
+
+	var buf bytes.Buffer
+	n, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, tb.FileLen(), n)
+	data := buf.Bytes()
+
+	check := func(t *testing.T, r *Read) {
+		s, ok, err := r.section(100)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "hello", string(s))
+		s, ok, err = r.section(101)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "a longer section", string(s))
+		_, ok, err = r.section(102)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		// The extra sections don't look like key data
+		assert.Equal(t, tb.KeyDataLen(), r.KeyDataLen())
+		assert.NoError(t, r.Validate())
+	}
+
+	t.Run("bytes", func(t *testing.T) {
+		r, err := NewFromBytes(data)
+		assert.NoError(t, err)
+		check(t, r)
+
+		var out strings.Builder
+		assert.NoError(t, r.Dump(&out, 0))
+		assert.Contains(t, out.String(), "Section(101)")
+	})
+
+	t.Run("windowed", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "table")
+		assert.NoError(t, os.WriteFile(name, data, 0o644))
+		r, err := NewFrom(name, WithWindowedMapping(4096, 1))
+		assert.NoError(t, err)
+		defer r.Close()
+		check(t, r)
+	})
+
+	t.Run("corrupt directory", func(t *testing.T) {
+		h, err := readHeader(data)
+		assert.NoError(t, err)
+
+		bad := append([]byte(nil), data...)
+		dir := unsafe.Slice((*directoryEntry)(unsafe.Pointer(&bad[h.directory])), h.sections)
+		dir[0].offset += 8
+		_, err = NewFromBytes(bad)
+		assert.ErrorIs(t, err, ErrCorrupt)
+
+		_, err = NewFromBytes(data[:len(data)-1])
+		assert.ErrorIs(t, err, ErrTruncated)
+	})
+}
+
+func TestRawSections(t *testing.T) {
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	hashes := r.Hashes()
+	offsets := r.KeyOffsets()
+	assert.Len(t, hashes, r.NumSlots())
+	assert.Len(t, offsets, r.NumSlots())
+	assert.Len(t, r.RawValues(), r.NumSlots()*r.ValueSize())
+	assert.Equal(t, r.KeyDataLen(), int64(len(r.RawKeyData())))
+
+	// Decode each entry from the raw sections and check it against a lookup
+	var found int
+	for i, h := range hashes {
+		if h == 0 {
+			continue
+		}
+		found++
+		l, n := binary.Varint(r.RawKeyData()[offsets[i]:])
+		key := string(r.RawKeyData()[offsets[i]+int64(n) : offsets[i]+int64(n)+l])
+		assert.Equal(t, uint32(slotHash(r.hashKey(key))), h)
+		v, ok := r.GetPtr(key)
+		assert.True(t, ok)
+		size := r.ValueSize()
+		assert.Equal(t, unsafe.Slice((*byte)(v), size), r.RawValues()[i*size:(i+1)*size])
+	}
+	assert.Equal(t, 10, found)
+}
@@ -0,0 +1,276 @@
+package statichash
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+/*
+A segmented file is a series of complete tables, each appended after the last. Each segment starts at the next
+8 byte boundary after the end of the previous one, or at the next sectionAlignment boundary if it was built
+WithPageAlignedSections. Padding is zero so can't be mistaken for a header. Only current format tables, which
+have a directory, can be segments, as the directory is how we find the end of each one.
+*/
+
+// Segmented is a table stored as a series of segments in one file, where each segment is a complete table.
+// New entries are added by appending a segment with AppendSegment rather than by rebuilding the whole table.
+// Lookups consult the newest segment first, so a key in a later segment overrides the same key in earlier
+// ones. Entries can't be deleted. As segments accumulate lookups get slower, so from time to time Compact the
+// segments into a single table and write that in place of the file.
+type Segmented struct {
+	data []byte
+	// segments are in the order they were appended, oldest first
+	segments []*Read
+}
+
+// AppendSegment appends t to the segmented file filename as its newest segment, creating the file if it
+// doesn't exist. t must be finalized, and have values of the same size as the existing segments. The file is
+// locked against concurrent appends, but readers that already have it open don't see the new segment until
+// they open it again.
+//
+// If writing the segment fails, the file is truncated back to the end of the last segment, so it holds the
+// segments it did before.
+func AppendSegment(filename string, t *Write) (err error) {
+	if !t.finalized {
+		return ErrNotFinalized
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f, true); err != nil {
+		return fmt.Errorf("locking %s: %w", filename, err)
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// A partial segment would stop the file being opened
+			f.Truncate(end)
+		}
+	}()
+	if end > 0 {
+		buf := make([]byte, headerSize)
+		if _, err := f.ReadAt(buf, 0); err != nil {
+			return fmt.Errorf("reading first segment of %s: %w", filename, err)
+		}
+		h, err := readHeader(buf)
+		if err != nil {
+			return fmt.Errorf("reading first segment of %s: %w", filename, err)
+		}
+		if int(h.valueSize) != t.valueSize {
+			return fmt.Errorf("%w: segments of %s have values of %d bytes, not %d", ErrValueSizeMismatch, filename, h.valueSize, t.valueSize)
+		}
+	}
+
+	// Seeking past the end leaves a hole that reads as zeros
+	if _, err := f.Seek(roundUp(end, segmentAlignment(t.flags)), io.SeekStart); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := t.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// segmentAlignment returns the alignment of the start of a segment with the given flags
+func segmentAlignment(flags int64) uintptr {
+	if flags&flagPageAligned != 0 {
+		return sectionAlignment
+	}
+	return unsafe.Alignof(int64(0))
+}
+
+// OpenSegmented opens a segmented file written with AppendSegment. The whole file is mapped into memory. With
+// WithMemoryLockLimit the sections of the newest segments are locked first. WithWindowedMapping and
+// WithSharedLock have no effect.
+func OpenSegmented(filename string, opts ...ReadOption) (*Segmented, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fileLength, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if fileLength < int64(minHeaderSize) {
+		return nil, fmt.Errorf("%w: %s is only %d bytes long", ErrTruncated, filename, fileLength)
+	}
+
+	if err := checkMappable(fileLength); err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	o := newReadOptions(opts)
+	data, err := o.mapMemory(f.Fd(), int(fileLength))
+	if err != nil {
+		return nil, err
+	}
+	s, err := segmentedFrom(data, &o)
+	if err != nil {
+		unmap(data)
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	if o.noMemLock {
+		// Lookups try the newest segment first, so we lock its sections first
+		limit := o.memLockLimit
+		for i := len(s.segments) - 1; i >= 0 && limit > 0; i-- {
+			limit -= s.segments[i].lockWithin(limit)
+		}
+	}
+	return s, nil
+}
+
+// segmentedFrom splits data into its segments. As with NewFrom, the segments aren't scanned for corruption
+// unless Validate is called.
+func segmentedFrom(data []byte, o *readOptions) (*Segmented, error) {
+	s := Segmented{data: data}
+	for offset := int64(0); offset < int64(len(data)); {
+		if !bytes.HasPrefix(data[offset:], fileMagic[:]) {
+			// This may be padding before a page aligned segment
+			offset = roundUp(offset, sectionAlignment)
+			if offset >= int64(len(data)) {
+				break
+			}
+		}
+
+		length, err := segmentLength(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("segment %d at %d: %w", len(s.segments), offset, err)
+		}
+		r, err := newFromData(data[offset : offset+length])
+		if err == nil {
+			err = r.apply(o)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("segment %d at %d: %w", len(s.segments), offset, err)
+		}
+		if len(s.segments) > 0 && r.valueSize != s.segments[0].valueSize {
+			return nil, fmt.Errorf("%w: segment %d has values of %d bytes, not %d", ErrValueSizeMismatch, len(s.segments), r.valueSize, s.segments[0].valueSize)
+		}
+		s.segments = append(s.segments, r)
+
+		offset = roundUp(offset+length, unsafe.Alignof(int64(0)))
+	}
+	if len(s.segments) == 0 {
+		return nil, fmt.Errorf("%w: no segments", ErrCorrupt)
+	}
+	return &s, nil
+}
+
+// segmentLength returns the length of the table at the start of data, which runs to the end of its directory
+func segmentLength(data []byte) (int64, error) {
+	h, err := readHeader(data)
+	if err != nil {
+		return 0, err
+	}
+	if h.format < formatV2 {
+		return 0, fmt.Errorf("%w: format %d tables can't be segments", ErrUnsupportedFormat, h.format)
+	}
+	length := h.directory + int64(h.sections)*directoryEntrySize
+	if h.directory < int64(h.headerSize) || length > int64(len(data)) {
+		return 0, fmt.Errorf("%w: directory at %d is outside the file", ErrTruncated, h.directory)
+	}
+	return length, nil
+}
+
+// GetPtr returns a pointer to the value for key in the newest segment that has it, as Read.GetPtr does
+func (s *Segmented) GetPtr(key string) (unsafe.Pointer, bool) {
+	for i := len(s.segments) - 1; i >= 0; i-- {
+		if v, ok := s.segments[i].GetPtr(key); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// GetString returns the string value for key in the newest segment that has it, as Read.GetString does
+func (s *Segmented) GetString(key string) (string, bool) {
+	for i := len(s.segments) - 1; i >= 0; i-- {
+		if v, ok := s.segments[i].GetString(key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Segments returns the number of segments in the file
+func (s *Segmented) Segments() int {
+	return len(s.segments)
+}
+
+// Validate checks every segment for corruption, as Read.Validate does
+func (s *Segmented) Validate() error {
+	for i, r := range s.segments {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("segment %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Compact merges the segments into a single finalized table, keeping the newest value of each key. The table
+// has the same options as the newest segment, plus any in opts. Write it over the segmented file with
+// Write.WriteFile, after which new segments can be appended to it again.
+func (s *Segmented) Compact(opts ...Option) (*Write, error) {
+	var numItems int
+	var keyLength int64
+	for _, r := range s.segments {
+		if err := r.needKeys("compact"); err != nil {
+			return nil, err
+		}
+		numItems += r.count
+		keyLength += r.KeyDataLen()
+	}
+
+	newest := s.segments[len(s.segments)-1]
+	w := New(numItems, int64(newest.valueSize), keyLength, append(newest.options(), opts...)...)
+	w.version = newest.version
+	for i := len(s.segments) - 1; i >= 0; i-- {
+		r := s.segments[i]
+		for it := r.Iterate(); it.Next(); {
+			key := it.Key()
+			if _, found := w.find(key, w.hashKey(key)); found {
+				// A newer segment has already set this key
+				continue
+			}
+			var err error
+			if r.flags&flagStringValues != 0 {
+				err = w.SetString(key, r.stringValue(it.index))
+			} else {
+				err = w.Set(key, it.Value())
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Finalize()
+	return w, nil
+}
+
+// Close unmaps the file
+func (s *Segmented) Close() error {
+	if s.data == nil {
+		return nil
+	}
+	err := unmap(s.data)
+	s.data = nil
+	s.segments = nil
+	return err
+}
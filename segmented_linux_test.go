@@ -0,0 +1,40 @@
+package statichash
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendSegmentFailure(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "segmented")
+	small := buildTable(t, 10)
+	small.Finalize()
+	assert.NoError(t, AppendSegment(name, small))
+	fi, err := os.Stat(name)
+	assert.NoError(t, err)
+
+	// Writes past the file size limit fail with EFBIG, as Go ignores SIGXFSZ
+	var limit syscall.Rlimit
+	assert.NoError(t, syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit))
+	defer syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit)
+	assert.NoError(t, syscall.Setrlimit(syscall.RLIMIT_FSIZE, &syscall.Rlimit{Cur: uint64(fi.Size()) + 4096, Max: limit.Max}))
+
+	big := buildTable(t, 10000)
+	big.Finalize()
+	assert.Error(t, AppendSegment(name, big))
+	assert.NoError(t, syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit))
+
+	// The partial segment is gone, so the file still opens
+	after, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, fi.Size(), after.Size())
+	s, err := OpenSegmented(name)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Segments())
+	assert.NoError(t, s.Validate())
+	assert.NoError(t, s.Close())
+}
@@ -0,0 +1,64 @@
+package statichash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmented(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "segmented")
+
+	segment := func(from, to, add int, opts ...Option) {
+		tb := New(to-from, 8, int64(10*(to-from)), opts...)
+		for i := from; i < to; i++ {
+			v := i + add
+			assert.NoError(t, tb.Set(fmt.Sprintf("key%d", i), unsafe.Pointer(&v)))
+		}
+		tb.Finalize()
+		assert.NoError(t, AppendSegment(name, tb))
+	}
+	segment(0, 100, 0)
+	segment(50, 150, 1000, WithPageAlignedSections())
+	segment(140, 141, 2000)
+
+	s, err := OpenSegmented(name)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, s.Segments())
+
+	check := func(get func(key string) (unsafe.Pointer, bool)) {
+		for i, want := range map[int]int{0: 0, 49: 49, 50: 1050, 149: 1149, 140: 2140} {
+			v, ok := get(fmt.Sprintf("key%d", i))
+			if assert.True(t, ok, i) {
+				assert.Equal(t, want, *(*int)(v), i)
+			}
+		}
+		_, ok := get("key150")
+		assert.False(t, ok)
+	}
+	check(s.GetPtr)
+
+	c, err := s.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, 150, c.Len())
+	check(c.GetPtr)
+	assert.NoError(t, s.Close())
+
+	tb := New(10, 4, 100)
+	assert.ErrorIs(t, AppendSegment(name, tb), ErrNotFinalized)
+	tb.Finalize()
+	assert.ErrorIs(t, AppendSegment(name, tb), ErrValueSizeMismatch)
+
+	// A partly written segment stops the file being opened
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	_, err = f.Write(fileMagic[:])
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	_, err = OpenSegmented(name)
+	assert.Error(t, err)
+}
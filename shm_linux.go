@@ -0,0 +1,75 @@
+package statichash
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shmDir is where Linux keeps POSIX shared memory objects. shm_open is a thin wrapper around opening files
+// here, so we do the same.
+const shmDir = "/dev/shm"
+
+// shmPath returns the path of the shared memory object called name. As with shm_open, name may start with a
+// slash but must not contain any others.
+func shmPath(name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" || strings.Contains(name, "/") || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid shared memory name %q", name)
+	}
+	return filepath.Join(shmDir, name), nil
+}
+
+// WriteShm saves the table as the POSIX shared memory object name, replacing any existing object of that name.
+// Every process that opens the table with OpenShm shares the same physical memory. The object is written under
+// a temporary name and renamed into place, so readers never see a partly written table. It lasts until it is
+// removed with RemoveShm or the host restarts.
+func (t *Write) WriteShm(name string) error {
+	path, err := shmPath(name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(shmDir, ".statichash-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if _, err := t.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// OpenShm opens the table saved as the POSIX shared memory object name by WriteShm
+func OpenShm(name string, opts ...ReadOption) (*Read, error) {
+	path, err := shmPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewFrom(path, opts...)
+}
+
+// RemoveShm removes the POSIX shared memory object name. Tables already open stay usable, and the memory is
+// freed once they are all closed.
+func RemoveShm(name string) error {
+	path, err := shmPath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,35 @@
+package statichash
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShm(t *testing.T) {
+	if _, err := os.Stat(shmDir); err != nil {
+		t.Skipf("no shared memory: %v", err)
+	}
+	name := fmt.Sprintf("/statichash-test-%d", os.Getpid())
+
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	assert.NoError(t, tb.WriteShm(name))
+
+	r, err := OpenShm(name)
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, 10, r.Len())
+
+	// The open table survives the object being removed
+	assert.NoError(t, RemoveShm(name))
+	assert.NoError(t, r.Validate())
+	_, err = OpenShm(name)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.Error(t, tb.WriteShm("a/b"))
+	_, err = OpenShm("..")
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package statichash
+
+import "unsafe"
+
+// slotRecord is a slot of a table built WithInterleavedSlots. The hash, fingerprint and key offset of the slot
+// are stored together, so a lookup that probes a slot touches a single cache line rather than one in each of the
+// Hashes, Fingerprints and Keys sections. If the table is built WithInlineValues the value follows, padded to
+// 8 bytes.
+type slotRecord struct {
+	hash        hash
+	fingerprint uint8
+	_           [3]byte
+	key         keyOffset
+}
+
+// slotRecordSize is the size of a slotRecord in the file
+const slotRecordSize = int64(unsafe.Sizeof(slotRecord{}))
+
+// maxInlineValueSize is the largest value WithInlineValues can store in a slot
+const maxInlineValueSize = 16
+
+// slotSize returns the size of each slot of a table with interleaved slots
+func slotSize(flags, valueSize int64) int64 {
+	if flags&flagInlineValues != 0 {
+		return slotRecordSize + roundUp(valueSize, unsafe.Alignof(keyOffset(0)))
+	}
+	return slotRecordSize
+}
+
+// slot returns the record of slot i of a table with interleaved slots
+func (t *table) slot(i int) *slotRecord {
+	return (*slotRecord)(unsafe.Pointer(&t.slots[i*t.slotSize]))
+}
+
+// The accessors below read and write a slot whichever way the table lays out its slots. Code that doesn't
+// need to be fast should use them rather than the section slices.
+
+// hashAt returns the slot hash of slot i, which is zero if the slot is empty
+func (t *table) hashAt(i int) hash {
+	if t.slots != nil {
+		return t.slot(i).hash
+	}
+	return t.hashes[i]
+}
+
+// keyAt returns the offset of the key of slot i
+func (t *table) keyAt(i int) keyOffset {
+	if t.slots != nil {
+		return t.slot(i).key
+	}
+	return t.keys[i]
+}
+
+// hasFingerprints returns true if the table stores a fingerprint for each slot. Interleaved slots always have
+// one, as it fits in the padding of the slotRecord.
+func (t *table) hasFingerprints() bool {
+	return t.slots != nil || t.fingerprints != nil
+}
+
+// fingerprintAt returns the fingerprint of slot i. The table must have fingerprints.
+func (t *table) fingerprintAt(i int) uint8 {
+	if t.slots != nil {
+		return t.slot(i).fingerprint
+	}
+	return t.fingerprints[i]
+}
+
+// setSlot fills in slot i
+func (t *table) setSlot(i int, h hash, fp uint8, key keyOffset) {
+	if t.slots != nil {
+		*t.slot(i) = slotRecord{hash: h, fingerprint: fp, key: key}
+		return
+	}
+	t.hashes[i] = h
+	if t.fingerprints != nil {
+		t.fingerprints[i] = fp
+	}
+	t.keys[i] = key
+}
+
+// moveSlot moves the entry in slot from to slot to, leaving from empty. It doesn't move the value.
+func (t *table) moveSlot(to, from int) {
+	if t.slots != nil {
+		*t.slot(to) = *t.slot(from)
+		*t.slot(from) = slotRecord{}
+		return
+	}
+	t.hashes[to] = t.hashes[from]
+	if t.fingerprints != nil {
+		t.fingerprints[to] = t.fingerprints[from]
+	}
+	t.keys[to] = t.keys[from]
+	t.clearSlot(from)
+}
+
+// clearSlot empties slot i. It doesn't clear the value.
+func (t *table) clearSlot(i int) {
+	if t.slots != nil {
+		*t.slot(i) = slotRecord{}
+		return
+	}
+	t.hashes[i] = 0
+	if t.fingerprints != nil {
+		t.fingerprints[i] = 0
+	}
+	t.keys[i] = 0
+}
+
+// clearSlots empties every slot
+func (t *table) clearSlots() {
+	clear(t.slots)
+	clear(t.hashes)
+	clear(t.fingerprints)
+	clear(t.keys)
+}
@@ -0,0 +1,56 @@
+package statichash
+
+import (
+	"sort"
+	"sync"
+)
+
+// sortCache holds the occupied slots of a table sorted by key, computed the first time they're needed
+type sortCache struct {
+	once  sync.Once
+	slots []slotIndex
+}
+
+// sortedSlots returns the occupied slots of the table in key order
+func (t *table) sortedSlots() []slotIndex {
+	if t.sortCache == nil {
+		// We're still writing, so the table may change
+		return t.sortSlots()
+	}
+	if t.sorted != nil {
+		return t.sorted[:t.count]
+	}
+	t.sortCache.once.Do(func() {
+		t.sortCache.slots = t.sortSlots()
+	})
+	return t.sortCache.slots
+}
+
+// sortSlots sorts the occupied slots of the table by key
+func (t *table) sortSlots() []slotIndex {
+	s := slotsByKey{
+		slots: make([]slotIndex, 0, t.count),
+		keys:  make([]string, 0, t.count),
+	}
+	for i := range t.numItems {
+		h := t.hashAt(i)
+		if h != 0 {
+			s.slots = append(s.slots, slotIndex(i))
+			s.keys = append(s.keys, t.getKey(t.keyAt(i)))
+		}
+	}
+	sort.Sort(s)
+	return s.slots
+}
+
+type slotsByKey struct {
+	slots []slotIndex
+	keys  []string
+}
+
+func (s slotsByKey) Len() int           { return len(s.slots) }
+func (s slotsByKey) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s slotsByKey) Swap(i, j int) {
+	s.slots[i], s.slots[j] = s.slots[j], s.slots[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
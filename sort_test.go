@@ -0,0 +1,32 @@
+package statichash
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedIterate(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithSortedIndex()}} {
+		tb := buildTable(t, 100, opts...)
+
+		var buf bytes.Buffer
+		tb.Finalize()
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		tr, err := NewFromBytes(buf.Bytes())
+		assert.NoError(t, err)
+
+		for _, tab := range []*table{&tb.table, &tr.table} {
+			var keys []string
+			it := tab.SortedIterate()
+			for it.Next() {
+				keys = append(keys, it.Key())
+			}
+			assert.Len(t, keys, 100)
+			assert.True(t, sort.StringsAreSorted(keys))
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package sqldriver is a read-only database/sql driver for statichash table files. Importing it registers the
+// driver as "statichash", with the name of the table file as the data source name.
+//
+//	db, err := sql.Open("statichash", "/path/to/table")
+//	row := db.QueryRow("SELECT value FROM t WHERE key = ?", "mykey")
+//
+// The table has the columns key and value, where value is the raw bytes of the value. Use NewConnector with a
+// statichash.Schema to also expose the fields of the value as columns. The supported queries are
+//
+//	SELECT <columns> FROM <table> WHERE key = ?
+//	SELECT <columns> FROM <table>
+//
+// where <columns> is * or a comma-separated list of column names. The table name is ignored.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unsafe"
+
+	"github.com/philpearl/statichash"
+)
+
+func init() {
+	sql.Register("statichash", Driver{})
+}
+
+// errReadOnly is returned for anything that would change the table
+var errReadOnly = errors.New("statichash tables are read-only")
+
+// Driver is the statichash database/sql driver
+type Driver struct{}
+
+// Open opens the table file name. The only columns are key and value.
+func (Driver) Open(name string) (driver.Conn, error) {
+	return NewConnector(name, statichash.Schema{}).Connect(context.Background())
+}
+
+// Connector opens a table file with a Schema describing its values. Pass it to sql.OpenDB.
+type Connector struct {
+	filename string
+	schema   statichash.Schema
+}
+
+// NewConnector returns a Connector for the table file filename. Each field in schema becomes a column.
+func NewConnector(filename string, schema statichash.Schema) *Connector {
+	return &Connector{filename: filename, schema: schema}
+}
+
+// Connect opens the table
+func (c *Connector) Connect(context.Context) (driver.Conn, error) {
+	r, err := statichash.NewFrom(c.filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.schema.Check(r.ValueSize()); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &conn{r: r, schema: &c.schema}, nil
+}
+
+// Driver returns the statichash Driver
+func (c *Connector) Driver() driver.Driver {
+	return Driver{}
+}
+
+type conn struct {
+	r      *statichash.Read
+	schema *statichash.Schema
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &stmt{c: c, byKey: q.byKey}
+	if q.columns == nil {
+		s.columns = append(s.columns, columnKey, columnValue)
+		for i := range c.schema.Fields {
+			s.columns = append(s.columns, column{name: c.schema.Fields[i].Name, field: &c.schema.Fields[i]})
+		}
+		return s, nil
+	}
+	for _, name := range q.columns {
+		switch name {
+		case "key":
+			s.columns = append(s.columns, columnKey)
+		case "value":
+			s.columns = append(s.columns, columnValue)
+		default:
+			f := c.schema.Field(name)
+			if f == nil {
+				return nil, fmt.Errorf("no column %q", name)
+			}
+			s.columns = append(s.columns, column{name: name, field: f})
+		}
+	}
+	return s, nil
+}
+
+// bytes returns the bytes of the value p points to
+func (c *conn) bytes(p unsafe.Pointer) []byte {
+	return unsafe.Slice((*byte)(p), c.r.ValueSize())
+}
+
+func (c *conn) Close() error {
+	return c.r.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errReadOnly
+}
+
+// column is a column in a result set. field is nil for the key and value columns
+type column struct {
+	name  string
+	field *statichash.Field
+}
+
+var (
+	columnKey   = column{name: "key"}
+	columnValue = column{name: "value"}
+)
+
+type stmt struct {
+	c       *conn
+	columns []column
+	byKey   bool
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	if s.byKey {
+		return 1
+	}
+	return 0
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errReadOnly
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &rows{stmt: s}
+	if !s.byKey {
+		rows.it = s.c.r.Iterate()
+		return rows, nil
+	}
+
+	key, ok := args[0].(string)
+	if !ok {
+		if b, isBytes := args[0].([]byte); isBytes {
+			key, ok = string(b), true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("key must be a string, not %T", args[0])
+	}
+	if _, found := s.c.r.GetPtr(key); found {
+		rows.key = &key
+	}
+	return rows, nil
+}
+
+type rows struct {
+	stmt *stmt
+	// it is set for full scans
+	it *statichash.Iterator
+	// key is set for a lookup that found the key, and cleared once it has been returned
+	key *string
+}
+
+func (r *rows) Columns() []string {
+	names := make([]string, len(r.stmt.columns))
+	for i, c := range r.stmt.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	var key string
+	var value []byte
+	switch {
+	case r.it != nil:
+		if !r.it.Next() {
+			return io.EOF
+		}
+		key = r.it.Key()
+		value = r.stmt.c.bytes(r.it.Value())
+	case r.key != nil:
+		key = *r.key
+		r.key = nil
+		p, _ := r.stmt.c.r.GetPtr(key)
+		value = r.stmt.c.bytes(p)
+	default:
+		return io.EOF
+	}
+
+	for i, c := range r.stmt.columns {
+		switch {
+		case c.field != nil:
+			dest[i] = driverValue(c.field.Decode(value))
+		case c.name == "key":
+			dest[i] = key
+		default:
+			dest[i] = value
+		}
+	}
+	return nil
+}
+
+// driverValue converts a value from Field.Decode to a type database/sql accepts. uint64 values that don't fit
+// in an int64 are returned as decimal strings.
+func driverValue(v any) driver.Value {
+	if u, ok := v.(uint64); ok {
+		if u > math.MaxInt64 {
+			return strconv.FormatUint(u, 10)
+		}
+		return int64(u)
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+package sqldriver
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/philpearl/statichash"
+	"github.com/stretchr/testify/assert"
+)
+
+type value struct {
+	Count int32
+	Score float64
+}
+
+func writeTable(t *testing.T) string {
+	t.Helper()
+	tb, err := statichash.FromMapOf(map[string]value{
+		"hat":  {Count: 1, Score: 2.5},
+		"coat": {Count: 2, Score: 3.5},
+	})
+	assert.NoError(t, err)
+	name := filepath.Join(t.TempDir(), "table")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	_, err = tb.WriteTo(f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	return name
+}
+
+func TestDriver(t *testing.T) {
+	db, err := sql.Open("statichash", writeTable(t))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var v []byte
+	assert.NoError(t, db.QueryRow("SELECT value FROM t WHERE key = ?", "hat").Scan(&v))
+	assert.Len(t, v, 16)
+
+	err = db.QueryRow("select value from t where key = ?", "gloves").Scan(&v)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = db.Exec("DELETE FROM t")
+	assert.Error(t, err)
+	_, err = db.Query("SELECT nope FROM t")
+	assert.EqualError(t, err, `no column "nope"`)
+}
+
+func TestConnector(t *testing.T) {
+	schema, err := statichash.SchemaOf[value]()
+	assert.NoError(t, err)
+	db := sql.OpenDB(NewConnector(writeTable(t), schema))
+	defer db.Close()
+
+	var count int
+	var score float64
+	assert.NoError(t, db.QueryRow("SELECT Count, Score FROM t WHERE key = ?;", "coat").Scan(&count, &score))
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 3.5, score)
+
+	rows, err := db.Query("SELECT * FROM t")
+	assert.NoError(t, err)
+	cols, err := rows.Columns()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key", "value", "Count", "Score"}, cols)
+
+	seen := map[string]float64{}
+	for rows.Next() {
+		var key string
+		var v []byte
+		assert.NoError(t, rows.Scan(&key, &v, &count, &score))
+		seen[key] = score
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, map[string]float64{"hat": 2.5, "coat": 3.5}, seen)
+
+	type big struct{ A [32]byte }
+	schema, err = statichash.SchemaOf[big]()
+	assert.NoError(t, err)
+	db = sql.OpenDB(NewConnector(writeTable(t), schema))
+	defer db.Close()
+	assert.ErrorIs(t, db.Ping(), statichash.ErrValueSizeMismatch)
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want query
+		err  bool
+	}{
+		{text: "SELECT * FROM t", want: query{}},
+		{text: "select key, value from things where key=?", want: query{columns: []string{"key", "value"}, byKey: true}},
+		{text: "SELECT a.b FROM t;", want: query{columns: []string{"a.b"}}},
+		{text: "SELECT FROM t", err: true},
+		{text: "SELECT * FROM t WHERE value = ?", err: true},
+		{text: "SELECT * FROM t LIMIT 1", err: true},
+		{text: "UPDATE t SET value = ?", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			q, err := parseQuery(test.text)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, q)
+		})
+	}
+}
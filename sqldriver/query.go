@@ -0,0 +1,130 @@
+package sqldriver
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// query is a parsed SELECT statement
+type query struct {
+	// columns is nil for SELECT *
+	columns []string
+	// byKey is set if the query has WHERE key = ?
+	byKey bool
+}
+
+// parseQuery parses the few forms of SELECT statement we support
+func parseQuery(text string) (q query, err error) {
+	tokens := tokenize(text)
+	if len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	p := parser{tokens: tokens}
+
+	if !p.keyword("SELECT") {
+		return q, fmt.Errorf("only SELECT is supported: %q", text)
+	}
+	if p.next() == "*" {
+		p.pos++
+	} else {
+		for {
+			name := p.next()
+			if name == "" || !isIdent(name) {
+				return q, fmt.Errorf("expected a column name in %q", text)
+			}
+			q.columns = append(q.columns, name)
+			p.pos++
+			if p.next() != "," {
+				break
+			}
+			p.pos++
+		}
+	}
+
+	if !p.keyword("FROM") || !isIdent(p.next()) {
+		return q, fmt.Errorf("expected FROM <table> in %q", text)
+	}
+	p.pos++
+
+	if p.keyword("WHERE") {
+		if !strings.EqualFold(p.next(), "key") {
+			return q, fmt.Errorf("only WHERE key = ? is supported: %q", text)
+		}
+		p.pos++
+		if p.next() != "=" {
+			return q, fmt.Errorf("only WHERE key = ? is supported: %q", text)
+		}
+		p.pos++
+		if p.next() != "?" {
+			return q, fmt.Errorf("only WHERE key = ? is supported: %q", text)
+		}
+		p.pos++
+		q.byKey = true
+	}
+
+	if p.pos != len(p.tokens) {
+		return q, fmt.Errorf("unexpected %q in %q", p.next(), text)
+	}
+	return q, nil
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// next returns the next token without consuming it, or "" at the end
+func (p *parser) next() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// keyword consumes the next token if it is the keyword kw
+func (p *parser) keyword(kw string) bool {
+	if strings.EqualFold(p.next(), kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// tokenize splits text into identifiers and single punctuation characters
+func tokenize(text string) []string {
+	var tokens []string
+	start := -1
+	for i, r := range text {
+		if isIdentRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, text[start:i])
+			start = -1
+		}
+		if !unicode.IsSpace(r) {
+			tokens = append(tokens, string(r))
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isIdent(s string) bool {
+	for _, r := range s {
+		if !isIdentRune(r) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,95 @@
+// Package statichashtest helps tests of code that reads statichash tables build the tables they need.
+package statichashtest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/statichash"
+)
+
+// BuildTemp builds a table holding entries into a file in a temporary directory, and opens it with NewFrom. The
+// table is closed when the test finishes. The values must all be the same size, and the keys are added in
+// sorted order. The test fails if the table can't be built or opened.
+func BuildTemp(t testing.TB, entries map[string][]byte, opts ...statichash.Option) *statichash.Read {
+	t.Helper()
+
+	keys := sortedKeys(entries)
+	var valueSize int
+	var keyLength int64
+	for i, key := range keys {
+		if i == 0 {
+			valueSize = len(entries[key])
+		} else if len(entries[key]) != valueSize {
+			t.Fatalf("statichashtest: value for %q is %d bytes, but the value for %q is %d", key, len(entries[key]), keys[0], valueSize)
+		}
+		keyLength += int64(len(key))
+	}
+
+	w := statichash.New(max(len(keys), 1), int64(valueSize), keyLength, opts...)
+	for _, key := range keys {
+		if err := w.Set(key, unsafe.Pointer(unsafe.SliceData(entries[key]))); err != nil {
+			t.Fatalf("statichashtest: setting %q: %v", key, err)
+		}
+	}
+	w.Finalize()
+
+	filename := filepath.Join(t.TempDir(), "table")
+	if err := w.WriteFile(filename); err != nil {
+		t.Fatalf("statichashtest: writing %s: %v", filename, err)
+	}
+	r, err := statichash.NewFrom(filename)
+	if err != nil {
+		t.Fatalf("statichashtest: opening %s: %v", filename, err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// BuildTempStrings is BuildTemp for a table of strings. It opens the table with OpenStringTable.
+func BuildTempStrings(t testing.TB, entries map[string]string, opts ...statichash.Option) *statichash.StringTable {
+	t.Helper()
+
+	keys := sortedKeys(entries)
+	var length int64
+	for _, key := range keys {
+		length += int64(len(key) + len(entries[key]))
+	}
+	s := statichash.NewStringTable(max(len(keys), 1), length, opts...)
+	for _, key := range keys {
+		if err := s.Set(key, entries[key]); err != nil {
+			t.Fatalf("statichashtest: setting %q: %v", key, err)
+		}
+	}
+	s.Finalize()
+
+	filename := filepath.Join(t.TempDir(), "table")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("statichashtest: %v", err)
+	}
+	if _, err := s.WriteTo(f); err != nil {
+		t.Fatalf("statichashtest: writing %s: %v", filename, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("statichashtest: writing %s: %v", filename, err)
+	}
+	r, err := statichash.OpenStringTable(filename)
+	if err != nil {
+		t.Fatalf("statichashtest: opening %s: %v", filename, err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func sortedKeys[V any](entries map[string]V) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,31 @@
+package statichashtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTemp(t *testing.T) {
+	r := BuildTemp(t, map[string][]byte{
+		"a": {1, 2, 3, 4},
+		"b": {5, 6, 7, 8},
+	})
+	assert.Equal(t, 2, r.Len())
+	v, ok := r.GetValue("b")
+	assert.True(t, ok)
+	assert.Equal(t, []byte{5, 6, 7, 8}, v)
+	_, ok = r.GetValue("c")
+	assert.False(t, ok)
+
+	empty := BuildTemp(t, nil)
+	assert.Equal(t, 0, empty.Len())
+}
+
+func TestBuildTempStrings(t *testing.T) {
+	s := BuildTempStrings(t, map[string]string{"a": "apple", "b": "banana"})
+	assert.Equal(t, 2, s.Len())
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "apple", v)
+}
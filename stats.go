@@ -0,0 +1,79 @@
+package statichash
+
+import "math/bits"
+
+// Stats describes how full a table is, how its keys are distributed and how big each part of its file is
+type Stats struct {
+	// Slots is the number of slots in the table, and Entries the number that are occupied
+	Slots   int
+	Entries int
+	// LoadFactor is the proportion of slots that are occupied
+	LoadFactor float64
+	// ProbeLengths counts the entries by the number of slots a lookup of their key examines. ProbeLengths[1]
+	// is the number of entries found in their home slot. ProbeLengths[0] is always zero.
+	ProbeLengths []int
+	// KeyLengths counts the keys by length in bytes, in powers of two. KeyLengths[0] counts empty keys, and
+	// KeyLengths[i] counts keys of at least 1<<(i-1) bytes and less than 1<<i bytes.
+	KeyLengths []int
+	// MinKeyLength, MaxKeyLength and MeanKeyLength summarise the key lengths in bytes
+	MinKeyLength  int
+	MaxKeyLength  int
+	MeanKeyLength float64
+	// Sections lists the sections of the file in the order they appear
+	Sections []SectionStats
+}
+
+// SectionStats describes where a section is in the file
+type SectionStats struct {
+	Section Section
+	Offset  int64
+	Length  int64
+}
+
+// Stats examines every slot of the table to describe it. It reads every key, so it can take a while for a large
+// table.
+func (t *table) Stats() Stats {
+	s := Stats{
+		Slots:      t.numItems,
+		Entries:    t.count,
+		LoadFactor: float64(t.count) / float64(t.numItems),
+	}
+
+	var seen, totalKeyLength int
+	for i := range t.numItems {
+		h := t.hashAt(i)
+		if h == 0 {
+			continue
+		}
+		probe := t.probeLength(i)
+		for len(s.ProbeLengths) <= probe {
+			s.ProbeLengths = append(s.ProbeLengths, 0)
+		}
+		s.ProbeLengths[probe]++
+
+		l := len(t.getKey(t.keyAt(i)))
+		bucket := bits.Len(uint(l))
+		for len(s.KeyLengths) <= bucket {
+			s.KeyLengths = append(s.KeyLengths, 0)
+		}
+		s.KeyLengths[bucket]++
+		if seen == 0 || l < s.MinKeyLength {
+			s.MinKeyLength = l
+		}
+		s.MaxKeyLength = max(s.MaxKeyLength, l)
+		totalKeyLength += l
+		seen++
+	}
+	if seen > 0 {
+		s.MeanKeyLength = float64(totalKeyLength) / float64(seen)
+	}
+
+	for _, sec := range coreSections {
+		start, end := t.layout.section(sec)
+		s.Sections = append(s.Sections, SectionStats{Section: sec, Offset: start, Length: end - start})
+	}
+	for _, e := range t.sections {
+		s.Sections = append(s.Sections, SectionStats{Section: Section(e.kind), Offset: e.offset, Length: e.length})
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package statichash
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	tb := buildTable(t, 100)
+	var v int
+	assert.NoError(t, tb.Set("", unsafe.Pointer(&v)))
+	s := tb.Stats()
+
+	assert.Equal(t, 128, s.Slots)
+	assert.Equal(t, 101, s.Entries)
+	assert.InDelta(t, 101.0/128, s.LoadFactor, 1e-9)
+
+	var probed int
+	for _, n := range s.ProbeLengths {
+		probed += n
+	}
+	assert.Equal(t, 101, probed)
+	assert.Zero(t, s.ProbeLengths[0])
+	assert.Len(t, s.ProbeLengths, tb.maxProbeLength()+1)
+
+	// "" has length 0, key1 to key9 are 4 bytes, the rest 5 or 6, all in the 4 to 7 bucket
+	assert.Equal(t, []int{1, 0, 0, 100}, s.KeyLengths)
+	assert.Equal(t, 0, s.MinKeyLength)
+	assert.Equal(t, 6, s.MaxKeyLength)
+
+	assert.Len(t, s.Sections, len(coreSections))
+	assert.Equal(t, SectionHashes, s.Sections[0].Section)
+	assert.Equal(t, int64(128*4), s.Sections[0].Length)
+}
@@ -0,0 +1,141 @@
+package statichash
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// stringValueSize is the value size of a table whose values are strings. Each value is the offset of the
+// string in the key data.
+const stringValueSize = int(unsafe.Sizeof(keyOffset(0)))
+
+// maxInlineStringSize is the largest value size of a table built WithInlineStrings. The last byte of each value
+// holds the length of an inline string, or overflowString, so it must fit in a byte.
+const maxInlineStringSize = 248
+
+// overflowString marks a value of a table built WithInlineStrings whose string is stored in the key data. The
+// first 8 bytes of the value are then its offset, as in any other string table.
+const overflowString = 0xff
+
+// inlineStringSize returns the value size of a table built WithInlineStrings(n): room for n bytes and the length,
+// rounded up so that the offsets of longer strings stay aligned.
+func inlineStringSize(n int) int {
+	return max((n+1+7)&^7, 2*stringValueSize)
+}
+
+// stringTableValueSize returns the value size of a string table built with opts
+func stringTableValueSize(opts []Option) int {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.flags&flagInlineStrings != 0 {
+		return inlineStringSize(o.inlineStrings)
+	}
+	return stringValueSize
+}
+
+// holdsStrings returns true if the values of the table can be strings
+func (t *table) holdsStrings() bool {
+	return t.valueSize == stringValueSize || t.flags&flagInlineStrings != 0
+}
+
+// SetString sets the value for key to the string value. The string is stored alongside the keys, so
+// totalKeyLength passed to New must include the length of the values as well as the keys. The table must have
+// been created with a valueSize of 8, or built WithInlineStrings, in which case short strings are stored in the
+// value itself and don't need room with the keys. The table is marked as holding strings, so that GetString
+// can tell its values from integers. Don't mix SetString with Set in the same table.
+func (t *Write) SetString(key, value string) error {
+	if !t.holdsStrings() {
+		return fmt.Errorf("%w: string values need a value size of %d, not %d", ErrValueSizeMismatch, stringValueSize, t.valueSize)
+	}
+	if t.flags&flagHashOnly != 0 {
+		return fmt.Errorf("%w: string values are stored with the keys, so can't be set in a table built WithHashOnly", ErrNoKeys)
+	}
+	if t.finalized {
+		return ErrFinalized
+	}
+	t.flags |= flagStringValues
+	var record [maxInlineStringSize]byte
+	if t.flags&flagInlineStrings != 0 && len(value) < t.valueSize {
+		copy(record[:], value)
+		record[t.valueSize-1] = byte(len(value))
+		return t.Set(key, unsafe.Pointer(&record))
+	}
+	if !t.hasKeySpace(value) {
+		if !t.autoGrow {
+			return fmt.Errorf("%w: no room for the value of key %q", ErrKeySpaceExhausted, key)
+		}
+		t.growKeyData(len(value))
+	}
+	fileByteOrder.PutUint64(record[:], uint64(t.addKey(value)))
+	if t.flags&flagInlineStrings != 0 {
+		record[t.valueSize-1] = overflowString
+	}
+	return t.Set(key, unsafe.Pointer(&record))
+}
+
+// GetString returns the string value for key from a table built with SetString. The string refers directly to
+// the table's memory, so it is not valid after the table is closed. If the table is mapped in windows the
+// string is a copy. It returns false if the table doesn't hold strings, or if the value doesn't refer to a
+// string within the key data.
+func (t *table) GetString(key string) (string, bool) {
+	if t == nil || t.flags&flagStringValues == 0 {
+		return "", false
+	}
+	index, found := t.find(key, t.hashKey(key))
+	if !found {
+		return "", false
+	}
+	value, err := t.readValue(index)
+	if err != nil {
+		return "", false
+	}
+	if t.flags&flagInlineStrings != 0 {
+		if s, ok := inlineString(value); ok {
+			return s, true
+		}
+	}
+	s, err := t.checkedKey(keyOffset(fileByteOrder.Uint64(value)))
+	return s, err == nil
+}
+
+// stringValue returns the string stored as the value of slot index
+func (t *table) stringValue(index int) string {
+	value := t.value(index)
+	if t.flags&flagInlineStrings != 0 {
+		if s, ok := inlineString(value); ok {
+			return s
+		}
+	}
+	return t.getKey(*(*keyOffset)(unsafe.Pointer(unsafe.SliceData(value))))
+}
+
+// inlineString returns the string stored in value by a table built WithInlineStrings. It returns false if the
+// string is in the key data instead.
+func inlineString(value []byte) (string, bool) {
+	n := value[len(value)-1]
+	if n == overflowString {
+		return "", false
+	}
+	s := value[:n]
+	return unsafe.String(unsafe.SliceData(s), len(s)), true
+}
+
+// SetBytes sets the value for key to a variable-length blob, such as a serialized protobuf message. Blobs are
+// stored alongside the keys exactly as SetString stores strings, so the same rules apply to totalKeyLength and
+// the value size. Read the blob back with GetBytes.
+func (t *Write) SetBytes(key string, value []byte) error {
+	return t.SetString(key, unsafe.String(unsafe.SliceData(value), len(value)))
+}
+
+// GetBytes returns the blob value for key from a table built with SetBytes or SetString. The slice refers
+// directly to the table's memory, so it must not be modified and is not valid after the table is closed. If the
+// table is mapped in windows the slice is a copy.
+func (t *table) GetBytes(key string) ([]byte, bool) {
+	s, ok := t.GetString(key)
+	if !ok {
+		return nil, false
+	}
+	return unsafe.Slice(unsafe.StringData(s), len(s)), true
+}
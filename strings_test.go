@@ -0,0 +1,205 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetString(t *testing.T) {
+	tb := New(100, 8, 2000)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, tb.SetString(fmt.Sprintf("key%d", i), fmt.Sprintf("value %d", i)))
+	}
+	assert.NoError(t, tb.SetString("key7", "changed"))
+
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	v, ok := tr.GetString("key42")
+	assert.True(t, ok)
+	assert.Equal(t, "value 42", v)
+	v, ok = tr.GetString("key7")
+	assert.True(t, ok)
+	assert.Equal(t, "changed", v)
+	_, ok = tr.GetString("nope")
+	assert.False(t, ok)
+
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		tr.GetString("key42")
+	}))
+
+	name := writeTempTable(t, tb)
+	tw, err := NewFrom(name, WithWindowedMapping(4096, 1))
+	assert.NoError(t, err)
+	defer tw.Close()
+	v, ok = tw.GetString("key99")
+	assert.True(t, ok)
+	assert.Equal(t, "value 99", v)
+
+	assert.ErrorIs(t, New(10, 4, 100).SetString("a", "b"), ErrValueSizeMismatch)
+}
+
+func TestGetStringNotStrings(t *testing.T) {
+	tb := New(10, 8, 100)
+	v := int64(1 << 40)
+	assert.NoError(t, tb.Set("a", unsafe.Pointer(&v)))
+	tb.Finalize()
+	_, ok := readBack(t, tb).GetString("a")
+	assert.False(t, ok)
+
+	tb = New(10, 8, 100)
+	assert.NoError(t, tb.SetString("a", "b"))
+	tb.Finalize()
+	r := readBack(t, tb)
+	index, found := r.find("a", r.hashKey("a"))
+	assert.True(t, found)
+	fileByteOrder.PutUint64(r.value(index), 1<<40)
+	_, ok = r.GetString("a")
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, New(10, 8, 0, WithHashOnly()).SetString("a", "b"), ErrNoKeys)
+}
+
+func TestSetStringAutoGrow(t *testing.T) {
+	tb := New(2, 8, 4, WithAutoGrow(), WithInsertionOrder())
+	for i := 0; i < 200; i++ {
+		assert.NoError(t, tb.SetString(fmt.Sprintf("key%d", i), fmt.Sprintf("a long value for key %d", i)))
+	}
+	for i := 0; i < 200; i++ {
+		v, ok := tb.GetString(fmt.Sprintf("key%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("a long value for key %d", i), v)
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	tb := New(10, 8, 1000, WithBlobValues())
+	blobs := map[string][]byte{
+		"short": {1, 2, 3},
+		"long":  bytes.Repeat([]byte{0, 0xff}, 200),
+		"empty": {},
+	}
+	for k, v := range blobs {
+		assert.NoError(t, tb.SetBytes(k, v))
+	}
+
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	for k, want := range blobs {
+		v, ok := tr.GetBytes(k)
+		assert.True(t, ok)
+		assert.Len(t, v, len(want))
+		assert.Equal(t, cap(v), len(v))
+		if len(want) > 0 {
+			assert.Equal(t, want, v)
+		}
+	}
+	_, ok := tr.GetBytes("nope")
+	assert.False(t, ok)
+
+	st, err := StringTableFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	s, ok := st.Get("short")
+	assert.True(t, ok)
+	assert.Equal(t, "\x01\x02\x03", s)
+}
+
+func TestInlineStrings(t *testing.T) {
+	value := func(i int) string {
+		if i%3 == 0 {
+			return fmt.Sprintf("a much longer value that has to go with the keys %d", i)
+		}
+		return fmt.Sprintf("v%d", i)
+	}
+	st := NewStringTable(100, 2000, WithInlineStrings(20))
+	assert.Equal(t, 24, st.w.valueSize)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, st.Set(fmt.Sprintf("key%d", i), value(i)))
+	}
+	edges := map[string]string{
+		"empty":    "",
+		"fits":     strings.Repeat("x", 23),
+		"overflow": strings.Repeat("y", 24),
+	}
+	for k, v := range edges {
+		assert.NoError(t, st.Set(k, v))
+	}
+	st.Finalize()
+	var buf bytes.Buffer
+	_, err := st.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	// Only the long values are stored with the keys
+	plain := NewStringTable(100, 4000)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, plain.Set(fmt.Sprintf("key%d", i), value(i)))
+	}
+	assert.Less(t, st.w.keyOffset, plain.w.keyOffset)
+
+	r, err := StringTableFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, r.r.Validate())
+	check := func(t *testing.T, get func(key string) (string, bool)) {
+		for i := 0; i < 100; i++ {
+			v, ok := get(fmt.Sprintf("key%d", i))
+			assert.True(t, ok)
+			assert.Equal(t, value(i), v)
+		}
+		for k, want := range edges {
+			v, ok := get(k)
+			assert.True(t, ok)
+			assert.Equal(t, want, v)
+		}
+	}
+	t.Run("write", func(t *testing.T) { check(t, st.Get) })
+	t.Run("read", func(t *testing.T) { check(t, r.Get) })
+	t.Run("windowed", func(t *testing.T) {
+		tw, err := NewFrom(writeTempTable(t, st.w), WithWindowedMapping(4096, 2))
+		assert.NoError(t, err)
+		defer tw.Close()
+		check(t, tw.GetString)
+	})
+	t.Run("salvage", func(t *testing.T) {
+		w, lost, err := Salvage(buf.Bytes())
+		assert.NoError(t, err)
+		assert.Zero(t, lost)
+		check(t, w.GetString)
+	})
+	t.Run("migrate", func(t *testing.T) {
+		w, err := Migrate(r.r, 200)
+		assert.NoError(t, err)
+		assert.Equal(t, stringValueSize, w.valueSize)
+		check(t, w.GetString)
+		plain := readBack(t, w)
+		assert.True(t, Equal(r.r, plain))
+
+		w, err = Migrate(plain, 200, WithInlineStrings(7))
+		assert.NoError(t, err)
+		assert.Equal(t, 16, w.valueSize)
+		check(t, w.GetString)
+	})
+
+	// Inline strings are only found in string tables
+	data := bytes.Clone(buf.Bytes())
+	editHeader(t, data, func(h *header) { h.flags &^= flagStringValues })
+	_, err = NewFromBytes(data)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	assert.Panics(t, func() { New(10, 8, 100, WithInlineStrings(20)) })
+	assert.Panics(t, func() { NewStringTable(10, 100, WithInlineStrings(maxInlineStringSize)) })
+	assert.Panics(t, func() { NewStringTable(10, 100, WithInlineStrings(8), WithColumns(8, 8)) })
+}
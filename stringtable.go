@@ -0,0 +1,99 @@
+package statichash
+
+import "io"
+
+// StringTable is a table with string keys and string values. The value strings are stored with the keys, and
+// Get returns them without allocating. Create one to write with NewStringTable, or open one written earlier
+// with OpenStringTable or StringTableFromBytes.
+type StringTable struct {
+	t *table
+	// w is set if the table is for writing
+	w *Write
+	// r is set if the table was read from a file
+	r *Read
+}
+
+// NewStringTable creates a StringTable for writing. totalLength is the total length of all the keys and values.
+// Values stored WithInlineStrings needn't be included.
+func NewStringTable(numItems int, totalLength int64, opts ...Option) *StringTable {
+	w := New(numItems, int64(stringTableValueSize(opts)), totalLength, append(opts, withStringValues())...)
+	return &StringTable{t: &w.table, w: w}
+}
+
+// OpenStringTable opens a StringTable saved to filename
+func OpenStringTable(filename string, opts ...ReadOption) (*StringTable, error) {
+	r, err := NewFrom(filename, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return stringTableFrom(r)
+}
+
+// StringTableFromBytes is like OpenStringTable, but reads the table from data, as NewFromBytes does
+func StringTableFromBytes(data []byte, opts ...ReadOption) (*StringTable, error) {
+	r, err := NewFromBytes(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return stringTableFrom(r)
+}
+
+func stringTableFrom(r *Read) (*StringTable, error) {
+	if r.flags&flagStringValues == 0 {
+		r.Close()
+		return nil, ErrNotStringTable
+	}
+	return &StringTable{t: &r.table, r: r}, nil
+}
+
+// Set sets the value for key. It returns ErrReadOnly if the table was read from a file.
+func (s *StringTable) Set(key, value string) error {
+	if s.w == nil {
+		return ErrReadOnly
+	}
+	return s.w.SetString(key, value)
+}
+
+// Get returns the value for key. The string refers directly to the table's memory, so it is not valid after
+// the table is closed.
+func (s *StringTable) Get(key string) (string, bool) {
+	return s.t.GetString(key)
+}
+
+// Len returns the number of entries in the table
+func (s *StringTable) Len() int {
+	return s.t.Len()
+}
+
+// Range calls f for each entry in the table, in the order Iterate visits them, until f returns false
+func (s *StringTable) Range(f func(key, value string) bool) {
+	for it := s.t.Iterate(); it.Next(); {
+		if !f(it.Key(), s.t.stringValue(it.index)) {
+			return
+		}
+	}
+}
+
+// Finalize seals a table created with NewStringTable so it can be saved, as Write.Finalize does
+func (s *StringTable) Finalize() BuildReport {
+	if s.w == nil {
+		return BuildReport{}
+	}
+	return s.w.Finalize()
+}
+
+// WriteTo saves a table created with NewStringTable once it has been finalized
+func (s *StringTable) WriteTo(w io.Writer) (int64, error) {
+	if s.w == nil {
+		return 0, ErrReadOnly
+	}
+	return s.w.WriteTo(w)
+}
+
+// Close releases the resources of a table that was read from a file
+func (s *StringTable) Close() error {
+	if s.r == nil {
+		return nil
+	}
+	return s.r.Close()
+}
@@ -0,0 +1,80 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringTable(t *testing.T) {
+	st := NewStringTable(50, 1000, WithInsertionOrder())
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, st.Set(fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i*2)))
+	}
+	v, ok := st.Get("k3")
+	assert.True(t, ok)
+	assert.Equal(t, "v6", v)
+
+	var buf bytes.Buffer
+	st.Finalize()
+	_, err := st.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	rt, err := StringTableFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	defer rt.Close()
+	assert.Equal(t, 50, rt.Len())
+	v, ok = rt.Get("k49")
+	assert.True(t, ok)
+	assert.Equal(t, "v98", v)
+	_, ok = rt.Get("k50")
+	assert.False(t, ok)
+
+	var i int
+	rt.Range(func(key, value string) bool {
+		assert.Equal(t, fmt.Sprintf("k%d", i), key)
+		assert.Equal(t, fmt.Sprintf("v%d", i*2), value)
+		i++
+		return i < 10
+	})
+	assert.Equal(t, 10, i)
+
+	assert.ErrorIs(t, rt.Set("a", "b"), ErrReadOnly)
+	_, err = rt.WriteTo(&buf)
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	rt, err = OpenStringTable(writeTempTable(t, st.w))
+	assert.NoError(t, err)
+	v, _ = rt.Get("k10")
+	assert.Equal(t, "v20", v)
+	assert.NoError(t, rt.Close())
+
+	_, err = OpenStringTable(writeTempTable(t, buildTable(t, 10)))
+	assert.ErrorIs(t, err, ErrNotStringTable)
+}
+
+func TestStringTableEqual(t *testing.T) {
+	read := func(keys ...string) *Read {
+		st := NewStringTable(10, 100)
+		for _, k := range keys {
+			assert.NoError(t, st.Set(k, "value of "+k))
+		}
+		var buf bytes.Buffer
+		st.Finalize()
+		_, err := st.WriteTo(&buf)
+		assert.NoError(t, err)
+		r, err := NewFromBytes(buf.Bytes())
+		assert.NoError(t, err)
+		return r
+	}
+
+	// The value strings are at different offsets in the two tables
+	a, b := read("x", "y", "z"), read("z", "y", "x")
+	assert.True(t, Equal(a, b))
+	assert.False(t, Equal(a, read("x", "y", "w")))
+
+	var buf bytes.Buffer
+	assert.Error(t, WritePatch(&buf, a, b))
+}
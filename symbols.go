@@ -0,0 +1,112 @@
+package statichash
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/philpearl/symboltab"
+)
+
+/*
+Strings that repeat across many values, such as country names or categories, can be interned with Write.Intern.
+Each distinct string is stored once, in a symbols section after the key data, and values hold its Symbol, a
+compact ID that doesn't need pointers. Read.Symbols translates the IDs back to strings.
+
+The symbols section is a count n, then the end offset of each of the n strings within the string bytes, all as
+int64, then the string bytes. Symbol i is string i-1.
+*/
+
+// Symbol is the ID of a string interned with Write.Intern. The zero Symbol is the empty string.
+type Symbol uint32
+
+// Intern returns the Symbol for s, adding s to the table's symbols if it isn't already there. Store the Symbol
+// in a value in place of the string, and read the string back with Symbols.String.
+func (t *Write) Intern(s string) (Symbol, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if t.symbols == nil {
+		t.symbols = symboltab.New(64)
+	}
+	seq, found := t.symbols.StringToSequence(s, false)
+	if found {
+		return Symbol(seq), nil
+	}
+	if t.finalized {
+		return 0, ErrFinalized
+	}
+	seq, _ = t.symbols.StringToSequence(s, true)
+	return Symbol(seq), nil
+}
+
+// encodeSymbols returns the symbols section for the interned strings
+func (t *Write) encodeSymbols() []byte {
+	n := t.symbols.Len()
+	var length int
+	for seq := 1; seq <= n; seq++ {
+		length += len(t.symbols.SequenceToString(int32(seq)))
+	}
+	data := make([]byte, 8*(n+1), 8*(n+1)+length)
+	fileByteOrder.PutUint64(data, uint64(n))
+	for seq := 1; seq <= n; seq++ {
+		data = append(data, t.symbols.SequenceToString(int32(seq))...)
+		fileByteOrder.PutUint64(data[8*seq:], uint64(len(data)-8*(n+1)))
+	}
+	return data
+}
+
+// Symbols translates the Symbols stored in a table's values back to strings
+type Symbols struct {
+	ends    []int64
+	strings []byte
+}
+
+// Symbols returns the strings interned when the table was built. The strings refer directly to the table's
+// memory, so they are not valid after the table is closed. Symbols checks the whole section, so call it once
+// and keep the result rather than calling it for each lookup.
+func (r *Read) Symbols() (*Symbols, error) {
+	data, ok, err := r.section(SectionSymbols)
+	if !ok || err != nil {
+		return &Symbols{}, err
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: symbols section is only %d bytes", ErrCorrupt, len(data))
+	}
+	n := fileByteOrder.Uint64(data)
+	if n > uint64(len(data)/8-1) {
+		return nil, fmt.Errorf("%w: %d symbols don't fit in a section of %d bytes", ErrCorrupt, n, len(data))
+	}
+	s := Symbols{
+		ends:    unsafe.Slice((*int64)(unsafe.Pointer(&data[8])), n),
+		strings: data[8*(n+1):],
+	}
+	var prev int64
+	for i, end := range s.ends {
+		if end < prev || end > int64(len(s.strings)) {
+			return nil, fmt.Errorf("%w: symbol %d ends at %d, outside the section", ErrCorrupt, i+1, end)
+		}
+		prev = end
+	}
+	return &s, nil
+}
+
+// Len returns the number of symbols
+func (s *Symbols) Len() int {
+	return len(s.ends)
+}
+
+// String returns the string for id, and false if there is no such symbol
+func (s *Symbols) String(id Symbol) (string, bool) {
+	if id == 0 {
+		return "", true
+	}
+	if int(id) > len(s.ends) {
+		return "", false
+	}
+	var start int64
+	if id > 1 {
+		start = s.ends[id-2]
+	}
+	b := s.strings[start:s.ends[id-1]]
+	return unsafe.String(unsafe.SliceData(b), len(b)), true
+}
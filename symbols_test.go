@@ -0,0 +1,77 @@
+package statichash
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymbols(t *testing.T) {
+	type place struct {
+		Country  Symbol
+		Category Symbol
+		Count    int32
+	}
+	countries := []string{"France", "Germany", "", "Japan"}
+
+	tb := New(100, int64(unsafe.Sizeof(place{})), 1000)
+	for i := 0; i < 100; i++ {
+		country, err := tb.Intern(countries[i%len(countries)])
+		assert.NoError(t, err)
+		category, err := tb.Intern(fmt.Sprintf("category %d", i%7))
+		assert.NoError(t, err)
+		assert.NoError(t, tb.SetValue(fmt.Sprintf("key%d", i), place{Country: country, Category: category, Count: int32(i)}))
+	}
+	tb.Finalize()
+
+	// Known strings can still be looked up, but new ones can't be added
+	s, err := tb.Intern("Japan")
+	assert.NoError(t, err)
+	assert.NotZero(t, s)
+	_, err = tb.Intern("Spain")
+	assert.ErrorIs(t, err, ErrFinalized)
+
+	var buf bytes.Buffer
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	symbols, err := r.Symbols()
+	assert.NoError(t, err)
+	assert.Equal(t, 3+7, symbols.Len())
+	for i := 0; i < 100; i++ {
+		p, ok := r.GetPtr(fmt.Sprintf("key%d", i))
+		if !assert.True(t, ok) {
+			continue
+		}
+		v := (*place)(p)
+		country, ok := symbols.String(v.Country)
+		assert.True(t, ok)
+		assert.Equal(t, countries[i%len(countries)], country)
+		category, ok := symbols.String(v.Category)
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("category %d", i%7), category)
+	}
+	_, ok := symbols.String(Symbol(symbols.Len() + 1))
+	assert.False(t, ok)
+}
+
+func TestNoSymbols(t *testing.T) {
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	symbols, err := r.Symbols()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, symbols.Len())
+	_, ok := symbols.String(1)
+	assert.False(t, ok)
+}
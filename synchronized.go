@@ -0,0 +1,86 @@
+package statichash
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// SyncWrite wraps a Write with a mutex so that many goroutines can add to the table at once. Lookups return
+// copies of values, as a pointer into the table could be invalidated by a concurrent Set that grows it.
+type SyncWrite struct {
+	mu sync.Mutex
+	t  *Write
+}
+
+// Synchronized returns a wrapper around the table that is safe for concurrent use. The table must not be used
+// directly while the wrapper is in use.
+func (t *Write) Synchronized() *SyncWrite {
+	return &SyncWrite{t: t}
+}
+
+// Set is Write.Set under the lock
+func (s *SyncWrite) Set(key string, val unsafe.Pointer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.Set(key, val)
+}
+
+// SetValue is Write.SetValue under the lock
+func (s *SyncWrite) SetValue(key string, v any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.SetValue(key, v)
+}
+
+// SetString is Write.SetString under the lock
+func (s *SyncWrite) SetString(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.SetString(key, value)
+}
+
+// SetBytes is Write.SetBytes under the lock
+func (s *SyncWrite) SetBytes(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.SetBytes(key, value)
+}
+
+// Upsert calls fn with a copy of the value for key, or zeroed bytes if key isn't in the table, and stores the
+// value as fn leaves it. No other change can come between reading and storing the value, so Upsert can be used
+// to build up values such as counts from many goroutines.
+func (s *SyncWrite) Upsert(key string, fn func(value []byte, found bool)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value := make([]byte, s.t.valueSize)
+	old, found := s.t.GetValue(key)
+	copy(value, old)
+	fn(value, found)
+	return s.t.Set(key, bytesPointer(value))
+}
+
+// GetValue returns a copy of the value for key, and whether the key was found
+func (s *SyncWrite) GetValue(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.t.GetValue(key)
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), v...), true
+}
+
+// Len returns the number of entries in the table
+func (s *SyncWrite) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.Len()
+}
+
+// Write returns the wrapped table once the goroutines adding to it have finished, so that it can be finalized
+// and saved
+func (s *SyncWrite) Write() *Write {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t
+}
@@ -0,0 +1,46 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynchronized(t *testing.T) {
+	s := New(100, 8, 1000, WithAutoGrow()).Synchronized()
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				v := int64(g*1000 + i)
+				assert.NoError(t, s.Set(fmt.Sprintf("key%d-%d", g, i), unsafe.Pointer(&v)))
+				assert.NoError(t, s.Upsert(fmt.Sprintf("count%d", i%10), func(value []byte, found bool) {
+					binary.NativeEndian.PutUint64(value, binary.NativeEndian.Uint64(value)+1)
+				}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8*500+10, s.Len())
+	for i := range 10 {
+		v, ok := s.GetValue(fmt.Sprintf("count%d", i))
+		if assert.True(t, ok) {
+			assert.Equal(t, uint64(8*50), binary.NativeEndian.Uint64(v))
+		}
+	}
+
+	tb := s.Write()
+	tb.Finalize()
+	p, ok := tb.GetPtr("key3-7")
+	if assert.True(t, ok) {
+		assert.Equal(t, int64(3007), *(*int64)(p))
+	}
+}
@@ -0,0 +1,52 @@
+package statichash
+
+// SyncMap presents a Typed table with the read methods of a sync.Map, so code written against sync.Map can
+// switch to a static table with few changes. Keys that are not strings are never found.
+type SyncMap struct {
+	get       func(key string) (any, bool)
+	has       func(key string) bool
+	rangeFunc func(f func(key, value any) bool)
+}
+
+// NewSyncMap wraps t as a SyncMap
+func NewSyncMap[T any](t *Typed[T]) *SyncMap {
+	return &SyncMap{
+		get: func(key string) (any, bool) {
+			v, ok := t.Get(key)
+			if !ok {
+				return nil, false
+			}
+			return v, true
+		},
+		has: func(key string) bool {
+			_, ok := t.r.GetPtr(key)
+			return ok
+		},
+		rangeFunc: func(f func(key, value any) bool) {
+			t.Range(func(key string, value T) bool {
+				return f(key, value)
+			})
+		},
+	}
+}
+
+// Load returns the value stored for key, or nil if there is none. ok reports whether the key was found.
+func (m *SyncMap) Load(key any) (value any, ok bool) {
+	k, isString := key.(string)
+	if !isString {
+		return nil, false
+	}
+	return m.get(k)
+}
+
+// LoadOK reports whether key is present without copying its value
+func (m *SyncMap) LoadOK(key any) bool {
+	k, isString := key.(string)
+	return isString && m.has(k)
+}
+
+// Range calls f for each key and value in the table until f returns false. As with sync.Map, keys are passed
+// as any, but are always strings.
+func (m *SyncMap) Range(f func(key, value any) bool) {
+	m.rangeFunc(f)
+}
@@ -0,0 +1,42 @@
+package statichash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncMap(t *testing.T) {
+	typed, err := NewTyped[typedValue](typedTable(t))
+	assert.NoError(t, err)
+
+	// The point is to be able to swap a SyncMap in for a sync.Map
+	var m interface {
+		Load(key any) (value any, ok bool)
+		Range(f func(key, value any) bool)
+	} = NewSyncMap(typed)
+
+	v, ok := m.Load("hat")
+	assert.True(t, ok)
+	assert.Equal(t, typedValue{A: 1, B: 2.5}, v)
+
+	v, ok = m.Load("gloves")
+	assert.False(t, ok)
+	assert.Nil(t, v)
+
+	_, ok = m.Load(42)
+	assert.False(t, ok)
+
+	seen := map[any]any{}
+	m.Range(func(key, value any) bool {
+		seen[key] = value
+		return true
+	})
+	assert.Len(t, seen, 3)
+	assert.Equal(t, typedValue{A: 2, B: 3.5}, seen["coat"])
+
+	sm := NewSyncMap(typed)
+	assert.True(t, sm.LoadOK("scarf"))
+	assert.False(t, sm.LoadOK("gloves"))
+	assert.False(t, sm.LoadOK(1))
+}
@@ -2,280 +2,1014 @@
 // table. The intention is to use it with large data tables where loading say a CSV and then hashing it has a
 // considerable impact on the start-up time of the process.
 //
-// The table has string keys only. It cannot grow, and needs the total size of the keys as it is created. The
-// expectation is that you have all the data in advance. The values should all be the same size and should not
-// contain any pointers
+// The table has string keys only. It needs the number of items and the total size of the keys as it is created,
+// and only grows if built WithAutoGrow. The expectation is that you have all the data in advance. The values
+// should all be the same size and should not contain any pointers
 package statichash
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"math/bits"
 	"os"
 	"reflect"
+	"time"
 	"unsafe"
 
-	"github.com/philpearl/aeshash"
+	"github.com/philpearl/symboltab"
 )
 
-// table is a hash-table that can be written and extracted from a file without much setup overhead. It does
-// not resize, so you need to know how many records will be written in advance. It cannot be written after
+// table is a hash-table that can be written and extracted from a file without much setup overhead. It only
+// resizes if built WithAutoGrow, so you usually need to know how many records will be written in advance. It cannot be written after
 // it has been loaded from a file.
 type table struct {
 	valueSize int
 	numItems  int
+	count     int
+	flags     int64
+	seed      uint64
+	created   int64
+	version   [32]byte
 
 	// This is the single allocation of all the underlying data
 	arena []int64
 
 	// These are sub-slices within arena
-	hashes    []hash
-	keys      []keyOffset
-	values    []byte
-	keyData   []byte
-	keyOffset int
+	hashes       []hash
+	fingerprints []uint8
+	keys         []keyOffset
+	order        []slotIndex
+	sorted       []slotIndex
+	values       []byte
+	keyData      []byte
+	keyOffset    int
+
+	// slots replaces hashes, fingerprints and keys if the table is built WithInterleavedSlots. It holds a
+	// slotRecord every slotSize bytes.
+	slots    []byte
+	slotSize int
+	// valueStride is the distance between consecutive values in values. It is the value size unless the values
+	// are inline in the slots.
+	valueStride int
+
+	// columns is the width of each column of the values if the table is columnar, and columnStart is the offset
+	// of each column within a value
+	columns     []int
+	columnStart []int
+
+	// sections are the optional sections listed in the file's directory
+	sections []directoryEntry
 
 	length int64
 
-	keyDataReader byteReader
+	// layout is where each section starts within the file
+	layout layout
+
+	// win is set if the values and key data are accessed through windows onto the file rather than via the
+	// values and keyData slices
+	win *windows
+
+	// trusted is set if lookups should match on hash alone
+	trusted bool
+	// trace is called after each lookup if it is set
+	trace func(Trace)
+
+	// maxProbe is the most slots find examines before deciding a key isn't present. Zero means there's no
+	// limit, as there mustn't be for a table we're adding keys to.
+	maxProbe int
+
+	// cuckooSeed seeds the hash that chooses each key's second slot in a table built WithCuckoo
+	cuckooSeed uint64
+
+	// sortCache holds the slots in key order if they've been sorted on demand. It is nil for tables we're
+	// writing until they're finalized, as they may change.
+	sortCache *sortCache
 }
 
 // Write is a hash-table you can write to and save to a file. Create one via New. The intention is that you
 // have the full details of the hash table before you begin, and the point is to create a hash table you can
-// very quickly read from a file and use without significant initialisation.
+// very quickly read from a file and use without significant initialisation. Once every entry is Set, call
+// Finalize then save the table with WriteTo.
 type Write struct {
 	table
+	// autoGrow is set if the table should grow rather than fail when it runs out of space
+	autoGrow bool
+	// duplicates counts the Sets that overwrote an existing key, and onDuplicate is called for each of them
+	duplicates  int
+	onDuplicate func(key string)
+	// finalized is set once Finalize has been called. The table can't be changed after that.
+	finalized bool
+	// extra holds the optional sections to write after the key data
+	extra []extraSection
+	// progress is called as the table is built and saved, and expected is the number of entries the table was
+	// created for
+	progress func(Progress)
+	expected int
+	// valueType is the last type SetValue found to be safe to store
+	valueType reflect.Type
+	// probeLength is the table's maximum probe length, recorded by Finalize to be saved in the header
+	probeLength int
+	// symbols holds the strings interned with Intern
+	symbols *symboltab.SymbolTab
 }
 
 // Read is a hash-table you can read from. The intention is that you create it from a file using NewFrom.
 // Create the file using a Write
 type Read struct {
 	table
-	data       uintptr
-	dataLength uintptr
+	data []byte
+	// mapped is true if data is memory we have mapped and need to unmap on Close
+	mapped bool
+	// mapping is memory we have mapped that data is part of, as for a table inside a zip archive. It is unmapped
+	// on Close.
+	mapping []byte
+	// file is kept open while the table is in use if it is mapped in windows, locked or opened WithKeepOpen
+	file *os.File
+	// encoder is used by MarshalJSON to encode values
+	encoder ValueEncoder
+	// progress is called as Warm pulls the table into memory
+	progress func(Progress)
+	// logger records when the table is closed, if it is set
+	logger *slog.Logger
+	// locked records the parts of the table locked into memory
+	locked []SectionStats
 }
 
 // New creates a new table for writing. The intention is that you know the details of the table in advance,
 // including the number of items, the size of the value stored and the total length of all the key strings.
 // The table must have string keys.
-//
-func New(numItems int, valueSize, totalKeyLength int64) *Write {
+func New(numItems int, valueSize, totalKeyLength int64, opts ...Option) *Write {
+	var t Write
+	t.Reset(numItems, valueSize, totalKeyLength, opts...)
+	return &t
+}
+
+// Reset empties the table and sets it up as if it had just been created with New. The memory for the table is
+// reused if it is large enough, which saves allocating a new table each time if you build many tables of
+// similar sizes. Anything read from the table before the Reset is invalidated.
+func (t *Write) Reset(numItems int, valueSize, totalKeyLength int64, opts ...Option) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 
+	expected := numItems
+	// A table needs at least one slot, even if it will be empty
+	numItems = max(numItems, 1)
+	if o.flags&flagHopscotch != 0 && o.flags&(flagCuckoo|flagInsertionOrder) != 0 {
+		panic("statichash: WithHopscotch can't be combined with WithCuckoo or WithInsertionOrder")
+	}
+	if o.flags&flagCuckoo != 0 {
+		if o.flags&flagInsertionOrder != 0 {
+			panic("statichash: WithCuckoo can't be combined with WithInsertionOrder")
+		}
+		numItems = cuckooSize(numItems)
+	}
+	if err := checkSize(int64(numItems), valueSize); err != nil {
+		// The error already starts with the package name
+		panic(err.Error())
+	}
 	// round up numItems to be a power of 2. This is so we can do modulo arithmetic faster
 	numItems = 1 << uint(int(unsafe.Sizeof(numItems))*8-bits.LeadingZeros(uint(numItems-1)))
 
-	hashes, keys, values, keyData, length := offsets(int64(numItems), valueSize, totalKeyLength)
-	t := Write{
+	if o.flags&flagInlineValues != 0 {
+		if o.flags&flagColumnar != 0 {
+			panic("statichash: WithInlineValues can't be combined with WithColumns")
+		}
+		if valueSize > maxInlineValueSize {
+			panic(fmt.Sprintf("statichash: values of %d bytes are too big for WithInlineValues, which allows at most %d", valueSize, maxInlineValueSize))
+		}
+	}
+
+	if o.flags&flagInlineStrings != 0 {
+		if o.flags&flagColumnar != 0 {
+			panic("statichash: WithInlineStrings can't be combined with WithColumns")
+		}
+		if o.inlineStrings < 0 || o.inlineStrings >= maxInlineStringSize {
+			panic(fmt.Sprintf("statichash: WithInlineStrings(%d) must be between 0 and %d", o.inlineStrings, maxInlineStringSize-1))
+		}
+		if size := inlineStringSize(o.inlineStrings); valueSize != int64(size) {
+			panic(fmt.Sprintf("statichash: WithInlineStrings(%d) needs a value size of %d, not %d", o.inlineStrings, size, valueSize))
+		}
+	}
+
+	if o.flags&flagHashOnly != 0 && o.flags&(flagCuckoo|flagSortedIndex|flagStringValues) != 0 {
+		panic("statichash: WithHashOnly can't be combined with WithCuckoo, WithSortedIndex or string values")
+	}
+
+	var columns []int
+	if o.flags&flagColumnar != 0 {
+		columns = checkColumns(o.columns, valueSize)
+		numItems = max(numItems, minColumnarSlots)
+	}
+
+	l := offsets(int64(numItems), valueSize, totalKeyLength, o.flags)
+
+	// We allocate []int64 to ensure we have an 8-byte boundary for the start of our data. The header is
+	// written into the start of the arena when the table is saved.
+	arena := t.arena
+	if words := (l.length + 7) / int64(unsafe.Sizeof(int64(0))); int64(cap(arena)) >= words {
+		arena = arena[:words]
+		clear(arena)
+	} else {
+		arena = make([]int64, words)
+	}
+
+	*t = Write{
 		table: table{
 			valueSize: int(valueSize),
 			numItems:  numItems,
+			flags:     o.flags,
+			seed:      o.seed,
+			created:   o.created,
+			version:   o.version,
+			arena:     arena,
+			length:    l.length,
+			layout:    l,
 		},
+		autoGrow:    o.grow,
+		onDuplicate: o.onDuplicate,
+		progress:    o.progress,
+		expected:    expected,
+	}
+	t.setColumns(columns)
+	if o.flags&flagCuckoo != 0 {
+		t.cuckooSeed = o.seed ^ prime3
 	}
 
-	// We allocate []int64 to ensure we have an 8-byte boundary for the start of our data
-	t.arena = make([]int64, ((length+1)/int64(unsafe.Sizeof(int64(0))))-1)
-	t.length = length
-
-	slice := *(*reflect.SliceHeader)(unsafe.Pointer(&t.arena))
-	dataStart := slice.Data
-	slice.Len = numItems
-	slice.Cap = numItems
-
-	slice.Data = dataStart + uintptr(hashes)
-	t.hashes = *(*[]hash)(unsafe.Pointer(&slice))
+	t.setSections(unsafe.Pointer(unsafe.SliceData(t.arena)), l)
 
-	slice.Data = dataStart + uintptr(keys)
-	t.keys = *(*[]keyOffset)(unsafe.Pointer(&slice))
+	if o.schema != nil {
+		if err := o.schema.Check(int(valueSize)); err != nil {
+			panic(fmt.Sprintf("statichash: %v", err))
+		}
+		data, err := json.Marshal(o.schema)
+		if err != nil {
+			panic(fmt.Sprintf("statichash: encoding schema: %v", err))
+		}
+		t.addSection(SectionSchema, data)
+	}
+	if o.valueType != nil {
+		t.setValueType(o.valueType)
+	}
+}
 
-	slice.Data = dataStart + uintptr(values)
-	slice.Len = t.numItems * t.valueSize
-	slice.Cap = t.numItems * t.valueSize
-	t.values = *(*[]byte)(unsafe.Pointer(&slice))
+// checkColumns panics if the column widths aren't valid for values of valueSize bytes. It returns a copy of
+// the widths.
+func checkColumns(widths []int, valueSize int64) []int {
+	if len(widths) == 0 || len(widths) > maxColumns {
+		panic(fmt.Sprintf("statichash: %d columns given, must be between 1 and %d", len(widths), maxColumns))
+	}
+	var total int64
+	for _, w := range widths {
+		if w <= 0 || w > math.MaxUint16 {
+			panic(fmt.Sprintf("statichash: invalid column width %d", w))
+		}
+		total += int64(w)
+	}
+	if total != valueSize {
+		panic(fmt.Sprintf("statichash: columns add up to %d bytes but the value size is %d", total, valueSize))
+	}
+	return append([]int(nil), widths...)
+}
 
-	slice.Data = dataStart + uintptr(keyData)
-	slice.Len = int(length - keyData)
-	slice.Cap = int(length - keyData)
-	t.keyData = *(*[]byte)(unsafe.Pointer(&slice))
+// setColumns records the widths of the columns of a columnar table
+func (t *table) setColumns(widths []int) {
+	t.columns = widths
+	t.columnStart = nil
+	var start int
+	for _, w := range widths {
+		t.columnStart = append(t.columnStart, start)
+		start += w
+	}
+}
 
-	return &t
+// setSections points the section slices at the right places in the data starting at dataStart
+func (t *table) setSections(dataStart unsafe.Pointer, l layout) {
+	if debug {
+		t.debugCheckLayout(dataStart, l)
+	}
+	if t.flags&flagInterleaved != 0 {
+		size := slotSize(t.flags, int64(t.valueSize))
+		t.slots = unsafe.Slice((*byte)(unsafe.Add(dataStart, l.hashes)), int64(t.numItems)*size)
+		t.slotSize = int(size)
+	} else {
+		t.hashes = unsafe.Slice((*hash)(unsafe.Add(dataStart, l.hashes)), t.numItems)
+		if t.flags&flagFingerprints != 0 {
+			t.fingerprints = unsafe.Slice((*uint8)(unsafe.Add(dataStart, l.fingerprints)), t.numItems)
+		}
+		t.keys = unsafe.Slice((*keyOffset)(unsafe.Add(dataStart, l.keys)), t.numItems)
+	}
+	if t.flags&flagInsertionOrder != 0 {
+		t.order = unsafe.Slice((*slotIndex)(unsafe.Add(dataStart, l.order)), t.numItems)
+	}
+	if t.flags&flagSortedIndex != 0 {
+		t.sorted = unsafe.Slice((*slotIndex)(unsafe.Add(dataStart, l.sorted)), t.numItems)
+	}
+	if t.flags&flagInlineValues != 0 {
+		// Each value follows the slotRecord in its slot
+		t.values = t.slots[slotRecordSize:]
+		t.valueStride = t.slotSize
+	} else {
+		t.values = unsafe.Slice((*byte)(unsafe.Add(dataStart, l.values)), t.numItems*t.valueSize)
+		t.valueStride = t.valueSize
+	}
+	t.keyData = unsafe.Slice((*byte)(unsafe.Add(dataStart, l.keyData)), l.length-l.keyData)
 }
 
 // NewFrom creates a new, fully populated hash-table from a file prepared using Write.WriteTo.
-func NewFrom(filename string) (*Read, error) {
-
-	// First we map in the entire file
+//
+// The file is mapped into memory and then closed, as the mapping doesn't need it, so an open table doesn't hold
+// a file descriptor. The file is kept open until the table is closed if the table is opened WithKeepOpen,
+// WithSharedLock or WithWindowedMapping.
+func NewFrom(filename string, opts ...ReadOption) (*Read, error) {
 	f, err := os.Open(filename)
 	if err != nil {
+		o := newReadOptions(opts)
+		o.logOpen(filename, nil, err)
 		return nil, err
 	}
+	return NewFromFile(f, opts...)
+}
+
+// NewFromFile is like NewFrom, but reads the table from a file that is already open. This lets a process open a
+// table from a file descriptor passed to it by another process, for example one created by WriteMemfd. The table
+// takes ownership of f. f is closed if NewFromFile fails, and otherwise once the table no longer needs it.
+func NewFromFile(f *os.File, opts ...ReadOption) (*Read, error) {
+	o := newReadOptions(opts)
+	r, err := newFromFile(f, &o)
+	o.logOpen(f.Name(), r, err)
+	if r != nil && o.logger != nil {
+		r.logger = o.logger.With("file", f.Name())
+	}
+	return r, err
+}
+
+// newFromFile is NewFromFile once the options are applied
+func newFromFile(f *os.File, o *readOptions) (*Read, error) {
+	filename := f.Name()
+	if o.lock {
+		if err := lockFile(f, false); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("locking %s: %w", filename, err)
+		}
+	}
 
 	fileLength, err := f.Seek(0, io.SeekEnd)
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
 
-	data, err := mapMemory(f.Fd(), uintptr(fileLength))
+	if fileLength < int64(minHeaderSize) {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s is only %d bytes long", ErrTruncated, filename, fileLength)
+	}
+
+	if o.windowSize > 0 {
+		r, err := newWindowed(f, fileLength, o)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", filename, err)
+		}
+		if err := r.apply(o); err != nil {
+			r.Close()
+			return nil, err
+		}
+		return r, nil
+	}
+
+	// Map in the entire file
+	if err := checkMappable(fileLength); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	data, err := o.mapMemory(f.Fd(), int(fileLength))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("mapping %s: %w", filename, err)
+	}
+
+	r, err := newFromData(data)
 	if err != nil {
+		unmap(data)
+		f.Close()
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	r.mapped = true
+	if o.lock || o.keepOpen {
+		// The lock is released when the file is closed, so we keep it open until the table is closed
+		r.file = f
+	} else if err := f.Close(); err != nil {
+		// The mapping doesn't need the file to stay open
+		r.Close()
+		return nil, err
+	}
+	if err := r.apply(o); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewFromContext is like NewFrom, but returns the context's error if ctx is cancelled before the table is
+// open. Mapping and locking a large file into memory can take a long time, particularly over a network
+// filesystem, and can't be interrupted. If ctx is cancelled first, NewFromContext returns straight away and
+// the table is closed once the open completes.
+func NewFromContext(ctx context.Context, filename string, opts ...ReadOption) (*Read, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	return newFromData(data, uintptr(fileLength))
+	type result struct {
+		r   *Read
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		r, err := NewFrom(filename, opts...)
+		done <- result{r: r, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.r, res.err
+	case <-ctx.Done():
+		go func() {
+			if res := <-done; res.err == nil {
+				res.r.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
 }
 
 // NewFromBytes creates a table from the bytes of a file saved using a Write. This can be useful if the data
-// is not stored in a separate file, but rather is built into the executable via something like bindata
-func NewFromBytes(data []byte) (*Read, error) {
-	slice := *(*reflect.SliceHeader)(unsafe.Pointer(&data))
-	return newFromData(slice.Data, uintptr(slice.Len))
-}
+// is not stored in a separate file, but rather is built into the executable via something like bindata.
+// WithWindowedMapping has no effect here.
+//
+// The data is treated as untrusted. As well as the header and section checks NewFrom makes, NewFromBytes checks
+// that every key offset and key length is within the key data, so that lookups can't go out of bounds. It
+// returns an error wrapping ErrCorrupt or ErrTruncated if the data is bad. It doesn't hash the keys, so it
+// doesn't catch everything Validate does. If data isn't 8-byte aligned it is copied.
+func NewFromBytes(data []byte, opts ...ReadOption) (*Read, error) {
+	o := newReadOptions(opts)
 
-func newFromData(data, length uintptr) (*Read, error) {
-	h := (*header)(unsafe.Pointer(data))
+	if uintptr(unsafe.Pointer(unsafe.SliceData(data)))%unsafe.Alignof(int64(0)) != 0 {
+		// The sections hold 8-byte values, so we need an aligned copy
+		buf := make([]int64, (len(data)+7)/8)
+		aligned := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(buf))), len(data))
+		copy(aligned, data)
+		data = aligned
+	}
 
-	hashes, keys, values, keyData, _ := offsets(h.numItems, h.valueSize, 0)
-	t := Read{
-		table: table{
-			valueSize: int(h.valueSize),
-			numItems:  int(h.numItems),
-		},
-		data:       data,
-		dataLength: length,
+	r, err := newFromData(data)
+	if err != nil {
+		return nil, err
 	}
+	if err := r.checkBounds(); err != nil {
+		return nil, err
+	}
+	if err := r.apply(&o); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
-	dataStart := data + unsafe.Sizeof(*h)
-	slice := reflect.SliceHeader{
-		Len: int(h.numItems),
-		Cap: int(h.numItems),
+// apply applies the options that affect how an opened table behaves, and checks the table is what the caller
+// expects
+func (r *Read) apply(o *readOptions) error {
+	if o.valueSize != 0 && o.valueSize != r.valueSize {
+		return fmt.Errorf("%w: table has values of %d bytes, expected %d", ErrValueSizeMismatch, r.valueSize, o.valueSize)
+	}
+	r.trusted = o.trusted
+	r.trace = o.trace
+	if r.mapped || r.mapping != nil {
+		if o.noMemLock {
+			r.lockWithin(o.memLockLimit)
+		} else {
+			// The whole mapping was locked when it was made
+			r.noteLocked()
+		}
+	}
+	r.encoder = o.encoder
+	r.progress = o.progress
+	if o.schema != nil {
+		return r.checkSchema(o.schema)
 	}
+	return nil
+}
 
-	slice.Data = dataStart + uintptr(hashes)
-	t.hashes = *(*[]hash)(unsafe.Pointer(&slice))
+func newFromData(data []byte) (*Read, error) {
+	h, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.validate(int64(len(data))); err != nil {
+		return nil, err
+	}
 
-	slice.Data = dataStart + uintptr(keys)
-	t.keys = *(*[]keyOffset)(unsafe.Pointer(&slice))
+	t := Read{
+		table: readTable(&h, int64(len(data))),
+		data:  data,
+	}
+	if err := t.readDirectory(&h, bytes.NewReader(data), int64(len(data))); err != nil {
+		return nil, err
+	}
+	t.setSections(unsafe.Pointer(unsafe.SliceData(data)), t.layout)
+	t.countV0(&h)
 
-	slice.Data = dataStart + uintptr(values)
-	slice.Len = int(h.numItems * h.valueSize)
-	slice.Cap = slice.Len
+	return &t, nil
+}
 
-	t.values = *(*[]byte)(unsafe.Pointer(&slice))
+// readTable sets up a table from the header of a file of the given length. The sections still need to be
+// set.
+func readTable(h *header, fileLength int64) table {
+	l := h.offsets()
+	// The key data runs to the end of the file
+	l.length = fileLength
 
-	slice.Data = dataStart + uintptr(keyData)
-	slice.Len = int(int64(length) - keyData)
-	slice.Cap = slice.Len
-	t.keyData = *(*[]byte)(unsafe.Pointer(&slice))
+	var columns []int
+	if h.flags&flagColumnar != 0 {
+		for _, w := range h.columns {
+			if w != 0 {
+				columns = append(columns, int(w))
+			}
+		}
+	}
 
-	return &t, nil
+	t := table{
+		valueSize:  int(h.valueSize),
+		numItems:   int(h.numItems),
+		count:      int(h.count),
+		flags:      h.flags,
+		seed:       h.seed,
+		created:    h.created,
+		version:    h.version,
+		maxProbe:   int(h.maxProbe),
+		cuckooSeed: h.cuckooSeed,
+		length:     fileLength,
+		layout:     l,
+		sortCache:  &sortCache{},
+	}
+	t.setColumns(columns)
+	return t
 }
 
-// Close releases the resources associated with the table
+// Close unmaps the table and closes its file, if it kept it open. It releases everything even if something
+// fails, and returns the first error. The table can't be used once it is closed.
 func (r *Read) Close() error {
-	if r.data != 0 && r.dataLength != 0 {
-		if err := unmap(r.data, r.dataLength); err != nil {
-			return err
+	err := r.close()
+	if r.logger != nil {
+		if err != nil {
+			r.logger.Error("closing table failed", "error", err)
+		} else {
+			r.logger.Info("closed table")
 		}
-		r.data = 0
-		r.dataLength = 0
 	}
+	return err
+}
 
-	return nil
+// close is Close without logging
+func (r *Read) close() error {
+	var err error
+	if r.win != nil {
+		err = r.win.close()
+		r.win = nil
+	}
+	if r.mapped && r.data != nil {
+		if e := unmap(r.data); err == nil {
+			err = e
+		}
+		r.data = nil
+	}
+	if r.mapping != nil {
+		if e := unmap(r.mapping); err == nil {
+			err = e
+		}
+		r.mapping = nil
+		r.data = nil
+	}
+	if r.file != nil {
+		if e := r.file.Close(); err == nil {
+			err = e
+		}
+		r.file = nil
+	}
+	return err
+}
+
+// File returns the table's file if it is kept open, as it is for a table opened WithKeepOpen, and otherwise nil.
+// The file is closed when the table is closed.
+func (r *Read) File() *os.File {
+	return r.file
 }
 
 // Cap returns the underlying capacity of the table
 func (t *table) Cap() int {
-	return len(t.hashes)
+	return t.numItems
+}
+
+// Len returns the number of entries in the table
+func (t *table) Len() int {
+	return t.count
+}
+
+// ValueSize returns the size of each value in bytes
+func (t *table) ValueSize() int {
+	return t.valueSize
+}
+
+// NumSlots returns the number of slots in the table. This is the same as Cap.
+func (t *table) NumSlots() int {
+	return t.numItems
+}
+
+// KeyDataLen returns the size in bytes of the key data section, including any unused space
+func (t *table) KeyDataLen() int64 {
+	return t.layout.length - t.layout.keyData
 }
 
-// WriteTo writes the hash table to f
+// FileLen returns the length of the table file
+func (t *table) FileLen() int64 {
+	return t.length
+}
+
+// WriteTo writes the hash table to f. The table must have been finalized with Finalize, otherwise WriteTo
+// returns ErrNotFinalized.
 func (t *Write) WriteTo(f io.Writer) (int64, error) {
+	if !t.finalized {
+		return 0, ErrNotFinalized
+	}
+
+	if !hostLittleEndian {
+		return 0, fmt.Errorf("%w: tables can only be written on little-endian platforms", ErrUnsupportedFormat)
+	}
+
+	dir, dirOffset, _ := t.directory()
 	h := header{
-		numItems:  int64(t.numItems),
-		valueSize: int64(t.valueSize),
-	}
-	data := *(*[]byte)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: uintptr(unsafe.Pointer(&h)),
-		Len:  int(unsafe.Sizeof(h)),
-		Cap:  int(unsafe.Sizeof(h)),
-	}))
-	l1, err := f.Write(data)
-	if err != nil {
-		return 0, err
+		magic:      fileMagic,
+		format:     currentFormat,
+		headerSize: headerSize,
+		numItems:   int64(t.numItems),
+		valueSize:  int64(t.valueSize),
+		count:      int64(t.count),
+		flags:      t.flags,
+		seed:       t.seed,
+		created:    t.created,
+		version:    t.version,
+		directory:  dirOffset,
+		sections:   uint32(len(dir)),
+		maxProbe:   uint32(t.probeLength),
+		cuckooSeed: t.cuckooSeed,
+	}
+	for i, w := range t.columns {
+		h.columns[i] = uint16(w)
+	}
+	if h.created == 0 && t.flags&flagSeeded == 0 {
+		h.created = time.Now().UnixNano()
 	}
 
-	arenaSlice := *(*reflect.SliceHeader)(unsafe.Pointer(&t.arena))
+	data := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(t.arena))), t.length)
+	h.encode(data)
 
-	data = *(*[]byte)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: arenaSlice.Data,
-		Len:  int(t.length),
-		Cap:  int(t.length),
-	}))
+	if t.progress == nil {
+		n, err := f.Write(data)
+		if err != nil {
+			return int64(n), err
+		}
+		return t.writeSections(f, int64(n), dir)
+	}
 
-	l2, err := f.Write(data)
-	return int64(l1 + l2), err
+	total := t.FileLen()
+	var n int64
+	for len(data) > 0 {
+		chunk := data[:min(len(data), progressBytes)]
+		m, err := f.Write(chunk)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+		data = data[m:]
+		t.progress(Progress{Stage: StageWrite, Done: n, Total: total})
+	}
+	n, err := t.writeSections(f, n, dir)
+	if err == nil {
+		t.progress(Progress{Stage: StageWrite, Done: n, Total: total})
+	}
+	return n, err
+}
+
+// WriteFile saves the table to the named file, creating it if necessary. It takes an exclusive advisory lock on
+// the file while writing, so a reader opening it WithSharedLock can't see a partly written table. WriteFile
+// returns an error wrapping ErrLocked if the file is open by such a reader, and leaves the file unchanged.
+func (t *Write) WriteFile(filename string) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f, true); err != nil {
+		return fmt.Errorf("locking %s: %w", filename, err)
+	}
+	// We can only truncate the file once we hold the lock
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if _, err := t.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 // Set a key & value in the hash table. Pass a pointer to the value. The value is copied into the hash table
-// using the size passed on New. The key is also copied.
-func (t *Write) Set(key string, val unsafe.Pointer) {
-	hash := hash(aeshash.Hash(key))
+// using the size passed on New. The key is also copied. If the key is new and there's no slot for it Set
+// returns an error wrapping ErrTableFull, and if there's no room left in the key data for it an error wrapping
+// ErrKeySpaceExhausted. Set returns ErrFinalized if the table has been finalized.
+func (t *Write) Set(key string, val unsafe.Pointer) error {
+	if t.finalized {
+		return ErrFinalized
+	}
+	h := t.hashKey(key)
 
-	index, found := t.find(key, hash)
+	index, found := t.find(key, h)
+	if !found && t.flags&flagCuckoo != 0 {
+		return t.addCuckoo(key, h, val)
+	}
+	if !found && t.flags&flagHashOnly == 0 && !t.hasKeySpace(key) {
+		if !t.autoGrow {
+			return fmt.Errorf("%w: no room for key %q", ErrKeySpaceExhausted, key)
+		}
+		t.growKeyData(len(key))
+		index, _ = t.find(key, h)
+	}
 	if !found {
-		t.hashes[index] = hash
-		t.keys[index] = t.addKey(key)
+		index = t.hopscotchSlot(h, index)
+		if index < 0 && t.autoGrow {
+			t.grow(t.numItems*2, int64(len(t.keyData))*2)
+			index, _ = t.find(key, h)
+			index = t.hopscotchSlot(h, index)
+		}
+		if index < 0 {
+			return fmt.Errorf("%w: no slot for key %q", ErrTableFull, key)
+		}
+		t.insert(index, h, t.newKey(key, h))
+		t.ingested()
+	} else {
+		t.duplicates++
+		if t.onDuplicate != nil {
+			t.onDuplicate(key)
+		}
+	}
+	t.setValue(index, unsafe.Slice((*byte)(val), t.valueSize))
+	return nil
+}
+
+// ingested reports progress once a new key has been added
+func (t *Write) ingested() {
+	if t.progress != nil && t.count%progressEntries == 0 {
+		t.progress(Progress{Stage: StageIngest, Done: int64(t.count), Total: int64(t.expected)})
+	}
+}
+
+// SetValue is like Set, but takes the value itself, or a non-nil pointer to it, rather than an unsafe.Pointer.
+// It uses reflection to check that the value contains no pointers and is the table's value size before copying
+// it in, so a value holding a string or slice can't be saved by mistake. It returns an error wrapping
+// ErrHasPointers or ErrValueSizeMismatch if the check fails.
+func (t *Write) SetValue(key string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return fmt.Errorf("%w: value for %q is nil", ErrHasPointers, key)
+	}
+
+	typ := rv.Type()
+	if typ != t.valueType {
+		if err := checkPointerFree(typ); err != nil {
+			return err
+		}
+		if int(typ.Size()) != t.valueSize {
+			return fmt.Errorf("%w: table has values of %d bytes, %s is %d bytes", ErrValueSizeMismatch, t.valueSize, typ, typ.Size())
+		}
+		t.valueType = typ
+	}
+
+	if !rv.CanAddr() {
+		// The value was passed directly, so we need an addressable copy of it
+		p := reflect.New(typ)
+		p.Elem().Set(rv)
+		rv = p.Elem()
 	}
-	copy(t.values[index*t.valueSize:], *(*[]byte)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: uintptr(val),
-		Cap:  t.valueSize,
-		Len:  t.valueSize,
-	})))
+	return t.Set(key, rv.Addr().UnsafePointer())
+}
+
+// Duplicates returns the number of times Set has been called with a key that was already in the table
+func (t *Write) Duplicates() int {
+	return t.duplicates
+}
+
+// insert fills in the empty slot index for a new key with hash h whose key data is at offset
+func (t *Write) insert(index int, h uint64, offset keyOffset) {
+	t.setSlot(index, slotHash(h), fingerprint(h), offset)
+	if t.order != nil {
+		t.order[t.count] = slotIndex(index)
+	}
+	t.count++
 }
 
 // GetPtr gets the value associated with key. It returns an unsafe.Pointer to the value. Access this by
 // casting to the appropriate type
 //
-//  v, ok := t.GetPtr("key")
-//  if !ok {
-//     return
-//  }
-//  value := (*myType)(v)
+//	v, ok := t.GetPtr("key")
+//	if !ok {
+//	   return
+//	}
+//	value := (*myType)(v)
 //
+// GetPtr doesn't allocate, so it is safe to call at very high rates. The exceptions are columnar tables and
+// tables mapped in windows, where the value is assembled or copied into new memory. Misses never allocate.
 func (t *table) GetPtr(key string) (val unsafe.Pointer, ok bool) {
 	if t == nil {
 		return nil, false
 	}
-	hash := hash(aeshash.Hash(key))
-	index, found := t.find(key, hash)
+	index, found := t.find(key, t.hashKey(key))
 	if found {
-		val = unsafe.Pointer(&t.values[index*int(t.valueSize)])
+		val, found = t.lookupPtr(index)
 	}
 	return val, found
 }
 
-// find looks for the location of the key in the hash table
-func (t *table) find(key string, hashVal hash) (cursor int, found bool) {
+// valuePtr returns a pointer to the value in slot index. If the table is mapped in windows this points to a
+// copy of the value, as the window may be unmapped at any time. If the table is columnar it points to a copy
+// assembled from the columns.
+func (t *table) valuePtr(index int) unsafe.Pointer {
+	if (t.win != nil && t.flags&flagInlineValues == 0) || t.columns != nil {
+		return unsafe.Pointer(unsafe.SliceData(t.value(index)))
+	}
+	if debug {
+		t.debugCheckSlot(index)
+	}
+	return unsafe.Pointer(&t.values[index*t.valueStride])
+}
+
+// lookupPtr is valuePtr for lookups. If the table is mapped in windows and the value can't be read it returns
+// false, so the key is reported as not found.
+func (t *table) lookupPtr(index int) (unsafe.Pointer, bool) {
+	if (t.win != nil && t.flags&flagInlineValues == 0) || t.columns != nil {
+		v, err := t.readValue(index)
+		if err != nil {
+			return nil, false
+		}
+		return unsafe.Pointer(unsafe.SliceData(v)), true
+	}
+	return t.valuePtr(index), true
+}
+
+// GetPtrWithHash is like GetPtr, but takes the hash of the key rather than calculating it. h must be the
+// table's Hash of the key. This saves hashing the key twice if you already need the hash for something else.
+func (t *table) GetPtrWithHash(key string, h uint64) (val unsafe.Pointer, ok bool) {
+	if t == nil {
+		return nil, false
+	}
+	index, found := t.find(key, h)
+	if found {
+		val, found = t.lookupPtr(index)
+	}
+	return val, found
+}
+
+// GetValue is like GetPtr, but returns the bytes of the value rather than a pointer to them. The slice refers
+// directly to the table's memory where GetPtr's pointer would, so it must not be modified.
+func (t *table) GetValue(key string) ([]byte, bool) {
+	if t == nil {
+		return nil, false
+	}
+	index, found := t.find(key, t.hashKey(key))
+	if !found {
+		return nil, false
+	}
+	val, ok := t.lookupPtr(index)
+	if !ok {
+		return nil, false
+	}
+	return unsafe.Slice((*byte)(val), t.valueSize), true
+}
+
+// bytesPointer returns a pointer to the start of b suitable for passing to Set
+func bytesPointer(b []byte) unsafe.Pointer {
+	return unsafe.Pointer(unsafe.SliceData(b))
+}
+
+// value returns the bytes of the value in slot index. If the table is mapped in windows and the value can't be
+// read it is zero.
+func (t *table) value(index int) []byte {
+	v, _ := t.readValue(index)
+	return v
+}
+
+// readValue is like value, but also returns the error if the table is mapped in windows and the value can't be
+// read
+func (t *table) readValue(index int) ([]byte, error) {
+	if t.columns != nil {
+		v := make([]byte, t.valueSize)
+		var err error
+		for c := range t.columns {
+			col, e := t.column(c, index)
+			if err == nil {
+				err = e
+			}
+			copy(v[t.columnStart[c]:], col)
+		}
+		return v, err
+	}
+	if t.win != nil && t.flags&flagInlineValues == 0 {
+		return t.win.copy(t.layout.values+int64(index*t.valueSize), int64(t.valueSize))
+	}
+	offset := index * t.valueStride
+	return t.values[offset : offset+t.valueSize], nil
+}
+
+// find looks for the location of the key in the hash table. If the key is not present it returns the empty
+// slot where it would go, or -1 if the table is full.
+func (t *table) find(key string, h uint64) (cursor int, found bool) {
+	if t.trace != nil {
+		return t.findTraced(key, h)
+	}
+	return t.lookup(key, h)
+}
+
+// lookup is find without tracing
+func (t *table) lookup(key string, h uint64) (cursor int, found bool) {
+	if t.flags&flagCuckoo != 0 {
+		return t.findCuckoo(key, h)
+	}
 	l := t.numItems
-	cursor = int(hashVal) & (l - 1)
+	cursor = int(slotHash(h)) & (l - 1)
 	start := cursor
-	// TODO: check if 0 hash is a good indicator for an empty slot. Is hash ever zero?
-	for t.hashes[cursor] != 0 {
-		if t.hashes[cursor] == hashVal && t.getKey(t.keys[cursor]) == key {
+	// slotHash never returns zero, so a zero hash indicates an empty slot
+	for probes := 1; t.hashAt(cursor) != 0; probes++ {
+		if t.matches(cursor, key, h) {
 			return cursor, true
 		}
+		if probes == t.maxProbe {
+			// Every key in the table is closer to its home slot than this
+			return -1, false
+		}
 		cursor++
 		if cursor == l {
 			cursor = 0
 		}
 		if cursor == start {
-			panic("out of space!")
+			return -1, false
 		}
 	}
 	return cursor, false
 }
 
+// matches returns true if slot cursor holds key, whose full hash is h
+func (t *table) matches(cursor int, key string, h uint64) bool {
+	hashVal, fp := slotHash(h), fingerprint(h)
+	if t.slots != nil {
+		s := t.slot(cursor)
+		return s.hash == hashVal && s.fingerprint == fp && t.keyMatches(s.key, key, h)
+	}
+	return t.hashes[cursor] == hashVal &&
+		(t.fingerprints == nil || t.fingerprints[cursor] == fp) &&
+		t.keyMatches(t.keys[cursor], key, h)
+}
+
+// keyMatches returns true if the key stored at offset is key, whose full hash is h. A table built WithHashOnly
+// stores the hash rather than the key, and a trusted table doesn't compare keys at all.
+func (t *table) keyMatches(offset keyOffset, key string, h uint64) bool {
+	if t.trusted {
+		return true
+	}
+	if t.flags&flagHashOnly != 0 {
+		return offset == keyOffset(h)
+	}
+	return t.keyEquals(offset, key)
+}
+
 // addKey saves a key. We write the length then the key bytes, and return the offset of the start of the
 // length. The length is stored as a variable length int as most strings will likely be < 128 bytes
 func (t *table) addKey(key string) keyOffset {
+	if debug {
+		t.debugCheckKeySpace(key)
+	}
 	start := t.keyOffset
 	t.keyOffset += binary.PutVarint(t.keyData[t.keyOffset:], int64(len(key)))
 	copy(t.keyData[t.keyOffset:], key)
@@ -284,22 +1018,29 @@ func (t *table) addKey(key string) keyOffset {
 	return keyOffset(start)
 }
 
-// getKey returns a string key.
-func (t *table) getKey(offset keyOffset) string {
-	t.keyDataReader.buf = t.keyData[offset:]
-	t.keyDataReader.offset = 0
-	len, _ := binary.ReadVarint(&t.keyDataReader)
-	data := t.keyData[t.keyDataReader.offset+int(offset) : t.keyDataReader.offset+int(offset)+int(len)]
-	return *(*string)(unsafe.Pointer(&data))
-}
-
-type byteReader struct {
-	buf    []byte
-	offset int
+// keyEquals returns true if the key stored at offset is key
+func (t *table) keyEquals(offset keyOffset, key string) bool {
+	if t.win != nil {
+		return t.win.keyEquals(t.layout.keyData+int64(offset), key)
+	}
+	return t.getKey(offset) == key
 }
 
-func (br *byteReader) ReadByte() (byte, error) {
-	b := br.buf[br.offset]
-	br.offset++
-	return b, nil
+// getKey returns a string key. If the table is mapped in windows the key is copied, and is empty if it can't be
+// read. A table built WithHashOnly has no keys, so the key is always empty.
+func (t *table) getKey(offset keyOffset) string {
+	if t.flags&flagHashOnly != 0 {
+		return ""
+	}
+	if t.win != nil {
+		key, _ := t.win.key(t.layout.keyData + int64(offset))
+		return key
+	}
+	if debug {
+		t.debugCheckKey(offset)
+	}
+	// Read the length without any state in the table, so lookups are safe from many goroutines
+	len, n := binary.Varint(t.keyData[offset:])
+	data := t.keyData[int(offset)+n : int(offset)+n+int(len)]
+	return unsafe.String(unsafe.SliceData(data), int(len))
 }
@@ -1,8 +1,12 @@
 package statichash
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"unsafe"
 
@@ -88,6 +92,7 @@ func TestWriteRead(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 	defer os.Remove(f.Name())
+	tb.Finalize()
 	_, err = tb.WriteTo(f)
 	assert.NoError(t, err)
 	assert.NoError(t, f.Close())
@@ -105,3 +110,119 @@ func TestWriteRead(t *testing.T) {
 		}
 	}
 }
+
+func TestGetValue(t *testing.T) {
+	tb := buildTable(t, 10)
+	v, ok := tb.GetValue("key3")
+	assert.True(t, ok)
+	assert.Equal(t, 7, *(*int)(unsafe.Pointer(&v[0])))
+	assert.Len(t, v, 8)
+	_, ok = tb.GetValue("nope")
+	assert.False(t, ok)
+}
+
+func TestNewFromContext(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "table")
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(name))
+
+	tr, err := NewFromContext(context.Background(), name)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, tr.Len())
+	assert.NoError(t, tr.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewFromContext(ctx, name)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFileDescriptors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "table")
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(name))
+
+	r, err := NewFrom(name)
+	assert.NoError(t, err)
+	assert.Nil(t, r.File())
+	assert.Equal(t, 10, r.Len())
+	assert.NoError(t, r.Close())
+
+	r, err = NewFrom(name, WithKeepOpen())
+	assert.NoError(t, err)
+	f := r.File()
+	if assert.NotNil(t, f) {
+		fi, err := f.Stat()
+		assert.NoError(t, err)
+		assert.Equal(t, tb.FileLen(), fi.Size())
+	}
+	assert.NoError(t, r.Close())
+	assert.Nil(t, r.File())
+	assert.ErrorIs(t, f.Close(), os.ErrClosed)
+	assert.NoError(t, r.Close())
+
+	// On Linux we can check that opening a table doesn't leave a file descriptor open
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return
+	}
+	for range 10 {
+		r, err := NewFrom(name)
+		assert.NoError(t, err)
+		defer r.Close()
+	}
+	after, err := os.ReadDir("/proc/self/fd")
+	assert.NoError(t, err)
+	assert.Equal(t, len(fds), len(after))
+}
+
+func TestTrustedHashes(t *testing.T) {
+	tb := buildTable(t, 100)
+	var buf bytes.Buffer
+	tb.Finalize()
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	tr, err := NewFromBytes(buf.Bytes(), WithTrustedHashes())
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		v, ok := tr.GetPtr(fmt.Sprintf("key%d", 100-i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+
+	// Corrupt a key. A trusted lookup doesn't notice
+	key := "key50"
+	index, found := tr.find(key, tr.hashKey(key))
+	assert.True(t, found)
+	off := int(tr.keys[index]) + 1
+	buf.Bytes()[int(tr.layout.keyData)+off] = 'K'
+
+	_, ok := tr.GetPtr(key)
+	assert.True(t, ok)
+
+	tr.trusted = false
+	_, ok = tr.GetPtr(key)
+	assert.False(t, ok)
+}
+
+func TestGeometry(t *testing.T) {
+	tb := New(7, 8, 21)
+	var buf bytes.Buffer
+	tb.Finalize()
+	n, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 8, tr.ValueSize())
+	assert.Equal(t, 8, tr.NumSlots())
+	assert.Equal(t, int64(21+4*8), tr.KeyDataLen())
+	assert.Equal(t, n, tr.FileLen())
+	assert.Equal(t, tb.FileLen(), tr.FileLen())
+}
@@ -0,0 +1,46 @@
+package statichash
+
+// Trace describes a lookup, for the function given to WithTrace
+type Trace struct {
+	// Key is the key looked up and Hash its hash
+	Key  string
+	Hash uint64
+	// HomeSlot is the first slot the lookup examined
+	HomeSlot int
+	// Slot is the slot the key is in, or -1 if it wasn't found
+	Slot int
+	// Probes is the number of slots the lookup examined
+	Probes int
+	// Found is true if the key is in the table
+	Found bool
+}
+
+// findTraced is find for a table with a trace function
+func (t *table) findTraced(key string, h uint64) (int, bool) {
+	cursor, found := t.lookup(key, h)
+
+	mask := t.numItems - 1
+	tr := Trace{
+		Key:      key,
+		Hash:     h,
+		HomeSlot: int(slotHash(h)) & mask,
+		Slot:     -1,
+		Found:    found,
+	}
+	switch {
+	case found:
+		tr.Slot = cursor
+		tr.Probes = t.probeLength(cursor)
+	case t.flags&flagCuckoo != 0:
+		tr.Probes = 2
+	case cursor >= 0:
+		// The lookup stopped at an empty slot
+		tr.Probes = (cursor-tr.HomeSlot)&mask + 1
+	case t.maxProbe > 0:
+		tr.Probes = min(t.maxProbe, t.numItems)
+	default:
+		tr.Probes = t.numItems
+	}
+	t.trace(tr)
+	return cursor, found
+}
@@ -0,0 +1,47 @@
+package statichash
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	tb := buildTable(t, 100)
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err := tb.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	var traces []Trace
+	r, err := NewFromBytes(buf.Bytes(), WithTrace(func(tr Trace) { traces = append(traces, tr) }))
+	assert.NoError(t, err)
+
+	_, ok := r.GetPtr("key17")
+	assert.True(t, ok)
+	if assert.Len(t, traces, 1) {
+		tr := traces[0]
+		assert.Equal(t, "key17", tr.Key)
+		assert.Equal(t, r.hashKey("key17"), tr.Hash)
+		assert.True(t, tr.Found)
+		assert.Equal(t, r.probeLength(tr.Slot), tr.Probes)
+		assert.Equal(t, tr.Slot, (tr.HomeSlot+tr.Probes-1)&(r.numItems-1))
+	}
+
+	_, ok = r.GetPtr("missing")
+	assert.False(t, ok)
+	if assert.Len(t, traces, 2) {
+		tr := traces[1]
+		assert.False(t, tr.Found)
+		assert.Equal(t, -1, tr.Slot)
+		assert.GreaterOrEqual(t, tr.Probes, 1)
+		assert.LessOrEqual(t, tr.Probes, r.maxProbe+1)
+	}
+
+	// Tables opened without a trace function aren't traced
+	r, err = NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	r.GetPtr("key17")
+	assert.Len(t, traces, 2)
+}
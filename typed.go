@@ -0,0 +1,53 @@
+package statichash
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// Typed wraps a Read whose values are of type T, so values can be read without casting unsafe.Pointers
+type Typed[T any] struct {
+	r *Read
+}
+
+// NewTyped wraps r. It returns an error if T contains pointers or is not the same size as the values in r. If
+// the table was built WithValueType, or with FromMapT, it returns an error wrapping ErrSchemaMismatch unless T
+// is laid out the same way as the type the table was built with.
+func NewTyped[T any](r *Read) (*Typed[T], error) {
+	typ := reflect.TypeFor[T]()
+	if err := checkPointerFree(typ); err != nil {
+		return nil, err
+	}
+	var zero T
+	if int(unsafe.Sizeof(zero)) != r.valueSize {
+		return nil, fmt.Errorf("%w: table has values of %d bytes, %T is %d bytes", ErrValueSizeMismatch, r.valueSize, zero, unsafe.Sizeof(zero))
+	}
+	if err := r.checkValueType(typ); err != nil {
+		return nil, err
+	}
+	return &Typed[T]{r: r}, nil
+}
+
+// Read returns the underlying table
+func (t *Typed[T]) Read() *Read {
+	return t.r
+}
+
+// Get returns a copy of the value for key, and whether the key was found
+func (t *Typed[T]) Get(key string) (value T, ok bool) {
+	p, ok := t.r.GetPtr(key)
+	if ok {
+		value = *(*T)(p)
+	}
+	return value, ok
+}
+
+// Range calls f for each entry in the table, in the order Iterate visits them, until f returns false
+func (t *Typed[T]) Range(f func(key string, value T) bool) {
+	for it := t.r.Iterate(); it.Next(); {
+		if !f(it.Key(), *(*T)(it.Value())) {
+			return
+		}
+	}
+}
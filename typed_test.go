@@ -0,0 +1,63 @@
+package statichash
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedValue struct {
+	A int32
+	B float64
+}
+
+func typedTable(t *testing.T) *Read {
+	tb, err := FromMapOf(map[string]typedValue{
+		"hat":   {A: 1, B: 2.5},
+		"coat":  {A: 2, B: 3.5},
+		"scarf": {A: 3, B: 4.5},
+	})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	tr, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	return tr
+}
+
+func TestTyped(t *testing.T) {
+	tr := typedTable(t)
+
+	_, err := NewTyped[int32](tr)
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+	_, err = NewTyped[[2]string](tr)
+	assert.ErrorIs(t, err, ErrHasPointers)
+
+	typed, err := NewTyped[typedValue](tr)
+	assert.NoError(t, err)
+	assert.Equal(t, tr, typed.Read())
+
+	v, ok := typed.Get("coat")
+	assert.True(t, ok)
+	assert.Equal(t, typedValue{A: 2, B: 3.5}, v)
+
+	_, ok = typed.Get("gloves")
+	assert.False(t, ok)
+
+	seen := map[string]typedValue{}
+	typed.Range(func(key string, value typedValue) bool {
+		seen[key] = value
+		return true
+	})
+	assert.Len(t, seen, 3)
+	assert.Equal(t, typedValue{A: 3, B: 4.5}, seen["scarf"])
+
+	var count int
+	typed.Range(func(key string, value typedValue) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
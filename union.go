@@ -0,0 +1,85 @@
+package statichash
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// shardSeed is the seed used to hash keys when choosing a shard. It is fixed so that builders and readers in
+// different processes always agree on which shard holds a key.
+const shardSeed = 0x9e3779b97f4a7c15
+
+// ShardFor returns which of n shards key belongs in. Use it to split data between the tables that make up a
+// Union.
+func ShardFor(key string, n int) int {
+	return int(seededHash(shardSeed, key) % uint64(n))
+}
+
+// Union presents a dataset split across several tables as a single table. Each key must be stored in the
+// shard chosen by ShardFor, and the shards must be given to NewUnion in shard order.
+type Union struct {
+	shards []*Read
+}
+
+// NewUnion creates a Union over shards. All the shards must have the same value size.
+func NewUnion(shards ...*Read) (*Union, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("a union needs at least one shard")
+	}
+	for _, t := range shards[1:] {
+		if t.valueSize != shards[0].valueSize {
+			return nil, fmt.Errorf("%w: union shards have value sizes %d and %d", ErrValueSizeMismatch, shards[0].valueSize, t.valueSize)
+		}
+	}
+	return &Union{shards: shards}, nil
+}
+
+// OpenUnion opens each of the named files with NewFrom and creates a Union over them. The files must be listed
+// in shard order.
+func OpenUnion(filenames ...string) (*Union, error) {
+	shards := make([]*Read, 0, len(filenames))
+	for _, filename := range filenames {
+		t, err := NewFrom(filename)
+		if err != nil {
+			for _, t := range shards {
+				t.Close()
+			}
+			return nil, fmt.Errorf("opening shard %s: %w", filename, err)
+		}
+		shards = append(shards, t)
+	}
+
+	u, err := NewUnion(shards...)
+	if err != nil {
+		for _, t := range shards {
+			t.Close()
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// GetPtr gets the value associated with key from the shard that holds it. See table.GetPtr.
+func (u *Union) GetPtr(key string) (val unsafe.Pointer, ok bool) {
+	return u.shards[ShardFor(key, len(u.shards))].GetPtr(key)
+}
+
+// Len returns the total number of entries across all the shards
+func (u *Union) Len() int {
+	var n int
+	for _, t := range u.shards {
+		n += t.Len()
+	}
+	return n
+}
+
+// Close closes all the shards
+func (u *Union) Close() error {
+	var firstErr error
+	for _, t := range u.shards {
+		if err := t.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
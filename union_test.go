@@ -0,0 +1,39 @@
+package statichash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnion(t *testing.T) {
+	const numShards = 3
+	data := make([]map[string]int, numShards)
+	for i := range data {
+		data[i] = make(map[string]int)
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		data[ShardFor(key, numShards)][key] = i
+	}
+
+	shards := make([]*Read, numShards)
+	for i := range shards {
+		shards[i] = readFromMap(t, data[i])
+	}
+
+	u, err := NewUnion(shards...)
+	assert.NoError(t, err)
+	defer u.Close()
+	assert.Equal(t, 100, u.Len())
+
+	for i := 0; i < 100; i++ {
+		v, ok := u.GetPtr(fmt.Sprintf("key%d", i))
+		if assert.True(t, ok) {
+			assert.Equal(t, i, *(*int)(v))
+		}
+	}
+	_, ok := u.GetPtr("key100")
+	assert.False(t, ok)
+}
@@ -0,0 +1,272 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Change is an upsert or delete applied to a table file by UpdateFile
+type Change struct {
+	Key string
+	// Value is the new value for the key. It must be the table's value size, and is ignored if Delete is set.
+	Value []byte
+	// Delete removes the key from the table
+	Delete bool
+}
+
+// UpdateFile writes a copy of the table file src to dst with changes applied in order. If the changes fit in
+// the slots and key data the table has free, the file is copied wholesale, using copy_file_range where the
+// platform supports it, and only the regions the changes touch are rewritten. Otherwise the table is rebuilt in
+// full. incremental reports which happened.
+//
+// The key data of deleted keys is not reclaimed by an incremental update. String tables and tables built
+// WithHashOnly can't be updated. dst can't be src, as src is read while dst is written.
+func UpdateFile(dst, src string, changes []Change) (incremental bool, err error) {
+	if err := checkDistinct(dst, src); err != nil {
+		return false, err
+	}
+	r, err := NewFrom(src)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	if r.flags&flagStringValues != 0 {
+		return false, errors.New("updates to string tables are not supported")
+	}
+	if err := r.needKeys("update"); err != nil {
+		return false, err
+	}
+	for _, c := range changes {
+		if !c.Delete && len(c.Value) != r.valueSize {
+			return false, fmt.Errorf("%w: value for key %q is %d bytes but the table value size is %d", ErrValueSizeMismatch, c.Key, len(c.Value), r.valueSize)
+		}
+	}
+
+	if r.fitsChanges(changes) {
+		return true, r.updateCopy(dst, src, changes)
+	}
+	t, err := r.rebuild(changes)
+	if err != nil {
+		return false, err
+	}
+	return false, t.WriteFile(dst)
+}
+
+// checkDistinct returns an error if dst is the same file as src. dst needn't exist.
+func checkDistinct(dst, src string) error {
+	di, err := os.Stat(dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	si, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(di, si) {
+		return fmt.Errorf("can't update %s in place: write the updated table to another file", src)
+	}
+	return nil
+}
+
+// fitsChanges returns true if the changes can be made to a copy of the file without moving any sections
+func (r *Read) fitsChanges(changes []Change) bool {
+	if h, err := readHeader(r.data); err != nil || h.format != currentFormat {
+		// We update the header in place, so it needs to be the current one
+		return false
+	}
+	if r.flags&flagCuckoo != 0 {
+		// Adding a key to a cuckoo table can move any number of other entries, or rehash the whole table
+		return false
+	}
+	if r.flags&flagHopscotch != 0 {
+		// A new key may not fit near enough to its home slot even if there are free slots
+		return false
+	}
+
+	// present records whether each changed key is in the table as the changes are made. Deletes don't free
+	// the key data, and we don't count the slots they free, so this is conservative.
+	present := make(map[string]bool)
+	count := r.count
+	keySpace := r.KeyDataLen() - int64(r.usedKeyData())
+	var buf [binary.MaxVarintLen64]byte
+	for _, c := range changes {
+		p, ok := present[c.Key]
+		if !ok {
+			_, p = r.find(c.Key, r.hashKey(c.Key))
+		}
+		if !c.Delete && !p {
+			count++
+			keySpace -= int64(binary.PutVarint(buf[:], int64(len(c.Key))) + len(c.Key))
+		}
+		present[c.Key] = !c.Delete
+	}
+	return count <= r.numItems && keySpace >= 0
+}
+
+// usedKeyData returns the length of the key data actually used by keys
+func (t *table) usedKeyData() int {
+	var end int
+	for i := range t.numItems {
+		h := t.hashAt(i)
+		if h == 0 {
+			continue
+		}
+		offset := int(t.keyAt(i))
+		l, lenLen := binary.Varint(t.keyData[offset:])
+		end = max(end, offset+lenLen+int(l))
+	}
+	return end
+}
+
+// updateCopy copies the file src to dst, then applies the changes to a writable mapping of dst
+func (r *Read) updateCopy(dst, src string, changes []Change) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// io.Copy between files uses copy_file_range on Linux, so the data need not pass through this process
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+
+	data, err := mapFileWritable(out.Fd(), len(r.data))
+	if err != nil {
+		return err
+	}
+	mapped, err := newFromData(data)
+	if err != nil {
+		unmap(data)
+		return err
+	}
+	t := Write{table: mapped.table}
+	t.sortCache = nil
+	// Lookups can't be bounded while we add keys
+	t.maxProbe = 0
+	t.keyOffset = t.usedKeyData()
+
+	for _, c := range changes {
+		if c.Delete {
+			if index, found := t.find(c.Key, t.hashKey(c.Key)); found {
+				t.remove(index)
+			}
+			continue
+		}
+		if err := t.Set(c.Key, bytesPointer(c.Value)); err != nil {
+			unmap(data)
+			return err
+		}
+	}
+	t.Finalize()
+	fileByteOrder.PutUint64(data[headerCount:], uint64(t.count))
+	fileByteOrder.PutUint32(data[headerMaxProbe:], uint32(t.probeLength))
+	if t.flags&flagSeeded == 0 {
+		// As when the table is written, the build time is only recorded if the output needn't be reproducible
+		fileByteOrder.PutUint64(data[headerCreated:], uint64(time.Now().UnixNano()))
+	}
+
+	if err := unmap(data); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// remove deletes the entry in slot index. Entries later in the probe sequence are moved back so that lookups
+// still find them without passing an empty slot.
+func (t *Write) remove(index int) {
+	if t.order != nil {
+		p := t.orderPosition(index)
+		copy(t.order[p:], t.order[p+1:t.count])
+		t.order[t.count-1] = 0
+	}
+	t.count--
+
+	mask := t.numItems - 1
+	empty := index
+	t.clearSlot(empty)
+	for i := (index + 1) & mask; t.hashAt(i) != 0; i = (i + 1) & mask {
+		// The entry in slot i can fill the empty slot if that is no further from its home slot
+		home := int(t.hashAt(i)) & mask
+		if (i-home)&mask < (i-empty)&mask {
+			continue
+		}
+		t.moveSlot(empty, i)
+		t.setValue(empty, t.value(i))
+		if t.order != nil {
+			t.order[t.orderPosition(i)] = slotIndex(empty)
+		}
+		empty = i
+	}
+
+	t.setValue(empty, make([]byte, t.valueSize))
+}
+
+// orderPosition returns the position of slot in the insertion order
+func (t *Write) orderPosition(slot int) int {
+	for p, s := range t.order[:t.count] {
+		if int(s) == slot {
+			return p
+		}
+	}
+	panic(fmt.Sprintf("statichash: slot %d is not in the insertion order", slot))
+}
+
+// rebuild builds a new table with the entries of r and the changes applied
+func (r *Read) rebuild(changes []Change) (*Write, error) {
+	final := make(map[string]Change, len(changes))
+	var added []string
+	var keyLength int64
+	for _, c := range changes {
+		if _, ok := final[c.Key]; !ok {
+			if _, found := r.find(c.Key, r.hashKey(c.Key)); !found {
+				added = append(added, c.Key)
+			}
+		}
+		final[c.Key] = c
+		keyLength += int64(len(c.Key))
+	}
+
+	numItems := r.numItems
+	if r.flags&flagCuckoo != 0 {
+		numItems = cuckooCapacity(numItems)
+	}
+	t := New(max(numItems, r.count+len(added)), int64(r.valueSize), int64(r.usedKeyData())+keyLength, r.options()...)
+	t.version = r.version
+	it := r.Iterate()
+	for it.Next() {
+		key := it.Key()
+		val := it.Value()
+		if c, ok := final[key]; ok {
+			if c.Delete {
+				continue
+			}
+			val = bytesPointer(c.Value)
+		}
+		if err := t.Set(key, val); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range added {
+		if c := final[key]; !c.Delete {
+			if err := t.Set(key, bytesPointer(c.Value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	t.Finalize()
+	return t, nil
+}
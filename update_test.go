@@ -0,0 +1,194 @@
+package statichash
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func int64Bytes(v int64) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(&v)), unsafe.Sizeof(v))
+}
+
+// checkFile checks the table in file name holds exactly the keys and values in want
+func checkFile(t *testing.T, name string, want map[string]int64) *Read {
+	r, err := NewFrom(name)
+	assert.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+	assert.NoError(t, r.Validate())
+	assert.Equal(t, len(want), r.Len())
+	for k, v := range want {
+		p, ok := r.GetPtr(k)
+		if assert.True(t, ok, k) {
+			assert.Equal(t, v, *(*int64)(p), k)
+		}
+	}
+	return r
+}
+
+func TestUpdateFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+
+	want := make(map[string]int64)
+	tb := New(200, 8, 2000, WithInsertionOrder(), WithFingerprints(), WithSortedIndex())
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%03d", i)
+		want[key] = int64(i)
+		assert.NoError(t, tb.Set(key, unsafe.Pointer(&i)))
+	}
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(src))
+
+	changes := []Change{
+		{Key: "key010", Value: int64Bytes(1000)},
+		{Key: "new", Value: int64Bytes(-1)},
+		{Key: "key020", Delete: true},
+		{Key: "missing", Delete: true},
+		{Key: "key030", Delete: true},
+		{Key: "key030", Value: int64Bytes(30000)},
+	}
+	want["key010"] = 1000
+	want["new"] = -1
+	delete(want, "key020")
+	want["key030"] = 30000
+
+	t.Run("incremental", func(t *testing.T) {
+		dst := filepath.Join(dir, "incremental")
+		incremental, err := UpdateFile(dst, src, changes)
+		assert.NoError(t, err)
+		assert.True(t, incremental)
+		r := checkFile(t, dst, want)
+
+		var keys []string
+		it := r.Iterate()
+		for it.Next() {
+			keys = append(keys, it.Key())
+		}
+		assert.Equal(t, "key019", keys[19])
+		assert.Equal(t, "key021", keys[20])
+		assert.Equal(t, []string{"new", "key030"}, keys[len(keys)-2:])
+	})
+
+	t.Run("rebuild", func(t *testing.T) {
+		var more []Change
+		want := maps.Clone(want)
+		for i := 0; i < 200; i++ {
+			key := fmt.Sprintf("more%03d", i)
+			more = append(more, Change{Key: key, Value: int64Bytes(int64(i))})
+			want[key] = int64(i)
+		}
+
+		dst := filepath.Join(dir, "rebuild")
+		incremental, err := UpdateFile(dst, src, append(changes, more...))
+		assert.NoError(t, err)
+		assert.False(t, incremental)
+		checkFile(t, dst, want)
+	})
+
+	t.Run("value size", func(t *testing.T) {
+		_, err := UpdateFile(filepath.Join(dir, "bad"), src, []Change{{Key: "a", Value: []byte{1}}})
+		assert.ErrorIs(t, err, ErrValueSizeMismatch)
+	})
+}
+
+func TestUpdateFileDeletes(t *testing.T) {
+	// Deleting from a full table moves entries back along their probe sequences
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+
+	want := make(map[string]int64)
+	tb := New(16, 8, 200, WithColumns(4, 4))
+	for i := 0; i < 16; i++ {
+		key := fmt.Sprint(i)
+		want[key] = int64(i) << 33
+		assert.NoError(t, tb.Set(key, unsafe.Pointer(&[]int64{want[key]}[0])))
+	}
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(src))
+
+	var changes []Change
+	for i := 0; i < 16; i += 3 {
+		key := fmt.Sprint(i)
+		changes = append(changes, Change{Key: key, Delete: true})
+		delete(want, key)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	incremental, err := UpdateFile(dst, src, changes)
+	assert.NoError(t, err)
+	assert.True(t, incremental)
+	checkFile(t, dst, want)
+}
+
+func TestUpdateFileInPlace(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	tb := buildTable(t, 10)
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(src))
+	before, err := os.ReadFile(src)
+	assert.NoError(t, err)
+
+	v := make([]byte, 8)
+	for _, dst := range []string{src, filepath.Join(dir, ".", "src")} {
+		_, err = UpdateFile(dst, src, []Change{{Key: "new", Value: v}})
+		assert.Error(t, err)
+	}
+	after, err := os.ReadFile(src)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestUpdateFileBuildTime(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tb := New(4, 8, 100, WithBuildTime(old))
+	assert.NoError(t, tb.Set("a", bytesPointer(int64Bytes(1))))
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(src))
+
+	for name, changes := range map[string][]Change{
+		"incremental": {{Key: "b", Value: make([]byte, 8)}},
+		"rebuild":     {{Key: "b", Value: make([]byte, 8)}, {Key: "c", Value: make([]byte, 8)}, {Key: "d", Value: make([]byte, 8)}, {Key: "e", Value: make([]byte, 8)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dst := filepath.Join(dir, name)
+			incremental, err := UpdateFile(dst, src, changes)
+			assert.NoError(t, err)
+			assert.Equal(t, name == "incremental", incremental)
+			r, err := NewFrom(dst)
+			assert.NoError(t, err)
+			defer r.Close()
+			assert.True(t, r.Info().Created.After(old))
+		})
+	}
+}
+
+func TestUpdateFileRebuildFails(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+
+	// A hopscotch table runs out of neighbourhoods before its slots are full
+	tb := New(1024, 8, 20000, WithHopscotch())
+	for i := 0; tb.Set(fmt.Sprintf("key%d", i), bytesPointer(int64Bytes(int64(i)))) == nil; i++ {
+	}
+	tb.Finalize()
+	assert.NoError(t, tb.WriteFile(src))
+
+	// Filling every slot is too many for the rebuilt table to hold, and the long keys don't fit in the key data
+	// that's free, so the update can't be incremental
+	var changes []Change
+	for i := range tb.NumSlots() - tb.Len() {
+		changes = append(changes, Change{Key: fmt.Sprintf("a new key that is much longer than the others %d", i), Value: int64Bytes(int64(i))})
+	}
+	_, err := UpdateFile(filepath.Join(dir, "dst"), src, changes)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Validate checks the whole table for corruption, reading every key. For every occupied slot it checks that the
+// key is within the key data, that the stored hash and fingerprint match the key, and that a lookup of the key
+// would find the slot. It also checks the entry count and the order and sorted sections. Problems are reported
+// with an error wrapping ErrCorrupt. Validate is intended for checking files after they've been copied, and
+// takes a while for a large table. A table built WithHashOnly has no keys, so its slots are checked against the
+// full hashes stored in them instead. If the table is mapped in windows and a window can't be mapped, that
+// error is returned instead.
+func (r *Read) Validate() error {
+	err := r.validate()
+	if r.win != nil {
+		// Keys and values that can't be read look empty, which would otherwise be reported as corruption
+		if werr := r.win.firstErr(); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func (r *Read) validate() error {
+	if err := r.checkBounds(); err != nil {
+		return err
+	}
+
+	mask := r.numItems - 1
+	for i := range r.numItems {
+		h := r.hashAt(i)
+		if h == 0 {
+			continue
+		}
+
+		// checkBounds has checked the key is within the key data
+		key := r.getKey(r.keyAt(i))
+		full := r.entryHash(i)
+		if slotHash(full) != h {
+			return fmt.Errorf("%w: slot %d: stored hash %#x does not match key %q", ErrCorrupt, i, uint32(h), key)
+		}
+		if r.hasFingerprints() && r.fingerprintAt(i) != fingerprint(full) {
+			return fmt.Errorf("%w: slot %d: stored fingerprint does not match key %q", ErrCorrupt, i, key)
+		}
+
+		if r.flags&flagCuckoo != 0 {
+			// The key must be in one of its two slots, and not in the other as well
+			first, second := r.cuckooSlots(key, h)
+			other := first
+			switch i {
+			case first:
+				other = second
+			case second:
+			default:
+				return fmt.Errorf("%w: slot %d: key %q is in neither of its cuckoo slots %d and %d", ErrCorrupt, i, key, first, second)
+			}
+			if other != i && r.hashAt(other) == h && r.sameKey(other, i) {
+				return fmt.Errorf("%w: key %q is in slots %d and %d", ErrCorrupt, key, i, other)
+			}
+		} else {
+			if r.flags&flagHopscotch != 0 && r.probeLength(i) > hopscotchNeighbourhood {
+				return fmt.Errorf("%w: slot %d: key %q is outside the neighbourhood of its home slot", ErrCorrupt, i, key)
+			}
+			// Walk the probe sequence from the key's home slot. It must reach this slot without passing an
+			// empty slot or another copy of the key.
+			for cursor := int(h) & mask; cursor != i; cursor = (cursor + 1) & mask {
+				if r.hashAt(cursor) == 0 {
+					return fmt.Errorf("%w: slot %d: key %q is not reachable from its home slot", ErrCorrupt, i, key)
+				}
+				if r.hashAt(cursor) == h && r.sameKey(cursor, i) {
+					return fmt.Errorf("%w: key %q is in slots %d and %d", ErrCorrupt, key, cursor, i)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkBounds checks what lookups and iteration rely on to stay within the table: that the key of every
+// occupied slot, unless the table was built WithHashOnly, and its string value in a string table, is within the
+// key data, that the entry count is right, and that the order and sorted sections only refer to occupied slots.
+// It is much quicker than Validate as it doesn't hash the keys. Problems are reported with an error wrapping
+// ErrCorrupt.
+func (t *table) checkBounds() error {
+	var count int
+	for i := range t.numItems {
+		h := t.hashAt(i)
+		if h == 0 {
+			continue
+		}
+		count++
+
+		if t.flags&flagHashOnly == 0 {
+			if _, err := t.checkedKey(t.keyAt(i)); err != nil {
+				return fmt.Errorf("%w: slot %d: %v", ErrCorrupt, i, err)
+			}
+		}
+		if t.flags&flagStringValues != 0 {
+			value := t.value(i)
+			if t.flags&flagInlineStrings != 0 {
+				if n := value[len(value)-1]; n != overflowString {
+					if int(n) >= len(value) {
+						return fmt.Errorf("%w: slot %d: inline string of %d bytes in a value of %d", ErrCorrupt, i, n, len(value))
+					}
+					continue
+				}
+			}
+			if _, err := t.checkedKey(keyOffset(fileByteOrder.Uint64(value))); err != nil {
+				return fmt.Errorf("%w: slot %d: string value: %v", ErrCorrupt, i, err)
+			}
+		}
+	}
+
+	if count != t.count {
+		return fmt.Errorf("%w: header says there are %d entries but %d slots are occupied", ErrCorrupt, t.count, count)
+	}
+	for name, slots := range map[string][]slotIndex{"order": t.order, "sorted": t.sorted} {
+		if slots == nil {
+			continue
+		}
+		for _, slot := range slots[:count] {
+			if slot < 0 || int(slot) >= t.numItems || t.hashAt(int(slot)) == 0 {
+				return fmt.Errorf("%w: %s section refers to slot %d, which is not occupied", ErrCorrupt, name, slot)
+			}
+		}
+	}
+	return nil
+}
+
+// checkedKey is like getKey, but returns an error rather than panicking if the key at offset is not within the
+// key data
+func (t *table) checkedKey(offset keyOffset) (string, error) {
+	keyDataLen := t.KeyDataLen()
+	if offset < 0 || int64(offset) >= keyDataLen {
+		return "", fmt.Errorf("key offset %d is outside the key data", offset)
+	}
+	n := min(int64(binary.MaxVarintLen64), keyDataLen-int64(offset))
+	var lenBytes []byte
+	if t.win != nil {
+		var err error
+		if lenBytes, err = t.win.copy(t.layout.keyData+int64(offset), n); err != nil {
+			return "", err
+		}
+	} else {
+		lenBytes = t.keyData[offset : int64(offset)+n]
+	}
+	l, lenLen := binary.Varint(lenBytes)
+	if lenLen <= 0 || l < 0 || l > keyDataLen-int64(offset)-int64(lenLen) {
+		return "", fmt.Errorf("key at offset %d runs past the end of the key data", offset)
+	}
+	if t.win != nil && t.flags&flagHashOnly == 0 {
+		return t.win.key(t.layout.keyData + int64(offset))
+	}
+	return t.getKey(offset), nil
+}
@@ -0,0 +1,128 @@
+package statichash
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	data := func(opts ...Option) []byte {
+		tb := buildTable(t, 100, opts...)
+		tb.Finalize()
+		var buf bytes.Buffer
+		_, err := tb.WriteTo(&buf)
+		assert.NoError(t, err)
+		return buf.Bytes()
+	}
+
+	for _, opts := range [][]Option{nil, {WithFingerprints(), WithInsertionOrder(), WithSortedIndex()}, {WithSeed(3)}} {
+		tr, err := NewFromBytes(data(opts...))
+		assert.NoError(t, err)
+		assert.NoError(t, tr.Validate())
+	}
+
+	st := NewStringTable(10, 100)
+	st.Set("a", "b")
+	st.Finalize()
+	tr, err := NewFromBytes(func() []byte {
+		var buf bytes.Buffer
+		st.WriteTo(&buf)
+		return buf.Bytes()
+	}())
+	assert.NoError(t, err)
+	assert.NoError(t, tr.Validate())
+
+	tw, err := NewFrom(writeTempTable(t, buildTable(t, 100)), WithWindowedMapping(4096, 1))
+	assert.NoError(t, err)
+	defer tw.Close()
+	assert.NoError(t, tw.Validate())
+
+	occupied := func(r *Read) int {
+		for i, h := range r.hashes {
+			if h != 0 {
+				return i
+			}
+		}
+		return -1
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(r *Read)
+		err     string
+	}{
+		{
+			name:    "hash",
+			corrupt: func(r *Read) { r.hashes[occupied(r)]++ },
+			err:     "does not match key",
+		},
+		{
+			name:    "key offset",
+			corrupt: func(r *Read) { r.keys[occupied(r)] = 1 << 40 },
+			err:     "outside the key data",
+		},
+		{
+			name: "key length",
+			corrupt: func(r *Read) {
+				// A varint length of 8191
+				b := r.keyData[r.keys[occupied(r)]:]
+				b[0], b[1] = 0xfe, 0x7f
+			},
+			err: "runs past the end",
+		},
+		{
+			name:    "count",
+			corrupt: func(r *Read) { r.count++ },
+			err:     "header says there are 101 entries",
+		},
+		{
+			name: "unreachable",
+			corrupt: func(r *Read) {
+				// Clear the home slot of a key that's been displaced from it
+				mask := r.numItems - 1
+				for i, h := range r.hashes {
+					if h != 0 && int(h)&mask != i {
+						r.hashes[int(h)&mask] = 0
+						r.count--
+						return
+					}
+				}
+				t.Fatal("no displaced keys")
+			},
+			err: "not reachable",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr, err := NewFromBytes(data())
+			assert.NoError(t, err)
+			test.corrupt(tr)
+			err = tr.Validate()
+			assert.ErrorIs(t, err, ErrCorrupt)
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+
+	tr, err = NewFromBytes(data(WithInsertionOrder()))
+	assert.NoError(t, err)
+	tr.order[3] = slotIndex(tr.numItems)
+	assert.ErrorContains(t, tr.Validate(), "order section")
+}
+
+func FuzzCheckedKey(f *testing.F) {
+	f.Add([]byte{6, 'k', 'e', 'y', 0, 2, 'a'}, int64(0))
+	f.Add([]byte{6, 'k', 'e', 'y', 0, 2, 'a'}, int64(5))
+	f.Add([]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, int64(0))
+	f.Fuzz(func(t *testing.T, keyData []byte, offset int64) {
+		tb := table{keyData: keyData, layout: layout{length: int64(len(keyData))}}
+		key, err := tb.checkedKey(keyOffset(offset))
+		if err != nil {
+			return
+		}
+		if int64(len(key)) > int64(len(keyData))-offset {
+			t.Fatalf("key of %d bytes at offset %d is longer than the key data", len(key), offset)
+		}
+	})
+}
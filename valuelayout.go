@@ -0,0 +1,85 @@
+package statichash
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// valueLayoutSeed is the seed used to hash descriptions of value types. It is fixed so that the hash of a type
+// is the same in every program.
+const valueLayoutSeed = 0x9e3779b97f4a7c15
+
+// WithValueType records a hash of the layout of T in the file: the name, offset and size of each of its fields,
+// and those of any structs and arrays within it. NewTyped then refuses to read the table as a type laid out
+// differently, returning ErrSchemaMismatch, so a reader built from an older or newer version of the value type
+// fails at open rather than returning garbage. The name of T itself doesn't matter. FromMapT records the hash
+// automatically. New panics if T is not the size of the values.
+func WithValueType[T any]() Option {
+	typ := reflect.TypeFor[T]()
+	return func(o *options) {
+		o.valueType = typ
+	}
+}
+
+// valueLayoutHash returns the hash of the layout of typ recorded by WithValueType
+func valueLayoutHash(typ reflect.Type) uint64 {
+	var b strings.Builder
+	describeLayout(&b, typ)
+	return seededHash(valueLayoutSeed, b.String())
+}
+
+// describeLayout writes a description of the layout of typ to b. Named types are described by their
+// underlying type, so renaming a type doesn't change its description.
+func describeLayout(b *strings.Builder, typ reflect.Type) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		b.WriteString("struct{")
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			b.WriteString(f.Name)
+			b.WriteByte('@')
+			b.WriteString(strconv.FormatUint(uint64(f.Offset), 10))
+			b.WriteByte(' ')
+			describeLayout(b, f.Type)
+			b.WriteByte(';')
+		}
+		b.WriteByte('}')
+	case reflect.Array:
+		b.WriteByte('[')
+		b.WriteString(strconv.Itoa(typ.Len()))
+		b.WriteByte(']')
+		describeLayout(b, typ.Elem())
+	default:
+		b.WriteString(typ.Kind().String())
+	}
+	b.WriteByte('/')
+	b.WriteString(strconv.FormatUint(uint64(typ.Size()), 10))
+}
+
+// setValueType adds the section recording the layout hash of typ
+func (t *Write) setValueType(typ reflect.Type) {
+	if int(typ.Size()) != t.valueSize {
+		panic(fmt.Sprintf("statichash: %s is %d bytes but the value size is %d", typ, typ.Size(), t.valueSize))
+	}
+	data := make([]byte, 8)
+	fileByteOrder.PutUint64(data, valueLayoutHash(typ))
+	t.addSection(SectionValueLayout, data)
+}
+
+// checkValueType returns an error wrapping ErrSchemaMismatch if the table was built WithValueType of a type
+// laid out differently from typ. Tables built without it pass.
+func (r *Read) checkValueType(typ reflect.Type) error {
+	data, ok, err := r.section(SectionValueLayout)
+	if !ok || err != nil {
+		return err
+	}
+	if len(data) != 8 {
+		return fmt.Errorf("%w: value layout section is %d bytes", ErrCorrupt, len(data))
+	}
+	if fileByteOrder.Uint64(data) != valueLayoutHash(typ) {
+		return fmt.Errorf("%w: table was built with a value type laid out differently from %s", ErrSchemaMismatch, typ)
+	}
+	return nil
+}
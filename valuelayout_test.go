@@ -0,0 +1,93 @@
+package statichash
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueLayoutHash(t *testing.T) {
+	type renamed struct {
+		A int32
+		B float64
+	}
+	type fieldRenamed struct {
+		A int32
+		C float64
+	}
+	type reordered struct {
+		B float64
+		A int32
+	}
+	type resized struct {
+		A int64
+		B float64
+	}
+	type nested struct {
+		A int32
+		B struct{ X, Y float32 }
+	}
+
+	base := valueLayoutHash(reflect.TypeFor[typedValue]())
+	assert.Equal(t, base, valueLayoutHash(reflect.TypeFor[renamed]()))
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[fieldRenamed](),
+		reflect.TypeFor[reordered](),
+		reflect.TypeFor[resized](),
+		reflect.TypeFor[nested](),
+		reflect.TypeFor[[16]byte](),
+		reflect.TypeFor[[2]int64](),
+	} {
+		assert.NotEqual(t, base, valueLayoutHash(typ), typ.String())
+	}
+	assert.NotEqual(t, valueLayoutHash(reflect.TypeFor[[2]int64]()), valueLayoutHash(reflect.TypeFor[[16]byte]()))
+}
+
+func TestValueLayoutTyped(t *testing.T) {
+	type sameLayout struct {
+		A int32
+		B float64
+	}
+	type drifted struct {
+		A int32
+		_ [4]byte
+		C int64
+	}
+
+	// typedTable is built with FromMapOf, which records the layout
+	tr := typedTable(t)
+	_, err := NewTyped[typedValue](tr)
+	assert.NoError(t, err)
+	_, err = NewTyped[sameLayout](tr)
+	assert.NoError(t, err)
+	_, err = NewTyped[drifted](tr)
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+	// Tables built without a value type can be read as any type of the right size
+	tb := New(10, 16, 100)
+	assert.NoError(t, tb.Set("a", bytesPointer(make([]byte, 16))))
+	tb.Finalize()
+	var buf bytes.Buffer
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	_, err = NewTyped[drifted](r)
+	assert.NoError(t, err)
+
+	tb = New(10, 16, 100, WithValueType[sameLayout]())
+	tb.Finalize()
+	buf.Reset()
+	_, err = tb.WriteTo(&buf)
+	assert.NoError(t, err)
+	r, err = NewFromBytes(buf.Bytes())
+	assert.NoError(t, err)
+	_, err = NewTyped[typedValue](r)
+	assert.NoError(t, err)
+	_, err = NewTyped[drifted](r)
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+	assert.Panics(t, func() { New(10, 8, 100, WithValueType[sameLayout]()) })
+}
@@ -0,0 +1,76 @@
+package statichash
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// warmChunk is how much of the table Warm touches between checks of the context and the rate limit
+const warmChunk = 1 << 20
+
+// warmSink stops the compiler optimising away the reads that fault pages in
+var warmSink byte
+
+// Warm pulls every page of the table into memory, so that lookups made afterwards don't pay for page faults.
+// Services can call this before reporting themselves ready. It returns early with the context's error if ctx
+// is cancelled. For a table opened WithWindowedMapping only the index sections are warmed. Progress is reported
+// to the function given WithWarmProgress.
+func (r *Read) Warm(ctx context.Context) error {
+	return r.WarmRate(ctx, 0)
+}
+
+// WarmRate is like Warm, but reads at most bytesPerSecond so that warming a large table doesn't starve the
+// rest of the host of IO. A rate of zero means no limit.
+func (r *Read) WarmRate(ctx context.Context, bytesPerSecond int64) error {
+	chunk := warmChunk
+	if bytesPerSecond > 0 && bytesPerSecond/10 < int64(chunk) {
+		// Keep each step to roughly 100ms so we stay responsive to cancellation
+		chunk = int(bytesPerSecond / 10)
+		if chunk < os.Getpagesize() {
+			chunk = os.Getpagesize()
+		}
+	}
+
+	pageSize := os.Getpagesize()
+	start := time.Now()
+	var sink byte
+	for done := 0; done < len(r.data); {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := done + chunk
+		if end > len(r.data) {
+			end = len(r.data)
+		}
+		data := r.data[done:end]
+		if r.mapped {
+			// This is only a hint, so we don't mind if it fails
+			adviseWillNeed(data)
+		}
+		for i := 0; i < len(data); i += pageSize {
+			sink ^= data[i]
+		}
+		done = end
+		if r.progress != nil {
+			r.progress(Progress{Stage: StageWarm, Done: int64(done), Total: int64(len(r.data))})
+		}
+
+		if bytesPerSecond > 0 {
+			due := start.Add(time.Duration(float64(done) / float64(bytesPerSecond) * float64(time.Second)))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+	}
+	warmSink = sink
+
+	return nil
+}
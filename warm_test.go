@@ -0,0 +1,44 @@
+package statichash
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTempTable(t *testing.T, tb *Write) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "table")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	tb.Finalize()
+	_, err = tb.WriteTo(f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	return name
+}
+
+func TestWarm(t *testing.T) {
+	tr, err := NewFrom(writeTempTable(t, buildTable(t, 1000)))
+	assert.NoError(t, err)
+	defer tr.Close()
+
+	assert.NoError(t, tr.Warm(context.Background()))
+}
+
+func TestWarmRateCancelled(t *testing.T) {
+	tr, err := NewFrom(writeTempTable(t, buildTable(t, 10000)))
+	assert.NoError(t, err)
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// At this rate the table would take many seconds to warm
+	err = tr.WarmRate(ctx, 4096)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
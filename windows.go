@@ -0,0 +1,218 @@
+package statichash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+// newWindowed opens a table with only the index sections mapped. The values and key data are mapped on demand
+// through windows.
+func newWindowed(f *os.File, fileLength int64, o *readOptions) (*Read, error) {
+	buf := make([]byte, min(fileLength, headerSize))
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		f.Close()
+		if err == io.EOF {
+			err = ErrTruncated
+		}
+		return nil, err
+	}
+	h, err := readHeader(buf)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := h.validate(fileLength); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	t := Read{
+		table: readTable(&h, fileLength),
+		file:  f,
+	}
+	if err := t.readDirectory(&h, f, fileLength); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	indexLength := roundUp(t.layout.values, uintptr(os.Getpagesize()))
+	if indexLength > fileLength {
+		indexLength = fileLength
+	}
+	data, err := o.mapMemory(f.Fd(), int(indexLength))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.data = data
+	t.mapped = true
+
+	// We only set up the slices for the index sections. The values and key data are outside the mapping
+	t.setSections(unsafe.Pointer(unsafe.SliceData(data)), t.layout)
+	t.countV0(&h)
+	if t.flags&flagInlineValues == 0 {
+		t.values = nil
+	}
+	t.keyData = nil
+
+	t.win = &windows{
+		fd:         f.Fd(),
+		size:       o.windowSize,
+		max:        o.maxWindows,
+		fileLength: fileLength,
+	}
+
+	return &t, nil
+}
+
+// Err returns the first error mapping or unmapping a window of a table opened WithWindowedMapping. A lookup
+// whose key or value can't be read reports the key as not found, and the error is kept here.
+func (r *Read) Err() error {
+	if r.win == nil {
+		return nil
+	}
+	return r.win.firstErr()
+}
+
+// windows maps regions of a file on demand, keeping at most max of them mapped at once. The least recently
+// used window is unmapped to make room for a new one.
+type windows struct {
+	mu         sync.Mutex
+	fd         uintptr
+	size       int64
+	max        int
+	fileLength int64
+
+	// mapped is ordered from least to most recently used
+	mapped []window
+
+	// err is the first error mapping or unmapping a window
+	err error
+}
+
+type window struct {
+	offset int64
+	data   []byte
+}
+
+// get returns the n bytes at offset within the file. The caller must hold w.mu, and the bytes are only valid
+// until it is released. If the bytes can't be mapped the error is returned, and the first such error is kept
+// for Err.
+func (w *windows) get(offset, n int64) ([]byte, error) {
+	data, err := w.mapWindow(offset, n)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return data, err
+}
+
+func (w *windows) mapWindow(offset, n int64) ([]byte, error) {
+	for i, win := range w.mapped {
+		if offset >= win.offset && offset+n <= win.offset+int64(len(win.data)) {
+			copy(w.mapped[i:], w.mapped[i+1:])
+			w.mapped[len(w.mapped)-1] = win
+			return win.data[offset-win.offset : offset-win.offset+n], nil
+		}
+	}
+
+	// Windows normally start on a multiple of the window size, but are extended if the data we want runs over
+	// the end.
+	start := offset - offset%w.size
+	end := start + w.size
+	if e := roundUp(offset+n, uintptr(os.Getpagesize())); e > end {
+		end = e
+	}
+	if end > w.fileLength {
+		end = w.fileLength
+	}
+	if offset+n > end {
+		return nil, fmt.Errorf("%w: read of %d bytes at %d is beyond the end of the file", ErrTruncated, n, offset)
+	}
+
+	if len(w.mapped) == w.max {
+		if err := unmap(w.mapped[0].data); err != nil {
+			return nil, fmt.Errorf("unmapping window at %d: %w", w.mapped[0].offset, err)
+		}
+		copy(w.mapped, w.mapped[1:])
+		w.mapped = w.mapped[:len(w.mapped)-1]
+	}
+
+	data, err := mapFile(w.fd, start, int(end-start))
+	if err != nil {
+		return nil, fmt.Errorf("mapping window at %d: %w", start, err)
+	}
+	w.mapped = append(w.mapped, window{offset: start, data: data})
+
+	return data[offset-start : offset-start+n], nil
+}
+
+// copy returns a copy of the n bytes at offset within the file. If they can't be read the copy is zero.
+func (w *windows) copy(offset, n int64) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]byte, n)
+	data, err := w.get(offset, n)
+	copy(out, data)
+	return out, err
+}
+
+// keyData returns the bytes of the key stored at offset. The caller must hold w.mu.
+func (w *windows) keyData(offset int64) ([]byte, error) {
+	n := int64(binary.MaxVarintLen64)
+	if offset+n > w.fileLength {
+		n = w.fileLength - offset
+	}
+	if offset < 0 || n <= 0 {
+		// The offset is corrupt. We treat the key as empty rather than reading outside the file.
+		return nil, nil
+	}
+	data, err := w.get(offset, n)
+	if err != nil {
+		return nil, err
+	}
+	l, lenLen := binary.Varint(data)
+	if lenLen <= 0 || l < 0 || l > w.fileLength-offset-int64(lenLen) {
+		return nil, nil
+	}
+	return w.get(offset+int64(lenLen), l)
+}
+
+// key returns a copy of the key stored at offset
+func (w *windows) key(offset int64) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data, err := w.keyData(offset)
+	return string(data), err
+}
+
+// keyEquals returns true if the key stored at offset is key. A key that can't be read matches nothing.
+func (w *windows) keyEquals(offset int64, key string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data, err := w.keyData(offset)
+	return err == nil && string(data) == key
+}
+
+// firstErr returns the first error mapping or unmapping a window
+func (w *windows) firstErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *windows) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for _, win := range w.mapped {
+		if e := unmap(win.data); err == nil {
+			err = e
+		}
+	}
+	w.mapped = nil
+	return err
+}
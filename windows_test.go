@@ -0,0 +1,76 @@
+package statichash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowedMapping(t *testing.T) {
+	type value struct {
+		a, b int64
+		c    [48]byte
+	}
+
+	tb := New(1000, int64(unsafe.Sizeof(value{})), 20000)
+	for i := 0; i < 1000; i++ {
+		v := value{a: int64(i), b: int64(-i)}
+		tb.Set(fmt.Sprintf("key-%d-%s", i, string(make([]byte, i%10))), unsafe.Pointer(&v))
+	}
+
+	name := filepath.Join(t.TempDir(), "table")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	tb.Finalize()
+	_, err = tb.WriteTo(f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	tr, err := NewFrom(name, WithWindowedMapping(1, 3))
+	assert.NoError(t, err)
+	defer tr.Close()
+	assert.Equal(t, 1000, tr.Len())
+
+	for i := 0; i < 1000; i++ {
+		v, ok := tr.GetPtr(fmt.Sprintf("key-%d-%s", i, string(make([]byte, i%10))))
+		if assert.True(t, ok) {
+			assert.Equal(t, value{a: int64(i), b: int64(-i)}, *(*value)(v))
+		}
+	}
+	_, ok := tr.GetPtr("key-1000-")
+	assert.False(t, ok)
+
+	full, err := NewFrom(name)
+	assert.NoError(t, err)
+	defer full.Close()
+
+	assert.NoError(t, Diff(full, tr, func(kind DiffKind, key string) bool {
+		t.Errorf("unexpected difference %s %q", kind, key)
+		return true
+	}))
+}
+
+func TestWindowedMappingFails(t *testing.T) {
+	tb := New(100, 8, 2000)
+	for i := range int64(100) {
+		assert.NoError(t, tb.Set(fmt.Sprintf("key-%d", i), unsafe.Pointer(&i)))
+	}
+	name := writeTempTable(t, tb)
+
+	tr, err := NewFrom(name, WithWindowedMapping(1, 1))
+	assert.NoError(t, err)
+	defer tr.Close()
+	assert.NoError(t, tr.Err())
+
+	// With the file closed no new window can be mapped
+	assert.NoError(t, tr.file.Close())
+
+	_, ok := tr.GetPtr("key-1")
+	assert.False(t, ok)
+	assert.Error(t, tr.Err())
+	assert.Error(t, tr.Validate())
+}
@@ -0,0 +1,117 @@
+package statichash
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"unsafe"
+)
+
+// NewFromZip opens the table stored as the member name of the zip archive zipFile. If the member is stored
+// uncompressed and its data starts on an 8-byte boundary within the archive, the table is mapped straight from
+// the archive, so it needn't be extracted first. Otherwise the member is decompressed or copied into memory, as
+// if it were opened with NewFromBytes. Evict does nothing for tables opened from an archive, and
+// WithWindowedMapping and WithSharedLock have no effect.
+//
+// The member's data follows its local header in the archive, so to get a member that can be mapped, store it
+// with a name or extra field whose length brings the data onto an 8-byte boundary.
+func NewFromZip(zipFile, name string, opts ...ReadOption) (*Read, error) {
+	o := newReadOptions(opts)
+
+	f, err := os.Open(zipFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", zipFile, err)
+	}
+
+	var member *zip.File
+	for _, zf := range zr.File {
+		if zf.Name == name {
+			member = zf
+			break
+		}
+	}
+	if member == nil {
+		return nil, fmt.Errorf("opening %s: no member %s: %w", zipFile, name, fs.ErrNotExist)
+	}
+	if member.UncompressedSize64 > uint64(1<<63-1) {
+		return nil, fmt.Errorf("opening %s in %s: %w: member is %d bytes", name, zipFile, ErrTooLarge, member.UncompressedSize64)
+	}
+	size := int64(member.UncompressedSize64)
+	if err := checkMappable(size); err != nil {
+		return nil, fmt.Errorf("opening %s in %s: %w", name, zipFile, err)
+	}
+
+	var r *Read
+	offset, err := member.DataOffset()
+	if err == nil && member.Method == zip.Store && member.CompressedSize64 == member.UncompressedSize64 && offset%int64(unsafe.Alignof(int64(0))) == 0 {
+		r, err = mapTableAt(f, offset, size, &o)
+	} else {
+		r, err = readZipMember(member, size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s in %s: %w", name, zipFile, err)
+	}
+	if err := r.apply(&o); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// mapTableAt maps the size bytes of the table at offset in the file f, such as a zip archive that holds it
+// among other things. mmap needs an offset that is a multiple of the page size, so we map from the start of the
+// page the table starts in. The mapping is locked into memory as o says.
+func mapTableAt(f *os.File, offset, size int64, o *readOptions) (*Read, error) {
+	pageStart := offset &^ int64(os.Getpagesize()-1)
+	if err := checkMappable(offset + size - pageStart); err != nil {
+		return nil, err
+	}
+	mapping, err := o.mapMemoryAt(f.Fd(), pageStart, int(offset+size-pageStart))
+	if err != nil {
+		return nil, err
+	}
+	r, err := newFromData(mapping[offset-pageStart:])
+	if err != nil {
+		unmap(mapping)
+		return nil, err
+	}
+	r.mapping = mapping
+	return r, nil
+}
+
+// readZipMember reads a table of size bytes from a compressed member of an archive into memory, and checks it
+// as NewFromBytes does
+func readZipMember(member *zip.File, size int64) (*Read, error) {
+	rc, err := member.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// We read into []int64 so the table's sections are aligned
+	buf := make([]int64, (size+7)/8)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(buf))), size)
+	if _, err := io.ReadFull(rc, data); err != nil {
+		return nil, err
+	}
+	r, err := newFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkBounds(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
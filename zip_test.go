@@ -0,0 +1,95 @@
+package statichash
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromZip(t *testing.T) {
+	tb := buildTable(t, 1000)
+	tb.Finalize()
+	var table bytes.Buffer
+	_, err := tb.WriteTo(&table)
+	assert.NoError(t, err)
+
+	name := filepath.Join(t.TempDir(), "tables.zip")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	zw := zip.NewWriter(f)
+	add := func(name string, method uint16) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		assert.NoError(t, err)
+		_, err = w.Write(table.Bytes())
+		assert.NoError(t, err)
+	}
+	// The first local header is 30 bytes plus the name, so a 2 byte name puts the data on an 8-byte boundary
+	add("ab", zip.Store)
+	add("deflated", zip.Deflate)
+	assert.NoError(t, zw.Close())
+	assert.NoError(t, f.Close())
+
+	for _, test := range []struct {
+		member string
+		mapped bool
+	}{
+		{member: "ab", mapped: true},
+		{member: "deflated"},
+	} {
+		t.Run(test.member, func(t *testing.T) {
+			r, err := NewFromZip(name, test.member, WithValueSize(8))
+			assert.NoError(t, err)
+			defer func() { assert.NoError(t, r.Close()) }()
+			assert.Equal(t, test.mapped, r.mapping != nil)
+			assert.Equal(t, 1000, r.Len())
+			for i := 0; i < 1000; i++ {
+				v, ok := r.GetPtr(fmt.Sprintf("key%d", 1000-i))
+				if assert.True(t, ok) {
+					assert.Equal(t, i, *(*int)(v))
+				}
+			}
+		})
+	}
+
+	_, err = NewFromZip(name, "missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = NewFromZip(name, "ab", WithValueSize(4))
+	assert.ErrorIs(t, err, ErrValueSizeMismatch)
+}
+
+func TestNewFromZipCorrupt(t *testing.T) {
+	tb := buildTable(t, 100)
+	tb.Finalize()
+	var table bytes.Buffer
+	_, err := tb.WriteTo(&table)
+	assert.NoError(t, err)
+	r, err := NewFromBytes(table.Bytes())
+	assert.NoError(t, err)
+
+	// Every key offset points past the key data
+	data := table.Bytes()
+	start, end := r.layout.section(SectionKeys)
+	for i := start; i < end; i++ {
+		data[i] = 0xff
+	}
+
+	name := filepath.Join(t.TempDir(), "tables.zip")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "deflated", Method: zip.Deflate})
+	assert.NoError(t, err)
+	_, err = w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	assert.NoError(t, f.Close())
+
+	_, err = NewFromZip(name, "deflated")
+	assert.ErrorIs(t, err, ErrCorrupt)
+}